@@ -0,0 +1,87 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+
+	"github.com/aymanbagabas/go-osc52/v2"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// clipboardCommands are the clipboard tools tried in order on each platform
+var clipboardCommands = map[string][][]string{
+	"darwin":  {{"pbcopy"}},
+	"windows": {{"clip.exe"}},
+	"linux": {
+		{"wl-copy"},
+		{"xclip", "-selection", "clipboard"},
+		{"xsel", "--clipboard", "--input"},
+	},
+}
+
+// errNoClipboardTool is returned when none of the platform's clipboard tools
+// are installed
+var errNoClipboardTool = errors.New("no clipboard tool found")
+
+// copyWithTool puts text on the system clipboard using the first of the
+// platform's clipboard tools that works
+func copyWithTool(text string) error {
+	err := errNoClipboardTool
+	for _, args := range clipboardCommands[runtime.GOOS] {
+		if _, lookErr := exec.LookPath(args[0]); lookErr != nil {
+			continue
+		}
+		cmd := exec.Command(args[0], args[1:]...)
+		cmd.Stdin = strings.NewReader(text)
+		if runErr := cmd.Run(); runErr != nil {
+			err = fmt.Errorf("%s: %w", args[0], runErr)
+			continue
+		}
+		return nil
+	}
+	return err
+}
+
+// clipboardCmd puts text on the clipboard and reports the outcome through
+// done. Without a working clipboard tool (e.g. over SSH) it falls back to
+// asking the terminal with an OSC 52 escape sequence. That is written through
+// the program's output while the program holds off rendering, so it can't
+// land in the middle of a frame
+func clipboardCmd(text string, done func(err error) tea.Msg) tea.Cmd {
+	return func() tea.Msg {
+		if copyWithTool(text) == nil {
+			return done(nil)
+		}
+		return tea.Exec(&osc52Copy{text: text}, done)()
+	}
+}
+
+// osc52Copy is a tea.ExecCommand writing the OSC 52 sequence that copies text
+// to the terminal it's given as stdout
+type osc52Copy struct {
+	text string
+	out  io.Writer
+}
+
+func (c *osc52Copy) Run() error {
+	if c.out == nil {
+		return errors.New("no terminal to copy through")
+	}
+	seq := osc52.New(c.text)
+	if strings.HasPrefix(os.Getenv("TERM"), "screen") {
+		seq = seq.Screen()
+	}
+	if _, err := seq.WriteTo(c.out); err != nil {
+		return fmt.Errorf("failed to write to the terminal: %w", err)
+	}
+	return nil
+}
+
+func (c *osc52Copy) SetStdin(io.Reader)    {}
+func (c *osc52Copy) SetStdout(w io.Writer) { c.out = w }
+func (c *osc52Copy) SetStderr(io.Writer)   {}
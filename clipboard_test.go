@@ -0,0 +1,51 @@
+package main
+
+import (
+	"bytes"
+	"encoding/base64"
+	"errors"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+)
+
+func TestCopyWithToolFails(t *testing.T) {
+	if runtime.GOOS != "linux" {
+		t.Skip("fakes a linux clipboard tool")
+	}
+	dir := t.TempDir()
+	t.Setenv("PATH", dir)
+	if err := copyWithTool("text"); !errors.Is(err, errNoClipboardTool) {
+		t.Errorf("copyWithTool without tools = %v, want %v", err, errNoClipboardTool)
+	}
+
+	// A tool that's installed but can't reach a display
+	script := "#!/bin/sh\necho 'Error: Can't open display' >&2\nexit 1\n"
+	if err := os.WriteFile(filepath.Join(dir, "xclip"), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	err := copyWithTool("text")
+	if err == nil || errors.Is(err, errNoClipboardTool) || !strings.HasPrefix(err.Error(), "xclip: ") {
+		t.Errorf("copyWithTool with a failing xclip = %v, want its error", err)
+	}
+}
+
+func TestOSC52Copy(t *testing.T) {
+	t.Setenv("TERM", "xterm-256color")
+	var out bytes.Buffer
+	c := &osc52Copy{text: "a.txt"}
+	c.SetStdout(&out)
+	if err := c.Run(); err != nil {
+		t.Fatalf("Run: %v", err)
+	}
+	want := "\x1b]52;c;" + base64.StdEncoding.EncodeToString([]byte("a.txt")) + "\x07"
+	if out.String() != want {
+		t.Errorf("wrote %q, want %q", out.String(), want)
+	}
+
+	if err := (&osc52Copy{text: "a.txt"}).Run(); err == nil {
+		t.Error("Run without a terminal succeeded")
+	}
+}
@@ -2,14 +2,51 @@ package main
 
 import (
 	"bytes"
+	"errors"
 	"fmt"
 	"os"
+	"os/exec"
+	"runtime"
+	"strings"
+	"unicode/utf8"
 
 	tea "github.com/charmbracelet/bubbletea"
 
 	"github.com/rai/interactive-git/git"
 )
 
+// cp1252High maps bytes 0x80-0x9f as Windows-1252 decodes them; in Latin-1
+// they are control characters a terminal might act on
+var cp1252High = [32]rune{
+	'€', '\ufffd', '‚', 'ƒ', '„', '…', '†', '‡', 'ˆ', '‰', 'Š', '‹', 'Œ', '\ufffd', 'Ž', '\ufffd',
+	'\ufffd', '‘', '’', '“', '”', '•', '–', '—', '˜', '™', 'š', '›', 'œ', '\ufffd', 'ž', 'Ÿ',
+}
+
+// toUTF8 makes text valid UTF-8 for display. Bytes that aren't part of a
+// UTF-8 sequence are read as Windows-1252, the usual encoding of such files
+// and a superset of Latin-1's printable characters. It reports whether
+// anything had to be decoded that way
+func toUTF8(text string) (string, bool) {
+	if utf8.ValidString(text) {
+		return text, false
+	}
+
+	var b strings.Builder
+	b.Grow(len(text) + len(text)/4)
+	for len(text) > 0 {
+		r, size := utf8.DecodeRuneInString(text)
+		if r == utf8.RuneError && size == 1 {
+			r = rune(text[0])
+			if r >= 0x80 && r < 0xa0 {
+				r = cp1252High[r-0x80]
+			}
+		}
+		b.WriteRune(r)
+		text = text[size:]
+	}
+	return b.String(), true
+}
+
 // isBinaryFile checks if a file contains binary data by looking for null bytes
 // and other non-text indicators in the first 8KB of the file
 func isBinaryFile(data []byte) bool {
@@ -51,6 +88,13 @@ type gitStageMsg struct {
 	err   error
 }
 
+type gitStageTrackedMsg struct {
+	count      int
+	submodules int // Submodule pointer changes left out
+	of         int // Modified files in all, when a filter limited the stage
+	err        error
+}
+
 type gitUnstageMsg struct {
 	files []string
 	err   error
@@ -58,6 +102,106 @@ type gitUnstageMsg struct {
 
 type gitRefreshMsg struct{}
 
+type gitIntentToAddMsg struct {
+	files []string
+	err   error
+}
+
+// autoRefreshMsg is the periodic status poll enabled with --refresh
+type autoRefreshMsg struct{}
+
+type patchCheckMsg struct {
+	path    string
+	summary string
+	err     error
+}
+
+type patchApplyMsg struct {
+	path string
+	err  error
+}
+
+type revertMsg struct {
+	ref     string
+	aborted bool // The revert in progress was aborted instead
+	err     error
+}
+
+type redateCheckMsg struct {
+	ref     string
+	date    string
+	summary string // Short hash and subject of the commit
+	pushed  bool
+	err     error
+}
+
+type redateMsg struct {
+	summary string
+	aborted bool // The rebase in progress was aborted instead
+	err     error
+}
+
+type selectionDiffMsg struct {
+	count   int
+	content string
+	err     error
+}
+
+type patchModeMsg struct {
+	files []patchFile
+	err   error
+}
+
+type patchStagedMsg struct {
+	hunks int
+	err   error
+}
+
+type stagedDiffMsg struct {
+	content string
+	err     error
+}
+
+type restoreRefMsg struct {
+	ref   string
+	paths []string
+	err   error
+}
+
+type editorMsg struct {
+	message string
+	err     error
+}
+
+type pagerMsg struct {
+	err error
+}
+
+type gitResolveMsg struct {
+	file string
+	side string // "ours" or "theirs"
+	err  error
+}
+
+type stageProgressMsg struct {
+	stage     bool // Staging, or unstaging
+	remaining []string
+	done      int
+	total     int
+	err       error
+}
+
+// slowOperationMsg fires when the operation started as id may be taking too long
+type slowOperationMsg struct {
+	id int
+}
+
+// keyHintsMsg fires when no key has been pressed for keyHintsDelay since
+// the key hints scheduled as id
+type keyHintsMsg struct {
+	id int
+}
+
 type processingMsg struct {
 	active bool
 }
@@ -65,7 +209,30 @@ type processingMsg struct {
 type gitDiffMsg struct {
 	file    string
 	content string
+	hidden  int // Lines truncated from content
 	err     error
+
+	transcoded bool // content wasn't UTF-8 and was decoded best-effort
+}
+
+type splitDiffMsg struct {
+	file     string
+	staged   string
+	unstaged string
+	err      error
+}
+
+type gitBlameMsg struct {
+	file string
+	line int
+	info *git.BlameInfo
+	err  error
+}
+
+type gitDiffStatMsg struct {
+	staged   map[string]git.DiffStat
+	unstaged map[string]git.DiffStat
+	err      error
 }
 
 type gitCommitMsg struct {
@@ -74,6 +241,23 @@ type gitCommitMsg struct {
 	message string
 }
 
+type fileCommitReadyMsg struct {
+	paths []string
+	err   error
+}
+
+type gitHunkMsg struct {
+	message string
+	err     error
+}
+
+type gitShowCommitMsg struct {
+	ref     string
+	content string
+	sig     git.SigStatus
+	err     error
+}
+
 type gitAmendMsg struct {
 	success bool
 	err     error
@@ -111,7 +295,7 @@ func (m *Model) unstageFilesCmd(files []git.FileItem) tea.Cmd {
 		for _, f := range files {
 			// Only unstage staged files
 			if f.Status == git.StatusStaged {
-				filePaths = append(filePaths, f.Path)
+				filePaths = append(filePaths, f.Paths()...)
 			}
 		}
 
@@ -128,10 +312,56 @@ func (m *Model) unstageFilesCmd(files []git.FileItem) tea.Cmd {
 	}
 }
 
+// stageTrackedCmd stages all modifications to tracked files, skipping untracked ones
+func (m *Model) stageTrackedCmd() tea.Cmd {
+	if m.list.IsFiltered() {
+		return m.stageFilteredTrackedCmd()
+	}
+
+	var exclude []string
+	if m.settings.SkipSubmodules {
+		for path := range m.gitStatus.Submodules {
+			exclude = append(exclude, path)
+		}
+	}
+	count := m.gitStatus.UnstagedCount() - len(exclude)
+	return func() tea.Msg {
+		err := m.gitClient.StageTrackedModifications(exclude...)
+		return gitStageTrackedMsg{count: count, submodules: len(exclude), err: err}
+	}
+}
+
+// stageFilteredTrackedCmd is stageTrackedCmd for the modified files matching
+// the applied filter, leaving the hidden ones unstaged
+func (m *Model) stageFilteredTrackedCmd() tea.Cmd {
+	var paths []string
+	skipped := 0
+	for _, i := range m.visibleFiles() {
+		f := m.files[i]
+		if f.Status != git.StatusUnstaged {
+			continue
+		}
+		if f.Submodule != "" && m.settings.SkipSubmodules {
+			skipped++
+			continue
+		}
+		paths = append(paths, f.Path)
+	}
+	total := m.gitStatus.UnstagedCount()
+	return func() tea.Msg {
+		if len(paths) == 0 {
+			return gitStageTrackedMsg{submodules: skipped, of: total}
+		}
+		err := m.gitClient.Stage(paths...)
+		return gitStageTrackedMsg{count: len(paths), submodules: skipped, of: total, err: err}
+	}
+}
+
 // refreshStatusCmd refreshes the git status
 func (m *Model) refreshStatusCmd() tea.Cmd {
+	untracked := m.untrackedMode
 	return func() tea.Msg {
-		status, err := m.gitClient.Status()
+		status, err := m.gitClient.Status(untracked)
 		if err != nil {
 			return errorMsg{err: fmt.Sprintf("Failed to refresh status: %v", err)}
 		}
@@ -139,102 +369,433 @@ func (m *Model) refreshStatusCmd() tea.Cmd {
 	}
 }
 
-// toggleSelectionCmd handles toggling selection of files
-func (m *Model) toggleSelectionCmd(files []git.FileItem) tea.Cmd {
+// stageBatchSize is the number of paths handled per step of a bulk stage or
+// unstage, so progress can be reported between steps
+const stageBatchSize = 100
+
+// stageBatchCmd stages (or unstages) the next batch of paths and reports progress
+func (m *Model) stageBatchCmd(stage bool, paths []string, done, total int) tea.Cmd {
 	return func() tea.Msg {
-		// Determine if we're staging or unstaging
-		var staged []string
-		var unstaged []string
+		n := min(stageBatchSize, len(paths))
 
-		for _, f := range files {
-			if f.Status == git.StatusStaged {
-				staged = append(staged, f.Path)
-			} else {
-				unstaged = append(unstaged, f.Path)
-			}
+		var err error
+		if stage {
+			err = m.gitClient.Stage(paths[:n]...)
+		} else {
+			err = m.gitClient.Unstage(paths[:n]...)
 		}
 
-		// If we have more unstaged than staged, stage them
-		// Otherwise unstage them
-		if len(unstaged) > len(staged) {
-			err := m.gitClient.Stage(unstaged...)
-			if err != nil {
-				return errorMsg{err: fmt.Sprintf("Failed to stage files: %v", err)}
-			}
-			return statusMsg{msg: fmt.Sprintf("Staged %d file(s)", len(unstaged))}
+		return stageProgressMsg{
+			stage:     stage,
+			remaining: paths[n:],
+			done:      done + n,
+			total:     total,
+			err:       err,
 		}
+	}
+}
+
+// addedFileDiff renders a new file's content as an all-added diff, colored
+// like git's own output, so untracked files preview like tracked ones
+func addedFileDiff(path, content string) string {
+	const (
+		bold  = "\x1b[1m"
+		cyan  = "\x1b[36m"
+		green = "\x1b[32m"
+		reset = "\x1b[m"
+	)
+
+	var b strings.Builder
+	fmt.Fprintf(&b, "%sdiff --git a/%s b/%s%s\n", bold, path, path, reset)
+	fmt.Fprintf(&b, "%snew file (untracked)%s\n", bold, reset)
+	fmt.Fprintf(&b, "%s--- /dev/null%s\n", bold, reset)
+	fmt.Fprintf(&b, "%s+++ b/%s%s\n", bold, path, reset)
+	if content == "" {
+		return b.String() + "(empty file)"
+	}
+
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	fmt.Fprintf(&b, "%s@@ -0,0 +1,%d @@%s\n", cyan, len(lines), reset)
+	for _, line := range lines {
+		fmt.Fprintf(&b, "%s+%s%s\n", green, line, reset)
+	}
+	if !strings.HasSuffix(content, "\n") {
+		b.WriteString("\\ No newline at end of file\n")
+	}
 
-		// Unstage the staged files
-		err := m.gitClient.Unstage(staged...)
+	return strings.TrimSuffix(b.String(), "\n")
+}
+
+// diffCacheKey identifies a cached diff by file, status and diff options.
+// A type change leads its diff, so it's part of the key too
+func diffCacheKey(file git.FileItem, opts git.DiffOptions) string {
+	return fmt.Sprintf("%s\x00%s\x00%s\x00%s", file.Path, file.Status, file.TypeChange, opts.CacheKey())
+}
+
+// openWebCmd opens path, or the commit ref when path is empty, on the
+// origin's web interface in the default browser
+func (m *Model) openWebCmd(path, ref string) tea.Cmd {
+	return func() tea.Msg {
+		url, err := m.gitClient.WebURL(path, ref)
 		if err != nil {
-			return errorMsg{err: fmt.Sprintf("Failed to unstage files: %v", err)}
+			return errorMsg{err: fmt.Sprintf("Failed to open in browser: %v", err)}
+		}
+		if err := openBrowser(url); err != nil {
+			return errorMsg{err: fmt.Sprintf("Failed to open %s: %v", url, err)}
 		}
-		return statusMsg{msg: fmt.Sprintf("Unstaged %d file(s)", len(staged))}
+		return statusMsg{msg: "Opened " + url}
+	}
+}
+
+// openBrowser opens url with the platform's default handler without waiting
+// for it, so the TUI keeps running
+func openBrowser(url string) error {
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", url)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", url)
+	default:
+		cmd = exec.Command("xdg-open", url)
+	}
+	if err := cmd.Start(); err != nil {
+		return err
+	}
+	go cmd.Wait() // Reap the launcher once it hands off to the browser
+	return nil
+}
+
+// pagerCmd suspends the TUI to show file's diff in the user's pager
+func (m *Model) pagerCmd(file git.FileItem) tea.Cmd {
+	cmd := m.gitClient.PagerCommand(file, m.diffOptionsFor(file.Path))
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		// Like `git diff --exit-code`, a --no-index diff exits 1 when the
+		// files differ
+		var exitErr *exec.ExitError
+		if errors.As(err, &exitErr) && exitErr.ExitCode() == 1 {
+			err = nil
+		}
+		return pagerMsg{err: err}
+	})
+}
+
+// fetchSplitDiffCmd fetches both halves of a partially staged file's diff
+func (m *Model) fetchSplitDiffCmd(path string) tea.Cmd {
+	opts := m.diffOptionsFor(path)
+	return func() tea.Msg {
+		staged, err := m.gitClient.Diff(true, opts, path)
+		if err != nil {
+			return splitDiffMsg{file: path, err: err}
+		}
+		unstaged, err := m.gitClient.Diff(false, opts, path)
+		return splitDiffMsg{file: path, staged: staged, unstaged: unstaged, err: err}
+	}
+}
+
+// blameCmd looks up who last changed a line of file as of rev
+func (m *Model) blameCmd(file, rev string, line int) tea.Cmd {
+	return func() tea.Msg {
+		info, err := m.gitClient.BlameLine(rev, file, line)
+		return gitBlameMsg{file: file, line: line, info: info, err: err}
 	}
 }
 
 // fetchDiffCmd fetches the diff for a file
 func (m *Model) fetchDiffCmd(file git.FileItem) tea.Cmd {
+	opts := m.diffOptionsFor(file.Path)
+	limit := m.previewLimit
+	if file.Path == m.previewFull {
+		limit = 0
+	}
+	disk := m.diskCache
 	return func() tea.Msg {
-		// Check cache first
-		if content, ok := m.diffCache[file.Path]; ok {
-			return gitDiffMsg{file: file.Path, content: content, err: nil}
+		return m.loadDiff(file, opts, limit, disk)
+	}
+}
+
+// loadDiff fetches file's diff, from the caches when they have it, cut down
+// to limit lines (none when zero)
+func (m *Model) loadDiff(file git.FileItem, opts git.DiffOptions, limit int, disk *diskCache) gitDiffMsg {
+	// Check cache first
+	cacheKey := diffCacheKey(file, opts)
+	if content, ok := m.diffCache[cacheKey]; ok {
+		return previewDiffMsg(file.Path, content, limit)
+	}
+
+	// Then diffs kept from earlier sessions; untracked files are read
+	// directly, which is no slower than the cache
+	var diskKey string
+	if disk != nil && file.Status != git.StatusUntracked {
+		diskKey = disk.key(file, opts)
+		if content, ok := disk.get(diskKey); ok {
+			m.diffCache[cacheKey] = content
+			return previewDiffMsg(file.Path, content, limit)
 		}
+	}
 
-		// Fetch diff based on file status
-		var content string
-		var err error
+	// Fetch diff based on file status
+	var content string
+	var err error
 
-		switch file.Status {
-		case git.StatusStaged:
-			// Show staged diff
-			content, err = m.gitClient.Diff(file.Path, true)
-		case git.StatusUnstaged:
-			// Show unstaged diff
-			content, err = m.gitClient.Diff(file.Path, false)
-		case git.StatusUntracked:
-			// Show file contents for untracked files
-			contentBytes, readErr := os.ReadFile(file.Path)
-			if readErr != nil {
-				return gitDiffMsg{file: file.Path, content: fmt.Sprintf("Error reading file: %v", readErr), err: nil}
+	switch file.Status {
+	case git.StatusStaged:
+		// Show staged diff, including the original path so renames diff as renames
+		content, err = m.gitClient.Diff(true, opts, file.Paths()...)
+		if err == nil && !strings.Contains(content, "@@") {
+			// Pure renames and mode changes have no hunks; describe them
+			// rather than falling back to the worktree contents
+			if summary, sumErr := m.gitClient.DescribeStagedChange(file.Paths()...); sumErr == nil && summary != "" {
+				content = summary + "\n\n" + content
 			}
+		}
+	case git.StatusUnstaged, git.StatusConflicted:
+		// Show unstaged diff; for conflicts this is the combined diff
+		// with the conflict markers
+		content, err = m.gitClient.Diff(false, opts, file.Path)
+	case git.StatusUntracked:
+		// Untracked directories are listed as one entry in normal mode
+		if strings.HasSuffix(file.Path, "/") {
+			return gitDiffMsg{file: file.Path, content: "Untracked directory\n\nShow all untracked files to list its contents"}
+		}
+		// Show untracked files as an all-added diff
+		contentBytes, readErr := os.ReadFile(m.gitClient.FullPath(file.Path))
+		if readErr != nil {
+			return gitDiffMsg{file: file.Path, content: fmt.Sprintf("Error reading file: %v", readErr), err: nil}
+		}
+		// Check if file is binary
+		if isBinaryFile(contentBytes) {
+			content = "[BINARY] File cannot be previewed"
+		} else {
+			content = addedFileDiff(file.Path, string(contentBytes))
+		}
+	}
+
+	if err != nil {
+		return gitDiffMsg{file: file.Path, content: fmt.Sprintf("Error loading diff: %v", err), err: nil}
+	}
+
+	// Git diffs a type change as the old file deleted and the new one
+	// added under the same path; lead with what actually happened
+	if file.TypeChange != "" && content != "" {
+		content = "Type changed: " + file.TypeChange + "\n\n" + content
+	}
+
+	// Ignoring whitespace can hide every change in the file
+	if content == "" && file.Status != git.StatusUntracked && opts.IgnoreWhitespace {
+		content = "(only whitespace changes, hidden)"
+	}
+
+	// If no diff content (no changes), show the actual file content instead
+	if content == "" && file.Status != git.StatusUntracked {
+		// Try to read the file content instead
+		contentBytes, readErr := os.ReadFile(m.gitClient.FullPath(file.Path))
+		if readErr == nil {
 			// Check if file is binary
 			if isBinaryFile(contentBytes) {
 				content = "[BINARY] File cannot be previewed"
 			} else {
 				content = string(contentBytes)
 			}
+		} else {
+			content = fmt.Sprintf("(File has no changes)\n\nCould not read file: %v", readErr)
+		}
+	}
+
+	// Cache the result
+	m.diffCache[cacheKey] = content
+	if diskKey != "" {
+		disk.put(diskKey, content)
+	}
+
+	return previewDiffMsg(file.Path, content, limit)
+}
+
+// previewDiffMsg prepares fetched content for the preview, cut down to limit
+// lines and made displayable
+func previewDiffMsg(path, content string, limit int) gitDiffMsg {
+	content, hidden := truncateLines(content, limit)
+	content, transcoded := toUTF8(content)
+	return gitDiffMsg{file: path, content: content, hidden: hidden, transcoded: transcoded}
+}
+
+// truncateLines cuts content down to its first limit lines (all of them when
+// limit is zero), noting how many were left out. It returns the number of
+// hidden lines
+func truncateLines(content string, limit int) (string, int) {
+	if limit <= 0 {
+		return content, 0
+	}
+
+	// Find the end of line limit without splitting the whole content
+	end := 0
+	for i := 0; i < limit; i++ {
+		next := strings.IndexByte(content[end:], '\n')
+		if next < 0 {
+			return content, 0
 		}
+		end += next + 1
+	}
+	if end >= len(content) {
+		return content, 0
+	}
 
+	hidden := strings.Count(content[end:], "\n") + 1
+	if strings.HasSuffix(content, "\n") {
+		hidden--
+	}
+	return content[:end] + fmt.Sprintf("… (truncated, %d more lines; press L to load them)", hidden), hidden
+}
+
+// fetchDiffStatsCmd fetches added/deleted line counts for staged and
+// unstaged files
+func (m *Model) fetchDiffStatsCmd() tea.Cmd {
+	return func() tea.Msg {
+		staged, err := m.gitClient.DiffStat(true)
+		if err != nil {
+			return gitDiffStatMsg{err: err}
+		}
+		unstaged, err := m.gitClient.DiffStat(false)
+		return gitDiffStatMsg{staged: staged, unstaged: unstaged, err: err}
+	}
+}
+
+// stageLinesCmd stages the changed lines in [from, to] (preview line indices)
+// of a file's unstaged diff, or the whole hunk containing from
+func (m *Model) stageLinesCmd(file git.FileItem, from, to int, wholeHunk bool, previewLines int) tea.Cmd {
+	opts := m.diffOptionsFor(file.Path)
+	// A staged file's preview is the --cached diff, whose hunks are taken
+	// back out of the index instead
+	staged := file.Status == git.StatusStaged
+	return func() tea.Msg {
+		selected, err := m.selectedHunk(file, staged, staged, opts, from, to, wholeHunk, previewLines)
+		if err != nil {
+			return gitHunkMsg{err: err}
+		}
+
+		verb := "Staged"
+		if staged {
+			verb = "Unstaged"
+			err = m.gitClient.UnstageHunk(file.Path, selected)
+		} else {
+			err = m.gitClient.StageHunk(file.Path, selected)
+		}
 		if err != nil {
-			return gitDiffMsg{file: file.Path, content: fmt.Sprintf("Error loading diff: %v", err), err: nil}
-		}
-
-		// If no diff content (no changes), show the actual file content instead
-		if content == "" && file.Status != git.StatusUntracked {
-			// Try to read the file content instead
-			contentBytes, readErr := os.ReadFile(file.Path)
-			if readErr == nil {
-				// Check if file is binary
-				if isBinaryFile(contentBytes) {
-					content = "[BINARY] File cannot be previewed"
-				} else {
-					content = string(contentBytes)
+			return gitHunkMsg{err: err}
+		}
+
+		if wholeHunk {
+			return gitHunkMsg{message: fmt.Sprintf("%s hunk of %s", verb, file.Path)}
+		}
+		return gitHunkMsg{message: fmt.Sprintf("%s selected lines of %s", verb, file.Path)}
+	}
+}
+
+// discardLinesCmd reverts the changed lines in [from, to] (preview line
+// indices) of a file's unstaged diff in the working tree, or the whole hunk
+// containing from
+func (m *Model) discardLinesCmd(file git.FileItem, from, to int, wholeHunk bool, previewLines int) tea.Cmd {
+	opts := m.diffOptionsFor(file.Path)
+	return func() tea.Msg {
+		// Reverting the worktree treats unselected lines like unstaging does:
+		// additions stay as context, removals are dropped
+		selected, err := m.selectedHunk(file, false, true, opts, from, to, wholeHunk, previewLines)
+		if err != nil {
+			return gitHunkMsg{err: err}
+		}
+		if err := m.gitClient.DiscardHunk(file.Path, selected); err != nil {
+			return gitHunkMsg{err: err}
+		}
+
+		if wholeHunk {
+			return gitHunkMsg{message: fmt.Sprintf("Discarded hunk of %s", file.Path)}
+		}
+		return gitHunkMsg{message: fmt.Sprintf("Discarded selected lines of %s", file.Path)}
+	}
+}
+
+// selectedHunk reads a file's diff again and returns the part of it that
+// preview lines [from, to] cover, or the whole hunk containing from, ready
+// to be applied in reverse if reverse is set
+func (m *Model) selectedHunk(file git.FileItem, staged, reverse bool, opts git.DiffOptions, from, to int, wholeHunk bool, previewLines int) (git.Hunk, error) {
+	diff, err := m.gitClient.RawDiff(staged, opts, file.Path)
+	if err != nil {
+		return git.Hunk{}, err
+	}
+
+	// The preview must still line up with the diff we're patching from
+	if len(strings.Split(diff, "\n")) != previewLines {
+		return git.Hunk{}, fmt.Errorf("diff changed since it was displayed; refresh and try again")
+	}
+
+	var hunk git.Hunk
+	found := false
+	for _, h := range git.ParseHunks(diff) {
+		if h.Contains(from) {
+			hunk, found = h, true
+			break
+		}
+	}
+	if !found || !hunk.Contains(to) {
+		return git.Hunk{}, fmt.Errorf("select lines within a single hunk")
+	}
+
+	// Preview lines map onto hunk body lines just below the @@ header
+	start, end := from-hunk.Offset-1, to-hunk.Offset-1
+	if wholeHunk {
+		start, end = 0, len(hunk.Lines)-1
+	}
+	return hunk.SelectLines(max(start, 0), end, reverse)
+}
+
+// prepareFileCommitCmd stages files so they can be committed on their own
+func (m *Model) prepareFileCommitCmd(files ...git.FileItem) tea.Cmd {
+	return func() tea.Msg {
+		// Files chosen through an unstaged or untracked entry are committed
+		// as they are in the worktree, and need staging first
+		worktree := make(map[string]bool)
+		for _, file := range files {
+			if file.Status != git.StatusStaged {
+				worktree[file.Path] = true
+			}
+		}
+
+		var toStage, paths []string
+		seen := make(map[string]bool)
+		for _, file := range files {
+			if file.Status != git.StatusStaged {
+				toStage = append(toStage, file.Path)
+			} else if !worktree[file.Path] {
+				// Committing a path takes its worktree content, which would
+				// sweep in unstaged edits the user deliberately left out
+				dirty, err := m.gitClient.HasUnstagedChanges(file.Path)
+				if err != nil {
+					return fileCommitReadyMsg{err: err}
+				}
+				if dirty {
+					return fileCommitReadyMsg{err: fmt.Errorf("%s also has unstaged changes; stage or discard them before committing it alone", file.Path)}
+				}
+			}
+
+			for _, path := range file.Paths() {
+				if !seen[path] {
+					seen[path] = true
+					paths = append(paths, path)
 				}
-			} else {
-				content = fmt.Sprintf("(File has no changes)\n\nCould not read file: %v", readErr)
 			}
 		}
 
-		// Cache the result
-		m.diffCache[file.Path] = content
+		if err := m.gitClient.Stage(toStage...); err != nil {
+			return fileCommitReadyMsg{err: err}
+		}
 
-		return gitDiffMsg{file: file.Path, content: content, err: nil}
+		return fileCommitReadyMsg{paths: paths}
 	}
 }
 
-// commitCmd creates a commit with the given message and optional date
-func (m *Model) commitCmd(message, date string) tea.Cmd {
+// commitCmd creates a commit with the given message and optional date,
+// limited to paths when given
+func (m *Model) commitCmd(message, date string, paths []string) tea.Cmd {
 	return func() tea.Msg {
 		// Validate date if provided
 		var validatedDate string
@@ -247,7 +808,7 @@ func (m *Model) commitCmd(message, date string) tea.Cmd {
 		}
 
 		// Create the commit
-		err := m.gitClient.Commit(message, validatedDate)
+		err := m.gitClient.Commit(message, validatedDate, paths...)
 		if err != nil {
 			return gitCommitMsg{success: false, err: err, message: ""}
 		}
@@ -256,19 +817,89 @@ func (m *Model) commitCmd(message, date string) tea.Cmd {
 	}
 }
 
-// amendMessageCmd amends the HEAD commit message
-func (m *Model) amendMessageCmd(message string) tea.Cmd {
+// selectionDiffCmd combines the changes of files into one patch to read
+// together: staged changes first, then unstaged ones, then untracked files as
+// all-added diffs
+func (m *Model) selectionDiffCmd(files []git.FileItem) tea.Cmd {
+	opts := m.diffOptions
+	return func() tea.Msg {
+		var staged, unstaged []string
+		var parts []string
+		for _, f := range files {
+			switch f.Status {
+			case git.StatusStaged:
+				staged = append(staged, f.Paths()...)
+			case git.StatusUnstaged, git.StatusConflicted:
+				unstaged = append(unstaged, f.Path)
+			}
+		}
+
+		if len(staged) > 0 {
+			diff, err := m.gitClient.Diff(true, opts, staged...)
+			if err != nil {
+				return selectionDiffMsg{err: err}
+			}
+			parts = append(parts, "Staged changes:\n\n"+diff)
+		}
+		if len(unstaged) > 0 {
+			diff, err := m.gitClient.Diff(false, opts, unstaged...)
+			if err != nil {
+				return selectionDiffMsg{err: err}
+			}
+			parts = append(parts, "Unstaged changes:\n\n"+diff)
+		}
+
+		var added []string
+		for _, f := range files {
+			// Untracked directories have no single content to show
+			if f.Status != git.StatusUntracked || strings.HasSuffix(f.Path, "/") {
+				continue
+			}
+			content, err := os.ReadFile(m.gitClient.FullPath(f.Path))
+			switch {
+			case err != nil:
+				added = append(added, fmt.Sprintf("%s: %v\n", f.Path, err))
+			case isBinaryFile(content):
+				added = append(added, fmt.Sprintf("%s: [BINARY] File cannot be previewed\n", f.Path))
+			default:
+				added = append(added, addedFileDiff(f.Path, string(content)))
+			}
+		}
+		if len(added) > 0 {
+			parts = append(parts, "Untracked files:\n\n"+strings.Join(added, ""))
+		}
+
+		return selectionDiffMsg{count: len(files), content: strings.Join(parts, "\n")}
+	}
+}
+
+// showCommitCmd fetches the full details of a commit for the preview
+func (m *Model) showCommitCmd(ref string) tea.Cmd {
+	return func() tea.Msg {
+		content, err := m.gitClient.ShowCommit(ref)
+		if err != nil {
+			return gitShowCommitMsg{ref: ref, err: err}
+		}
+		// The badge is extra; an unverifiable commit still shows
+		sig, _ := m.gitClient.VerifyCommit(ref)
+		return gitShowCommitMsg{ref: ref, content: content, sig: sig}
+	}
+}
+
+// amendCmd amends HEAD with a new message and whatever is staged
+func (m *Model) amendCmd(message string) tea.Cmd {
+	resetDate := m.amendResetDate
 	return func() tea.Msg {
 		if message == "" {
 			return gitAmendMsg{success: false, err: fmt.Errorf("commit message cannot be empty"), message: ""}
 		}
 
-		err := m.gitClient.AmendMessage(message)
+		err := m.gitClient.AmendMessage(message, resetDate)
 		if err != nil {
 			return gitAmendMsg{success: false, err: err, message: ""}
 		}
 
-		return gitAmendMsg{success: true, err: nil, message: "[OK] Commit message amended successfully"}
+		return gitAmendMsg{success: true, err: nil, message: "[OK] HEAD amended successfully"}
 	}
 }
 
@@ -283,3 +914,178 @@ func (m *Model) softResetHeadCmd() tea.Cmd {
 		return gitAmendMsg{success: true, err: nil, message: "[OK] HEAD soft reset successfully. Changes staged."}
 	}
 }
+
+// revertCmd creates a commit undoing ref, or aborts the revert in progress
+func (m *Model) revertCmd(ref string, abort bool) tea.Cmd {
+	return func() tea.Msg {
+		if abort {
+			return revertMsg{aborted: true, err: m.gitClient.RevertAbort()}
+		}
+		return revertMsg{ref: ref, err: m.gitClient.Revert(ref, true)}
+	}
+}
+
+// redateCheckCmd looks up the commit to redate, so the confirmation can
+// name it and warn when it's already pushed
+func (m *Model) redateCheckCmd(ref, date string) tea.Cmd {
+	return func() tea.Msg {
+		summary, err := m.gitClient.CommitSummary(ref)
+		if err != nil {
+			return redateCheckMsg{err: err}
+		}
+		pushed, err := m.gitClient.IsPushedCommit(ref)
+		return redateCheckMsg{ref: ref, date: date, summary: summary, pushed: pushed, err: err}
+	}
+}
+
+// redateCmd sets the date of an earlier commit, or aborts the rebase in
+// progress when abort is set
+func (m *Model) redateCmd(ref, date, summary string, abort bool) tea.Cmd {
+	return func() tea.Msg {
+		if abort {
+			return redateMsg{aborted: true, err: m.gitClient.RebaseAbort()}
+		}
+		return redateMsg{summary: summary, err: m.gitClient.RedateCommit(ref, date)}
+	}
+}
+
+// resolveConflictCmd resolves a conflicted file with one side of the merge
+func (m *Model) resolveConflictCmd(file string, ours bool) tea.Cmd {
+	return func() tea.Msg {
+		if ours {
+			return gitResolveMsg{file: file, side: "ours", err: m.gitClient.CheckoutOurs(file)}
+		}
+		return gitResolveMsg{file: file, side: "theirs", err: m.gitClient.CheckoutTheirs(file)}
+	}
+}
+
+// editMessageCmd suspends the TUI to edit the commit message in the user's
+// editor, prefilled with the current draft and the staged files as comments
+func (m *Model) editMessageCmd(draft string) tea.Cmd {
+	path, err := m.gitClient.GitPath("COMMIT_EDITMSG")
+	if err != nil {
+		return func() tea.Msg { return editorMsg{err: err} }
+	}
+
+	var b strings.Builder
+	b.WriteString(draft)
+	b.WriteString("\n\n# Please enter the commit message for your changes. Lines starting\n")
+	b.WriteString("# with '#' will be ignored, and an empty message aborts the commit.\n#\n")
+	b.WriteString("# Changes to be committed:\n")
+	for _, f := range m.gitStatus.Staged {
+		if !m.isCommitPath(f) {
+			continue
+		}
+		if stat, ok := m.stagedStats[f]; ok && !stat.Binary {
+			fmt.Fprintf(&b, "#\t%s (+%d -%d)\n", f, stat.Added, stat.Deleted)
+		} else {
+			fmt.Fprintf(&b, "#\t%s\n", f)
+		}
+	}
+	if err := os.WriteFile(path, []byte(b.String()), 0o644); err != nil {
+		return func() tea.Msg { return editorMsg{err: fmt.Errorf("failed to write %s: %w", path, err)} }
+	}
+
+	cmd, err := m.gitClient.EditorCommand(path)
+	if err != nil {
+		return func() tea.Msg { return editorMsg{err: err} }
+	}
+
+	return tea.ExecProcess(cmd, func(err error) tea.Msg {
+		if err != nil {
+			return editorMsg{err: fmt.Errorf("editor failed: %w", err)}
+		}
+		content, err := os.ReadFile(path)
+		if err != nil {
+			return editorMsg{err: fmt.Errorf("failed to read %s: %w", path, err)}
+		}
+		message, err := m.gitClient.CleanupMessage(string(content))
+		return editorMsg{message: message, err: err}
+	})
+}
+
+// checkPatchCmd dry-runs a patch file so the user sees what it touches first
+func (m *Model) checkPatchCmd(path string, cached bool) tea.Cmd {
+	return func() tea.Msg {
+		summary, err := m.gitClient.CheckPatch(path, cached)
+		return patchCheckMsg{path: path, summary: summary, err: err}
+	}
+}
+
+// applyPatchCmd applies a patch file that passed its check
+func (m *Model) applyPatchCmd(path string, cached bool) tea.Cmd {
+	return func() tea.Msg {
+		return patchApplyMsg{path: path, err: m.gitClient.ApplyPatch(path, cached)}
+	}
+}
+
+// loadPatchModeCmd reads the hunks of every unstaged file for patch mode.
+// Renames and binary files have no hunks to pick from and are left out
+func (m *Model) loadPatchModeCmd(files []git.FileItem) tea.Cmd {
+	return func() tea.Msg {
+		var loaded []patchFile
+		for _, file := range files {
+			if file.Status != git.StatusUnstaged || file.OrigPath != "" {
+				continue
+			}
+			diff, err := m.gitClient.RawDiff(false, git.DiffOptions{}, file.Path)
+			if err != nil {
+				return patchModeMsg{err: err}
+			}
+			if hunks := git.ParseHunks(diff); len(hunks) > 0 {
+				loaded = append(loaded, patchFile{path: file.Path, hunks: hunks})
+			}
+		}
+		return patchModeMsg{files: loaded}
+	}
+}
+
+// stagePatchHunksCmd stages the hunks chosen in patch mode
+func (m *Model) stagePatchHunksCmd(hunks map[string][]git.Hunk) tea.Cmd {
+	return func() tea.Msg {
+		staged := 0
+		for path, fileHunks := range hunks {
+			for _, h := range fileHunks {
+				if err := m.gitClient.StageHunk(path, h); err != nil {
+					return patchStagedMsg{hunks: staged, err: fmt.Errorf("%s: %w", path, err)}
+				}
+				staged++
+			}
+		}
+		return patchStagedMsg{hunks: staged}
+	}
+}
+
+// stagedDiffCmd fetches the staged patch for review
+func (m *Model) stagedDiffCmd(paths []string) tea.Cmd {
+	return func() tea.Msg {
+		content, err := m.gitClient.StagedDiff(paths...)
+		return stagedDiffMsg{content: content, err: err}
+	}
+}
+
+// restoreFromRefCmd replaces files with their content at ref
+func (m *Model) restoreFromRefCmd(ref string, paths []string) tea.Cmd {
+	return func() tea.Msg {
+		return restoreRefMsg{ref: ref, paths: paths, err: m.gitClient.RestoreFromRef(ref, paths...)}
+	}
+}
+
+// intentToAddCmd marks untracked files as intent to add, so they diff as new files
+func (m *Model) intentToAddCmd(files []git.FileItem) tea.Cmd {
+	return func() tea.Msg {
+		var paths []string
+		for _, f := range files {
+			if f.Status == git.StatusUntracked {
+				paths = append(paths, f.Path)
+			}
+		}
+
+		if len(paths) == 0 {
+			return statusMsg{msg: "No untracked files to add"}
+		}
+
+		err := m.gitClient.IntentToAdd(paths...)
+		return gitIntentToAddMsg{files: paths, err: err}
+	}
+}
@@ -0,0 +1,144 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/rai/interactive-git/git"
+	"github.com/rai/interactive-git/ui"
+)
+
+// commitFile is a file changed by the commit being browsed
+type commitFile struct {
+	git.FileItem
+}
+
+// Title implements list.DefaultItem, showing git's change letter and path
+func (f commitFile) Title() string {
+	if f.OrigPath != "" {
+		return fmt.Sprintf("%s %s -> %s", f.StatusSymbol, f.OrigPath, f.Path)
+	}
+	return fmt.Sprintf("%s %s", f.StatusSymbol, f.Path)
+}
+
+// Description implements list.DefaultItem; the title says it all
+func (f commitFile) Description() string {
+	return ""
+}
+
+type commitFilesMsg struct {
+	ref   string
+	files []git.FileItem
+	err   error
+}
+
+type commitFileDiffMsg struct {
+	ref     string
+	path    string
+	content string
+	err     error
+}
+
+// commitFilesCmd lists the files a commit changed
+func (m *Model) commitFilesCmd(ref string) tea.Cmd {
+	return func() tea.Msg {
+		files, err := m.gitClient.CommitFiles(ref)
+		return commitFilesMsg{ref: ref, files: files, err: err}
+	}
+}
+
+// commitFileDiffCmd fetches the diff a commit made to one file
+func (m *Model) commitFileDiffCmd(ref, path string) tea.Cmd {
+	return func() tea.Msg {
+		content, err := m.gitClient.ShowCommitFile(ref, path)
+		return commitFileDiffMsg{ref: ref, path: path, content: content, err: err}
+	}
+}
+
+// enterCommitFiles lists a commit's files to review them one at a time,
+// starting with the first one's diff
+func (m *Model) enterCommitFiles(ref string, files []git.FileItem) tea.Cmd {
+	items := make([]list.Item, len(files))
+	for i, f := range files {
+		items[i] = commitFile{f}
+	}
+
+	delegate := list.NewDefaultDelegate()
+	delegate.ShowDescription = false
+	delegate.SetSpacing(0)
+	m.commitFiles = list.New(items, delegate, 0, 0)
+	m.commitFiles.Title = fmt.Sprintf("Files in %s (%d)", ref, len(files))
+	m.commitFiles.SetShowHelp(false)
+	m.commitFiles.DisableQuitKeybindings()
+	m.commitFilesRef = ref
+	m.commitFileView.SetContent("")
+	m.state = StateCommitFiles
+	m.updateComponentSizes()
+	return m.showCommitFile()
+}
+
+// closeCommitFiles returns to the file list
+func (m *Model) closeCommitFiles() {
+	m.state = StateFileList
+	m.commitFiles = list.Model{}
+	m.commitFilesRef = ""
+	m.commitFileView.SetContent("")
+}
+
+// showCommitFile loads the diff of the file under the cursor
+func (m *Model) showCommitFile() tea.Cmd {
+	f, ok := m.commitFiles.SelectedItem().(commitFile)
+	if !ok {
+		return nil
+	}
+	return m.commitFileDiffCmd(m.commitFilesRef, f.Path)
+}
+
+// handleCommitFilesKeys moves through the commit's files, scrolls the diff
+// or closes the browser
+func (m Model) handleCommitFilesKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
+	// Let the filter prompt have every key while it's open
+	if m.commitFiles.FilterState() == list.Filtering {
+		var cmd tea.Cmd
+		m.commitFiles, cmd = m.commitFiles.Update(msg)
+		return m, tea.Batch(cmd, m.showCommitFile())
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.Close):
+		m.closeCommitFiles()
+		return m, nil
+
+	case key.Matches(msg, m.keys.PageUp), key.Matches(msg, m.keys.PageDown):
+		var cmd tea.Cmd
+		m.commitFileView, cmd = m.commitFileView.Update(msg)
+		return m, cmd
+
+	default:
+		before := m.commitFiles.Index()
+		var cmd tea.Cmd
+		m.commitFiles, cmd = m.commitFiles.Update(msg)
+		if m.commitFiles.Index() != before {
+			return m, tea.Batch(cmd, m.showCommitFile())
+		}
+		return m, cmd
+	}
+}
+
+// updateCommitFileDiff shows a loaded diff, unless the cursor has already
+// moved on to another file
+func (m *Model) updateCommitFileDiff(msg commitFileDiffMsg) {
+	f, ok := m.commitFiles.SelectedItem().(commitFile)
+	if m.state != StateCommitFiles || msg.ref != m.commitFilesRef || !ok || f.Path != msg.path {
+		return
+	}
+	if msg.err != nil {
+		m.commitFileView.SetContent(fmt.Sprintf("Error loading diff: %v", msg.err))
+	} else {
+		m.commitFileView.SetContent(ui.DegradeColors(msg.content, m.colorProfile))
+	}
+	m.commitFileView.GotoTop()
+}
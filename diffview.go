@@ -0,0 +1,237 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/rai/interactive-git/git"
+	"github.com/rai/interactive-git/ui"
+)
+
+type diffViewMsg struct {
+	file    string
+	content string
+}
+
+// diffViewCmd loads the whole of file's diff, however long, for reading
+// full screen
+func (m *Model) diffViewCmd(file git.FileItem) tea.Cmd {
+	opts := m.diffOptionsFor(file.Path)
+	disk := m.diskCache
+	return func() tea.Msg {
+		msg := m.loadDiff(file, opts, 0, disk)
+		return diffViewMsg{file: msg.file, content: msg.content}
+	}
+}
+
+// openDiffView shows a file's diff full screen, from the top
+func (m *Model) openDiffView(file, content string) {
+	m.state = StateDiff
+	m.diffViewFile = file
+	m.diffViewText = content
+	m.diffViewLines = strings.Split(ui.StripColors(content), "\n")
+	m.diffViewAt = -1
+	m.setDiffViewContent()
+	m.diffView.GotoTop()
+}
+
+// closeDiffView returns to the file list
+func (m *Model) closeDiffView() {
+	m.state = StateFileList
+	m.diffViewText = ""
+	m.diffViewLines = nil
+	m.diffViewRows = nil
+	m.diffView.SetContent("")
+	m.diffSearch.Blur()
+}
+
+// setDiffViewContent lays out the diff for the viewport's width, wrapping
+// long lines and marking the line the last jump landed on
+func (m *Model) setDiffViewContent() {
+	width := m.diffView.Width - 2 // Gutter
+	lines := strings.Split(m.diffViewText, "\n")
+	m.diffViewRows = make([]int, len(lines))
+
+	var rows []string
+	for i, line := range lines {
+		m.diffViewRows[i] = len(rows)
+		for j, row := range ui.WrapANSI(line, width) {
+			gutter := "  "
+			if i == m.diffViewAt && j == 0 {
+				gutter = ui.DiffCursorStyle.Render("▶ ")
+			}
+			rows = append(rows, gutter+row)
+		}
+	}
+	m.diffView.SetContent(strings.Join(rows, "\n"))
+}
+
+// diffViewLine returns the line shown at the top of the viewport, or the one
+// last jumped to while it's still on screen
+func (m *Model) diffViewLine() int {
+	top := m.diffView.YOffset
+	if m.diffViewAt >= 0 {
+		row := m.diffViewRows[m.diffViewAt]
+		if row >= top && row < top+m.diffView.Height {
+			return m.diffViewAt
+		}
+	}
+	line := 0
+	for i, start := range m.diffViewRows {
+		if start > top {
+			break
+		}
+		line = i
+	}
+	return line
+}
+
+// jumpDiffView marks a line and scrolls it to the top of the viewport
+func (m *Model) jumpDiffView(line int) {
+	m.diffViewAt = line
+	m.setDiffViewContent()
+	m.diffView.SetYOffset(m.diffViewRows[line])
+}
+
+// nextDiffHunk jumps to the next hunk header, or the previous one when
+// forward is false
+func (m *Model) nextDiffHunk(forward bool) tea.Cmd {
+	hunks := git.ParseHunks(strings.Join(m.diffViewLines, "\n"))
+	if len(hunks) == 0 {
+		m.status = "No hunks"
+		return m.clearStatus()
+	}
+
+	current := m.diffViewLine()
+	target := -1
+	for i, h := range hunks {
+		if forward && h.Offset > current {
+			target = i
+			break
+		}
+		if !forward && h.Offset < current {
+			target = i
+		}
+	}
+	if target < 0 {
+		if forward {
+			m.status = "Last hunk"
+		} else {
+			m.status = "First hunk"
+		}
+		return m.clearStatus()
+	}
+
+	m.jumpDiffView(hunks[target].Offset)
+	m.status = fmt.Sprintf("Hunk %d of %d", target+1, len(hunks))
+	return m.clearStatus()
+}
+
+// searchDiffView jumps to the next line containing the last search, ignoring
+// case, or the previous one when forward is false. The search wraps around
+// the diff; from is where it starts, itself included
+func (m *Model) searchDiffView(from int, forward bool) tea.Cmd {
+	if m.diffQuery == "" {
+		return nil
+	}
+	query := strings.ToLower(m.diffQuery)
+
+	var matches []int
+	for i, line := range m.diffViewLines {
+		if strings.Contains(strings.ToLower(line), query) {
+			matches = append(matches, i)
+		}
+	}
+	if len(matches) == 0 {
+		m.status = fmt.Sprintf("No match for %q", m.diffQuery)
+		return m.clearStatus()
+	}
+
+	// The first match at or after from, or the last one at or before it
+	target := 0
+	if forward {
+		for target < len(matches) && matches[target] < from {
+			target++
+		}
+		target %= len(matches)
+	} else {
+		target = len(matches) - 1
+		for target >= 0 && matches[target] > from {
+			target--
+		}
+		if target < 0 {
+			target = len(matches) - 1
+		}
+	}
+
+	m.jumpDiffView(matches[target])
+	m.status = fmt.Sprintf("Match %d of %d", target+1, len(matches))
+	return m.clearStatus()
+}
+
+// startDiffSearch opens the search prompt of the fullscreen diff
+func (m *Model) startDiffSearch() tea.Cmd {
+	m.diffSearch.Reset()
+	return m.diffSearch.Focus()
+}
+
+// handleDiffViewKeys scrolls, searches and jumps between hunks of the
+// fullscreen diff, or closes it
+func (m Model) handleDiffViewKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
+	// The search prompt takes every key while it's open
+	if m.diffSearch.Focused() {
+		switch {
+		case key.Matches(msg, m.keys.Confirm):
+			m.diffSearch.Blur()
+			m.diffQuery = m.diffSearch.Value()
+			return m, m.searchDiffView(m.diffViewLine(), true)
+
+		case key.Matches(msg, m.keys.Cancel):
+			m.diffSearch.Blur()
+			return m, nil
+
+		default:
+			var cmd tea.Cmd
+			m.diffSearch, cmd = m.diffSearch.Update(msg)
+			return m, cmd
+		}
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.Close):
+		m.closeDiffView()
+		return m, nil
+
+	case key.Matches(msg, m.keys.Search):
+		return m, m.startDiffSearch()
+
+	case key.Matches(msg, m.keys.NextMatch):
+		return m, m.searchDiffView(m.diffViewLine()+1, true)
+
+	case key.Matches(msg, m.keys.PrevMatch):
+		return m, m.searchDiffView(m.diffViewLine()-1, false)
+
+	case key.Matches(msg, m.keys.NextHunk):
+		return m, m.nextDiffHunk(true)
+
+	case key.Matches(msg, m.keys.PrevHunk):
+		return m, m.nextDiffHunk(false)
+
+	case key.Matches(msg, m.keys.Home):
+		m.diffView.GotoTop()
+		return m, nil
+
+	case key.Matches(msg, m.keys.End):
+		m.diffView.GotoBottom()
+		return m, nil
+
+	default:
+		// The viewport's own keys scroll: j/k, f/b and d/u for half pages
+		var cmd tea.Cmd
+		m.diffView, cmd = m.diffView.Update(msg)
+		return m, cmd
+	}
+}
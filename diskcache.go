@@ -0,0 +1,145 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+
+	"github.com/rai/interactive-git/git"
+)
+
+// defaultDiskCacheSize bounds the on-disk diff cache of each repository
+const defaultDiskCacheSize = 64 << 20
+
+// diskCache keeps rendered diffs between sessions, under the user cache
+// directory namespaced by repository. Keys include the modification times
+// of the files involved, so changed content misses rather than needing to
+// be invalidated; stale entries age out when the cache outgrows its bound
+type diskCache struct {
+	dir      string
+	client   *git.Client
+	stamps   []string // Files inside the git directory that change with the index or HEAD
+	maxBytes int64
+}
+
+// newDiskCache opens the disk cache for the client's repository
+func newDiskCache(client *git.Client, maxBytes int64) (*diskCache, error) {
+	base, err := os.UserCacheDir()
+	if err != nil {
+		return nil, fmt.Errorf("failed to locate cache directory: %w", err)
+	}
+	repo := sha256.Sum256([]byte(client.WorkDir()))
+	dir := filepath.Join(base, "igit", hex.EncodeToString(repo[:8]))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create cache directory: %w", err)
+	}
+
+	// The index changes with staging, the HEAD reflog with every commit,
+	// reset or checkout
+	var stamps []string
+	for _, name := range []string{"index", "logs/HEAD"} {
+		path, err := client.GitPath(name)
+		if err != nil {
+			return nil, err
+		}
+		stamps = append(stamps, path)
+	}
+
+	return &diskCache{dir: dir, client: client, stamps: stamps, maxBytes: maxBytes}, nil
+}
+
+// key identifies a diff along with the current state of everything it was
+// computed from
+func (c *diskCache) key(file git.FileItem, opts git.DiffOptions) string {
+	h := sha256.New()
+	fmt.Fprint(h, diffCacheKey(file, opts))
+	paths := append([]string{}, c.stamps...)
+	for _, path := range file.Paths() {
+		paths = append(paths, c.client.FullPath(path))
+	}
+	for _, path := range paths {
+		if info, err := os.Stat(path); err == nil {
+			fmt.Fprintf(h, "\x00%s %d %d", path, info.ModTime().UnixNano(), info.Size())
+		} else {
+			fmt.Fprintf(h, "\x00%s missing", path)
+		}
+	}
+	return hex.EncodeToString(h.Sum(nil))
+}
+
+// get returns the cached diff for key, if any
+func (c *diskCache) get(key string) (string, bool) {
+	path := filepath.Join(c.dir, key)
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return "", false
+	}
+	// Mark the entry as recently used so pruning keeps it
+	now := time.Now()
+	_ = os.Chtimes(path, now, now)
+	return string(content), true
+}
+
+// put stores a diff under key, then prunes the cache back to its bound
+func (c *diskCache) put(key, content string) {
+	// Write to a temporary file first so a concurrent reader never sees a
+	// partial entry
+	tmp, err := os.CreateTemp(c.dir, "tmp-")
+	if err != nil {
+		slog.Debug("disk cache write failed", "err", err)
+		return
+	}
+	_, err = tmp.WriteString(content)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = os.Rename(tmp.Name(), filepath.Join(c.dir, key))
+	}
+	if err != nil {
+		os.Remove(tmp.Name())
+		slog.Debug("disk cache write failed", "err", err)
+		return
+	}
+
+	c.prune()
+}
+
+// prune removes the least recently used entries until the cache fits maxBytes
+func (c *diskCache) prune() {
+	entries, err := os.ReadDir(c.dir)
+	if err != nil {
+		return
+	}
+
+	var infos []os.FileInfo
+	var total int64
+	for _, e := range entries {
+		info, err := e.Info()
+		if err != nil || !info.Mode().IsRegular() {
+			continue
+		}
+		infos = append(infos, info)
+		total += info.Size()
+	}
+	if total <= c.maxBytes {
+		return
+	}
+
+	sort.Slice(infos, func(i, j int) bool {
+		return infos[i].ModTime().Before(infos[j].ModTime())
+	})
+	for _, info := range infos {
+		if total <= c.maxBytes {
+			break
+		}
+		if os.Remove(filepath.Join(c.dir, info.Name())) == nil {
+			total -= info.Size()
+		}
+	}
+}
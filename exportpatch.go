@@ -0,0 +1,107 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/rai/interactive-git/git"
+)
+
+type patchExportedMsg struct {
+	path  string // "" when the patch went to the clipboard
+	files int
+	err   error
+}
+
+// exportPatchCmd writes the patch of files to path, or copies it to the
+// clipboard when path is empty
+func (m *Model) exportPatchCmd(files []string, staged bool, path string) tea.Cmd {
+	return func() tea.Msg {
+		patch, err := m.gitClient.ExportPatch(files, staged)
+		if err != nil {
+			return patchExportedMsg{err: err}
+		}
+		if patch == "" {
+			return patchExportedMsg{err: errors.New("no changes to export")}
+		}
+		files := strings.Count(patch, "\ndiff --git ") + 1
+		if path == "" {
+			return clipboardCmd(patch, func(err error) tea.Msg {
+				if err != nil {
+					return patchExportedMsg{err: fmt.Errorf("failed to copy patch: %w", err)}
+				}
+				return patchExportedMsg{files: files}
+			})()
+		}
+		if err := os.WriteFile(path, []byte(patch), 0o644); err != nil {
+			return patchExportedMsg{err: fmt.Errorf("failed to write patch: %w", err)}
+		}
+		return patchExportedMsg{path: path, files: files}
+	}
+}
+
+// enterExportPatchMode opens the prompt for where to export the selected
+// files' changes, or all staged changes when nothing is selected. Selected
+// files export their unstaged changes unless all of them are staged
+func (m *Model) enterExportPatchMode() {
+	selected := m.getSelectedFiles()
+	m.exportFiles = nil
+	m.exportStaged = len(selected) == 0
+	for _, f := range selected {
+		m.exportFiles = append(m.exportFiles, f.Paths()...)
+		if f.Status != git.StatusStaged {
+			m.exportStaged = false
+		}
+	}
+
+	m.state = StateExportPatch
+	m.exportInput.Reset()
+	m.exportInput.Focus()
+}
+
+// cancelExportPatch closes the export prompt
+func (m *Model) cancelExportPatch() {
+	m.state = StateFileList
+	m.exportFiles = nil
+	m.exportInput.Blur()
+}
+
+// handleExportPatchKeys handles keys in the export prompt. An existing file
+// is only replaced once confirmed
+func (m Model) handleExportPatchKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Confirm):
+		path := expandHome(strings.TrimSpace(m.exportInput.Value()))
+		files, staged := m.exportFiles, m.exportStaged
+		export := func(m *Model) tea.Cmd {
+			m.cancelExportPatch()
+			return m.runOperation("git diff", m.exportPatchCmd(files, staged, path))
+		}
+		if path != "" {
+			if _, err := os.Stat(path); err == nil {
+				m.askDestructive(fmt.Sprintf("%s already exists. Overwrite it?", path), export)
+				return m, nil
+			}
+		}
+		cmd := export(&m)
+		return m, cmd
+
+	case key.Matches(msg, m.keys.ToggleCached):
+		m.exportStaged = !m.exportStaged
+		return m, nil
+
+	case key.Matches(msg, m.keys.Cancel):
+		m.cancelExportPatch()
+		return m, nil
+
+	default:
+		var cmd tea.Cmd
+		m.exportInput, cmd = m.exportInput.Update(msg)
+		return m, cmd
+	}
+}
@@ -0,0 +1,138 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/rai/interactive-git/git"
+)
+
+// flaggedFile is a file listed with the index bits hiding its changes
+type flaggedFile struct {
+	git.FlaggedFile
+}
+
+// FilterValue implements list.Item, matching on the path
+func (f flaggedFile) FilterValue() string {
+	return f.Path
+}
+
+// Title implements list.DefaultItem
+func (f flaggedFile) Title() string {
+	return f.Path
+}
+
+// Description implements list.DefaultItem, naming the bits that are set
+func (f flaggedFile) Description() string {
+	return "[" + f.Flags() + "]"
+}
+
+type flaggedFilesMsg struct {
+	files   []git.FlaggedFile
+	refresh bool // Only updates the list if it's still open
+	err     error
+}
+
+type flagToggledMsg struct {
+	path         string
+	skipWorktree bool // Which bit changed; assume-unchanged otherwise
+	on           bool
+	err          error
+}
+
+// flaggedFilesCmd lists the files with assume-unchanged or skip-worktree
+// set, to open the list or, with refresh, to update it
+func (m *Model) flaggedFilesCmd(refresh bool) tea.Cmd {
+	return func() tea.Msg {
+		files, err := m.gitClient.FlaggedFiles()
+		return flaggedFilesMsg{files: files, refresh: refresh, err: err}
+	}
+}
+
+// setFlagCmd sets or clears path's skip-worktree or assume-unchanged bit
+func (m *Model) setFlagCmd(path string, skipWorktree, on bool) tea.Cmd {
+	return func() tea.Msg {
+		var err error
+		if skipWorktree {
+			err = m.gitClient.SkipWorktree(path, on)
+		} else {
+			err = m.gitClient.AssumeUnchanged(path, on)
+		}
+		return flagToggledMsg{path: path, skipWorktree: skipWorktree, on: on, err: err}
+	}
+}
+
+// showFlaggedFiles lists the flagged files, or refreshes the list when it's
+// already open. The list closes once no file is flagged
+func (m *Model) showFlaggedFiles(files []git.FlaggedFile) {
+	if len(files) == 0 {
+		if m.state == StateFlaggedFiles {
+			m.closeFlaggedFiles()
+		}
+		m.status = "No files have hidden changes"
+		return
+	}
+
+	items := make([]list.Item, len(files))
+	for i, f := range files {
+		items[i] = flaggedFile{f}
+	}
+	if m.state == StateFlaggedFiles {
+		m.flagged.SetItems(items)
+		return
+	}
+
+	m.flagged = list.New(items, list.NewDefaultDelegate(), 0, 0)
+	m.flagged.SetShowTitle(false)
+	m.flagged.SetShowHelp(false)
+	m.flagged.DisableQuitKeybindings()
+	m.state = StateFlaggedFiles
+	m.updateComponentSizes()
+}
+
+// closeFlaggedFiles returns to the file list
+func (m *Model) closeFlaggedFiles() {
+	m.state = StateFileList
+	m.flagged = list.Model{}
+}
+
+// handleFlaggedFilesKeys toggles the bits of the file under the cursor or
+// closes the list
+func (m Model) handleFlaggedFilesKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
+	// Let the filter prompt have every key while it's open
+	if m.flagged.SettingFilter() {
+		var cmd tea.Cmd
+		m.flagged, cmd = m.flagged.Update(msg)
+		return m, cmd
+	}
+
+	f, ok := m.flagged.SelectedItem().(flaggedFile)
+	switch {
+	case key.Matches(msg, m.keys.Close):
+		m.closeFlaggedFiles()
+		return m, nil
+
+	case ok && key.Matches(msg, m.keys.ToggleAssumeUnchanged):
+		return m, m.runOperation("git update-index", m.setFlagCmd(f.Path, false, !f.AssumeUnchanged))
+
+	case ok && key.Matches(msg, m.keys.ToggleSkipWorktree):
+		return m, m.runOperation("git update-index", m.setFlagCmd(f.Path, true, !f.SkipWorktree))
+
+	default:
+		var cmd tea.Cmd
+		m.flagged, cmd = m.flagged.Update(msg)
+		return m, cmd
+	}
+}
+
+// flagToggledStatus describes a changed bit for the status line
+func flagToggledStatus(msg flagToggledMsg) string {
+	flag := "assume-unchanged"
+	if msg.skipWorktree {
+		flag = "skip-worktree"
+	}
+	return fmt.Sprintf("%s: %s %s", msg.path, flag, onOff(msg.on))
+}
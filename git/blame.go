@@ -0,0 +1,70 @@
+package git
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// RevIndex names the index as a revision to BlameLine
+const RevIndex = ":"
+
+// BlameLine returns the commit that last changed line of file as of rev: a
+// commit, RevIndex for the staged content, or "" for the working tree
+func (c *Client) BlameLine(rev, file string, line int) (*BlameInfo, error) {
+	lines := fmt.Sprintf("%d,%d", line, line)
+
+	var output string
+	var err error
+	switch rev {
+	case RevIndex:
+		// blame has no way to name the index, so annotate its content instead
+		var content string
+		content, err = c.execGit("show", ":"+file)
+		if err == nil {
+			output, err = c.execGitStdin(content, "blame", "--porcelain", "-L", lines, "--contents", "-", "--", file)
+		}
+	case "":
+		output, err = c.execGit("blame", "--porcelain", "-L", lines, "--", file)
+	default:
+		output, err = c.execGit("blame", "--porcelain", "-L", lines, rev, "--", file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to blame %s:%d: %w", file, line, err)
+	}
+
+	return parseBlamePorcelain(output)
+}
+
+// parseBlamePorcelain parses the first entry of `git blame --porcelain`
+//
+// Format: a "HASH ORIG FINAL [COUNT]" line followed by "key value" headers
+// and the line itself prefixed with a tab
+func parseBlamePorcelain(output string) (*BlameInfo, error) {
+	lines := strings.Split(output, "\n")
+	fields := strings.Fields(lines[0])
+	if len(fields) < 3 {
+		return nil, fmt.Errorf("unexpected blame output: %q", lines[0])
+	}
+
+	info := &BlameInfo{Hash: fields[0]}
+	for _, line := range lines[1:] {
+		if strings.HasPrefix(line, "\t") {
+			break // The annotated line ends the entry
+		}
+		key, value, _ := strings.Cut(line, " ")
+		switch key {
+		case "author":
+			info.Author = value
+		case "author-time":
+			if secs, err := strconv.ParseInt(value, 10, 64); err == nil {
+				info.Time = time.Unix(secs, 0)
+			}
+		case "summary":
+			info.Summary = value
+		}
+	}
+
+	return info, nil
+}
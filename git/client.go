@@ -1,67 +1,353 @@
 package git
 
 import (
+	"bytes"
 	"context"
+	"errors"
 	"fmt"
+	"log/slog"
 	"os"
 	"os/exec"
 	"path/filepath"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
 	"time"
 )
 
 // Client wraps git command execution
 type Client struct {
 	workDir string
+	gitPath string // git executable run for every command
 	timeout time.Duration
+
+	mu       sync.Mutex // Guards warnings; commands run concurrently
+	warnings []string   // Kept by keepWarnings, see TakeWarnings
+}
+
+// executable is the git binary used by new clients, see SetExecutable
+var executable = "git"
+
+// SetExecutable sets the git binary used by clients created afterwards,
+// either a name looked up in PATH or a path to the executable
+func SetExecutable(path string) {
+	executable = path
 }
 
+// ErrNotRepository is returned by NewClient for a directory outside any
+// git repository
+var ErrNotRepository = errors.New("not a git repository")
+
+// ErrNoWorkTree is returned by NewClient for a bare repository, or from
+// inside a git directory, where there are no files to show
+var ErrNoWorkTree = errors.New("repository has no working tree")
+
+// ErrGitNotFound is returned by NewClient when the git executable isn't
+// installed or isn't where SetExecutable pointed
+var ErrGitNotFound = errors.New("git executable not found")
+
+// ErrNoAccess is returned by NewClient for a directory, or a repository in
+// it, that the user isn't allowed to read
+var ErrNoAccess = errors.New("permission denied")
+
+// ErrUnsafeRepository is returned by NewClient for a repository owned by
+// another user, which git refuses to work in until it's listed in the
+// safe.directory setting
+var ErrUnsafeRepository = errors.New("repository is owned by someone else")
+
 // NewClient creates a new git client for the given directory
 func NewClient(dir string) (*Client, error) {
 	absDir, err := filepath.Abs(dir)
 	if err != nil {
 		return nil, fmt.Errorf("failed to get absolute path: %w", err)
 	}
-
-	// Verify it's a git repository
-	cmd := exec.Command("git", "rev-parse", "--git-dir")
-	cmd.Dir = absDir
-	if err := cmd.Run(); err != nil {
-		return nil, fmt.Errorf("not a git repository: %s", absDir)
+	gitPath, workDir, err := checkRepo(absDir)
+	if err != nil {
+		return nil, err
 	}
-
 	return &Client{
-		workDir: absDir,
+		workDir: workDir,
+		gitPath: gitPath,
 		timeout: 10 * time.Second,
 	}, nil
 }
 
+// CheckRepo reports why dir can't be opened as a repository, as one of the
+// Err values above wrapped with details, or nil when it can
+func CheckRepo(dir string) error {
+	absDir, err := filepath.Abs(dir)
+	if err != nil {
+		return fmt.Errorf("failed to get absolute path: %w", err)
+	}
+	_, _, err = checkRepo(absDir)
+	return err
+}
+
+// checkRepo finds the git executable and the working tree of the repository
+// containing absDir
+func checkRepo(absDir string) (gitPath, workDir string, err error) {
+	if info, err := os.Stat(absDir); err != nil {
+		if errors.Is(err, os.ErrPermission) {
+			return "", "", fmt.Errorf("%w: %s", ErrNoAccess, absDir)
+		}
+		return "", "", fmt.Errorf("%w: %v", ErrNotRepository, err)
+	} else if !info.IsDir() {
+		return "", "", fmt.Errorf("%w: %s is not a directory", ErrNotRepository, absDir)
+	}
+
+	// Make sure git itself is available, so a missing binary isn't
+	// reported as a missing repository
+	gitPath, err = exec.LookPath(executable)
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %s", ErrGitNotFound, executable)
+	}
+
+	// Verify it's a git repository
+	output, err := revParse(gitPath, absDir, "--is-bare-repository")
+	if err != nil {
+		return "", "", err
+	}
+	if output == "true" {
+		return "", "", fmt.Errorf("%w: %s is a bare repository", ErrNoWorkTree, absDir)
+	}
+
+	// Let git say where the working tree is rather than assuming dir: it
+	// may be a subdirectory, or GIT_DIR and GIT_WORK_TREE may point elsewhere
+	output, err = revParse(gitPath, absDir, "--show-toplevel")
+	if err != nil {
+		return "", "", fmt.Errorf("%w: %s is inside the git directory", ErrNoWorkTree, absDir)
+	}
+	return gitPath, filepath.Clean(output), nil
+}
+
+// revParse runs git rev-parse in dir before any Client exists, telling a
+// missing repository from one git won't open from what it printed
+func revParse(gitPath, dir string, args ...string) (string, error) {
+	cmd := exec.Command(gitPath, append([]string{"rev-parse"}, args...)...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+	output, err := cmd.Output()
+	if err == nil {
+		return strings.TrimSpace(string(output)), nil
+	}
+
+	report := stderr.String()
+	switch {
+	case strings.Contains(report, "dubious ownership"):
+		// git names the repository's top level, which safe.directory wants
+		repo := dir
+		if _, rest, ok := strings.Cut(report, "repository at '"); ok {
+			repo, _, _ = strings.Cut(rest, "'")
+		}
+		return "", fmt.Errorf("%w: %s", ErrUnsafeRepository, repo)
+	case strings.Contains(report, "Permission denied"):
+		return "", fmt.Errorf("%w: %s", ErrNoAccess, dir)
+	case errors.Is(err, exec.ErrNotFound), errors.Is(err, os.ErrNotExist), errors.Is(err, os.ErrPermission):
+		// The executable vanished or can't be run
+		return "", fmt.Errorf("%w: %v", ErrGitNotFound, err)
+	}
+	return "", fmt.Errorf("%w: %s", ErrNotRepository, dir)
+}
+
+// maxLoggedOutput caps how much of a command's output is written to the log
+const maxLoggedOutput = 1024
+
+// logger records every git invocation; output is discarded until SetLogger
+// is called, so logging stays opt-in
+var logger = slog.New(slog.DiscardHandler)
+
+// SetLogger sends a record of every git command run by any Client to l
+func SetLogger(l *slog.Logger) {
+	logger = l
+}
+
 // execGit executes a git command and returns its output
 func (c *Client) execGit(args ...string) (string, error) {
+	return c.execGitStdin("", args...)
+}
+
+// execGitStdin executes a git command with input fed to its stdin
+func (c *Client) execGitStdin(input string, args ...string) (string, error) {
+	return c.runGit(input, nil, args...)
+}
+
+// execGitEnv executes a git command with extra environment variables
+func (c *Client) execGitEnv(env []string, args ...string) (string, error) {
+	return c.runGit("", env, args...)
+}
+
+// runGit executes a git command in the working directory, feeding it input
+// and adding env to the inherited environment. A failed command still
+// returns its stdout, for commands like diff --no-index that exit with 1
+// to report differences
+func (c *Client) runGit(input string, env []string, args ...string) (string, error) {
 	ctx, cancel := context.WithTimeout(context.Background(), c.timeout)
 	defer cancel()
 
-	cmd := exec.CommandContext(ctx, "git", args...)
+	cmd := exec.CommandContext(ctx, c.gitPath, args...)
 	cmd.Dir = c.workDir
+	if input != "" {
+		cmd.Stdin = strings.NewReader(input)
+	}
+	if len(env) > 0 {
+		cmd.Env = append(os.Environ(), env...)
+	}
+
+	// Only stdout is parsed; stderr carries errors, progress and warnings
+	// such as core.autocrlf's line ending notices, which must not end up in
+	// status or diff content
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
 
-	output, err := cmd.CombinedOutput()
+	start := time.Now()
+	err := cmd.Run()
+	logInvocation(args, time.Since(start), err, append(stdout.Bytes(), stderr.Bytes()...))
 	if err != nil {
-		return "", fmt.Errorf("git %s failed: %w\n%s", args[0], err, string(output))
+		report := stderr.String()
+		if strings.TrimSpace(report) == "" {
+			report = stdout.String()
+		}
+		return stdout.String(), fmt.Errorf("git %s failed: %w\n%s", args[0], err, report)
+	}
+
+	c.keepWarnings(stderr.String())
+	return stdout.String(), nil
+}
+
+// maxWarnings bounds the warnings kept until TakeWarnings collects them
+const maxWarnings = 20
+
+// keepWarnings records the "warning:" lines a successful command printed
+func (c *Client) keepWarnings(stderr string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, line := range strings.Split(stderr, "\n") {
+		line = strings.TrimSpace(line)
+		if !strings.HasPrefix(line, "warning: ") || slices.Contains(c.warnings, line) {
+			continue
+		}
+		if len(c.warnings) == maxWarnings {
+			c.warnings = c.warnings[1:]
+		}
+		c.warnings = append(c.warnings, line)
+	}
+}
+
+// TakeWarnings returns the warnings git printed since the last call, such as
+// line ending conversions, and forgets them. They are informational: the
+// commands printing them succeeded
+func (c *Client) TakeWarnings() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	warnings := c.warnings
+	c.warnings = nil
+	return warnings
+}
+
+// logInvocation writes a debug record of a finished git command
+func logInvocation(args []string, duration time.Duration, err error, output []byte) {
+	exitCode := 0
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		exitCode = exitErr.ExitCode()
+	} else if err != nil {
+		exitCode = -1 // Never started, or killed by the timeout
 	}
 
-	return string(output), nil
+	out := string(output)
+	if len(out) > maxLoggedOutput {
+		out = out[:maxLoggedOutput] + "…"
+	}
+
+	logger.Debug("git",
+		"args", args,
+		"duration", duration,
+		"exit", exitCode,
+		"output", out,
+	)
 }
 
-// CurrentBranch returns the name of the current branch
+// DetachedHead is reported as the branch name when HEAD is detached
+const DetachedHead = "HEAD"
+
+// CurrentBranch returns the name of the current branch, or DetachedHead
 func (c *Client) CurrentBranch() (string, error) {
 	output, err := c.execGit("branch", "--show-current")
 	if err != nil {
-		return "", err
+		// `branch --show-current` needs git 2.22; older releases can still
+		// resolve the symbolic name (which is "HEAD" when detached)
+		output, err = c.execGit("rev-parse", "--abbrev-ref", "HEAD")
+		if err != nil {
+			return "", err
+		}
+	}
+
+	branch := strings.TrimSpace(output)
+	if branch == "" {
+		// --show-current prints nothing on a detached HEAD
+		return DetachedHead, nil
+	}
+	return branch, nil
+}
+
+// Version is a parsed git release version
+type Version struct {
+	Major int
+	Minor int
+	Patch int
+}
+
+// MinVersion is the oldest git release providing every flag we rely on
+// (`git branch --show-current` was added in 2.22)
+var MinVersion = Version{Major: 2, Minor: 22}
+
+// versionPattern matches the numeric part of `git version` output, which may
+// carry vendor suffixes like "2.39.3 (Apple Git-146)" or "2.41.0.windows.1"
+var versionPattern = regexp.MustCompile(`(\d+)\.(\d+)(?:\.(\d+))?`)
+
+// String formats the version as MAJOR.MINOR.PATCH
+func (v Version) String() string {
+	return fmt.Sprintf("%d.%d.%d", v.Major, v.Minor, v.Patch)
+}
+
+// AtLeast reports whether v is the same as or newer than other
+func (v Version) AtLeast(other Version) bool {
+	if v.Major != other.Major {
+		return v.Major > other.Major
+	}
+	if v.Minor != other.Minor {
+		return v.Minor > other.Minor
+	}
+	return v.Patch >= other.Patch
+}
+
+// GitVersion returns the version of the git executable
+func (c *Client) GitVersion() (Version, error) {
+	output, err := c.execGit("version")
+	if err != nil {
+		return Version{}, err
+	}
+	return parseVersion(output)
+}
+
+// parseVersion parses the output of `git version`
+func parseVersion(output string) (Version, error) {
+	match := versionPattern.FindStringSubmatch(output)
+	if match == nil {
+		return Version{}, fmt.Errorf("unrecognized git version: %q", output)
 	}
-	// Remove trailing newline
-	if len(output) > 0 && output[len(output)-1] == '\n' {
-		output = output[:len(output)-1]
+
+	var v Version
+	v.Major, _ = strconv.Atoi(match[1])
+	v.Minor, _ = strconv.Atoi(match[2])
+	if match[3] != "" {
+		v.Patch, _ = strconv.Atoi(match[3])
 	}
-	return output, nil
+	return v, nil
 }
 
 // WorkDir returns the working directory of the git repository
@@ -69,10 +355,45 @@ func (c *Client) WorkDir() string {
 	return c.workDir
 }
 
-// IsRepo checks if a directory is a git repository
+// FullPath returns the absolute path of a file named relative to the
+// working tree, as git reports them
+func (c *Client) FullPath(path string) string {
+	return c.absPath(path)
+}
+
+// GitDir returns the absolute path of the repository's git directory.
+// In linked worktrees and submodules `.git` is a file pointing elsewhere,
+// so never assume `<workDir>/.git` is a directory; ask git instead
+func (c *Client) GitDir() (string, error) {
+	output, err := c.execGit("rev-parse", "--git-dir")
+	if err != nil {
+		return "", fmt.Errorf("failed to locate git directory: %w", err)
+	}
+	return c.absPath(strings.TrimSpace(output)), nil
+}
+
+// GitPath resolves a path inside the git directory, such as "index" or
+// "hooks/pre-commit", honoring worktree-specific and shared locations
+func (c *Client) GitPath(name string) (string, error) {
+	output, err := c.execGit("rev-parse", "--git-path", name)
+	if err != nil {
+		return "", fmt.Errorf("failed to resolve git path %s: %w", name, err)
+	}
+	return c.absPath(strings.TrimSpace(output)), nil
+}
+
+// absPath resolves a path git printed relative to the working directory
+func (c *Client) absPath(path string) string {
+	if filepath.IsAbs(path) {
+		return path
+	}
+	return filepath.Join(c.workDir, path)
+}
+
+// IsRepo checks if a directory is a git repository, see CheckRepo for why
+// it isn't
 func IsRepo(dir string) bool {
-	_, err := NewClient(dir)
-	return err == nil
+	return CheckRepo(dir) == nil
 }
 
 // GetCurrentWorkingDir returns the current working directory
@@ -0,0 +1,213 @@
+package git
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestCurrentBranch(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.commit("initial")
+
+	branch, err := r.client.CurrentBranch()
+	if err != nil || branch != "main" {
+		t.Errorf("CurrentBranch = %q, %v, want main", branch, err)
+	}
+
+	r.git("checkout", "-q", "--detach")
+	branch, err = r.client.CurrentBranch()
+	if err != nil || branch != DetachedHead {
+		t.Errorf("CurrentBranch when detached = %q, %v, want %s", branch, err, DetachedHead)
+	}
+}
+
+func TestCurrentBranchFallback(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.commit("initial")
+
+	// Behave like git older than 2.22, which doesn't know --show-current
+	fakeGit(t, `if [ "$1" = branch ] && [ "$2" = --show-current ]; then
+	echo "error: unknown option 'show-current'" >&2
+	exit 129
+fi`)
+	client, err := NewClient(r.dir)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	branch, err := client.CurrentBranch()
+	if err != nil || branch != "main" {
+		t.Errorf("CurrentBranch = %q, %v, want main", branch, err)
+	}
+
+	r.git("checkout", "-q", "--detach")
+	branch, err = client.CurrentBranch()
+	if err != nil || branch != DetachedHead {
+		t.Errorf("CurrentBranch when detached = %q, %v, want %s", branch, err, DetachedHead)
+	}
+}
+
+func TestGitDirLinkedWorktree(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.commit("initial")
+
+	worktree := filepath.Join(t.TempDir(), "linked")
+	r.git("worktree", "add", "-q", "-b", "linked", worktree)
+	if info, err := os.Stat(filepath.Join(worktree, ".git")); err != nil || info.IsDir() {
+		t.Fatalf(".git in a linked worktree should be a file: %v, %v", info, err)
+	}
+
+	client, err := NewClient(worktree)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	mainGitDir := strings.TrimSpace(r.git("rev-parse", "--absolute-git-dir"))
+
+	gitDir, err := client.GitDir()
+	if err != nil {
+		t.Fatalf("GitDir: %v", err)
+	}
+	if want := filepath.Join(mainGitDir, "worktrees", "linked"); !sameFile(gitDir, want) {
+		t.Errorf("GitDir = %s, want %s", gitDir, want)
+	}
+
+	// The index belongs to the worktree, hooks are shared with the main one
+	index, err := client.GitPath("index")
+	if err != nil {
+		t.Fatalf("GitPath(index): %v", err)
+	}
+	if want := filepath.Join(mainGitDir, "worktrees", "linked", "index"); !sameFile(index, want) {
+		t.Errorf("GitPath(index) = %s, want %s", index, want)
+	}
+	hooks, err := client.GitPath("hooks")
+	if err != nil {
+		t.Fatalf("GitPath(hooks): %v", err)
+	}
+	if want := filepath.Join(mainGitDir, "hooks"); !sameFile(hooks, want) {
+		t.Errorf("GitPath(hooks) = %s, want %s", hooks, want)
+	}
+
+	// Status works on the worktree's own changes
+	if err := os.WriteFile(filepath.Join(worktree, "a.txt"), []byte("two\n"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	status, err := client.Status(UntrackedNormal)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	assertPaths(t, "Unstaged", status.Unstaged, "a.txt")
+	if status.Branch != "linked" {
+		t.Errorf("Branch = %q, want linked", status.Branch)
+	}
+}
+
+func TestNewClientNoWorkTree(t *testing.T) {
+	r := newTestRepo(t)
+	bare := filepath.Join(t.TempDir(), "bare.git")
+	r.git("init", "-q", "--bare", bare)
+
+	for _, dir := range []string{bare, filepath.Join(bare, "refs"), filepath.Join(r.dir, ".git")} {
+		if _, err := NewClient(dir); !errors.Is(err, ErrNoWorkTree) {
+			t.Errorf("NewClient(%s) = %v, want ErrNoWorkTree", dir, err)
+		}
+		if err := CheckRepo(dir); !errors.Is(err, ErrNoWorkTree) {
+			t.Errorf("CheckRepo(%s) = %v, want ErrNoWorkTree", dir, err)
+		}
+	}
+
+	if err := CheckRepo(t.TempDir()); !errors.Is(err, ErrNotRepository) {
+		t.Errorf("CheckRepo outside a repository = %v, want ErrNotRepository", err)
+	}
+}
+
+// TestNewClientGitDirEnv checks GIT_DIR and GIT_WORK_TREE are followed, even
+// from a directory outside the repository
+func TestNewClientGitDirEnv(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.commit("initial")
+	r.write("a.txt", "two\n")
+
+	t.Setenv("GIT_DIR", filepath.Join(r.dir, ".git"))
+	t.Setenv("GIT_WORK_TREE", r.dir)
+	client, err := NewClient(t.TempDir())
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+	if !sameFile(client.WorkDir(), r.dir) {
+		t.Errorf("WorkDir = %s, want %s", client.WorkDir(), r.dir)
+	}
+	status, err := client.Status(UntrackedNormal)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	assertPaths(t, "Unstaged", status.Unstaged, "a.txt")
+}
+
+// sameFile reports whether two paths name the same file, after resolving
+// symlinks such as a temporary directory's
+func sameFile(a, b string) bool {
+	ai, aerr := os.Stat(a)
+	bi, berr := os.Stat(b)
+	if aerr != nil || berr != nil {
+		return filepath.Clean(a) == filepath.Clean(b)
+	}
+	return os.SameFile(ai, bi)
+}
+
+// TestWarningsKeptApart checks warnings git prints on stderr, such as
+// core.autocrlf's, stay out of the output that gets parsed and are kept for
+// TakeWarnings instead
+func TestWarningsKeptApart(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.commit("initial")
+	r.write("a.txt", "two\n")
+	r.write("b.txt", "new\n")
+
+	wantStatus := r.status()
+	wantDiff, err := r.client.Diff(false, DiffOptions{}, "a.txt")
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+
+	const warning = "warning: LF will be replaced by CRLF in a.txt."
+	fakeGit(t, `echo "`+warning+`" >&2`)
+	client, err := NewClient(r.dir)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	status, err := client.Status(UntrackedNormal)
+	if err != nil {
+		t.Fatalf("Status: %v", err)
+	}
+	assertPaths(t, "Unstaged", status.Unstaged, wantStatus.Unstaged...)
+	assertPaths(t, "Untracked", status.Untracked, wantStatus.Untracked...)
+	assertPaths(t, "Staged", status.Staged, wantStatus.Staged...)
+	diff, err := client.Diff(false, DiffOptions{}, "a.txt")
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	if diff != wantDiff {
+		t.Errorf("Diff with a warning on stderr = %q, want %q", diff, wantDiff)
+	}
+	if err := client.Stage("a.txt"); err != nil {
+		t.Fatalf("Stage: %v", err)
+	}
+
+	// Every command warned, but the same line is only kept once
+	warnings := client.TakeWarnings()
+	if len(warnings) != 1 || warnings[0] != warning {
+		t.Errorf("TakeWarnings = %q, want [%q]", warnings, warning)
+	}
+	if warnings := client.TakeWarnings(); len(warnings) != 0 {
+		t.Errorf("TakeWarnings again = %q, want them cleared", warnings)
+	}
+}
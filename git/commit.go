@@ -1,13 +1,18 @@
 package git
 
 import (
+	"errors"
 	"fmt"
+	"os"
+	"os/exec"
 	"strings"
 	"time"
 )
 
-// Commit creates a new commit with the given message and optional date
-func (c *Client) Commit(message, date string) error {
+// Commit creates a new commit with the given message and optional date.
+// When paths are given only those paths are committed (`git commit -- <paths>`),
+// leaving anything else in the index staged
+func (c *Client) Commit(message, date string, paths ...string) error {
 	if message == "" {
 		return fmt.Errorf("commit message cannot be empty")
 	}
@@ -19,6 +24,11 @@ func (c *Client) Commit(message, date string) error {
 		args = append(args, "--date", date)
 	}
 
+	if len(paths) > 0 {
+		args = append(args, "--")
+		args = append(args, paths...)
+	}
+
 	_, err := c.execGit(args...)
 	if err != nil {
 		return fmt.Errorf("failed to create commit: %w", err)
@@ -27,13 +37,47 @@ func (c *Client) Commit(message, date string) error {
 	return nil
 }
 
-// AmendMessage amends the HEAD commit message
-func (c *Client) AmendMessage(message string) error {
+// EditorCommand returns a command that opens file in the user's editor, as
+// chosen by git (GIT_EDITOR, core.editor, VISUAL, EDITOR, then its default)
+func (c *Client) EditorCommand(file string) (*exec.Cmd, error) {
+	output, err := c.execGit("var", "GIT_EDITOR")
+	if err != nil {
+		return nil, fmt.Errorf("failed to determine editor: %w", err)
+	}
+	editor := strings.TrimSpace(output)
+
+	// Like git, let the shell split editors configured with arguments,
+	// such as "code --wait"
+	cmd := exec.Command("sh", "-c", editor+` "$@"`, editor, file)
+	cmd.Dir = c.workDir
+	return cmd, nil
+}
+
+// CleanupMessage strips comment lines and surplus whitespace from an edited
+// commit message, the way `git commit` does
+func (c *Client) CleanupMessage(message string) (string, error) {
+	output, err := c.execGitStdin(message, "stripspace", "--strip-comments")
+	if err != nil {
+		return "", fmt.Errorf("failed to clean up commit message: %w", err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// AmendMessage amends the HEAD commit message, folding in anything staged.
+// The original author date is kept, as `git commit --amend` does, unless
+// resetDate is set, in which case it becomes the current time. The committer
+// date always updates
+func (c *Client) AmendMessage(message string, resetDate bool) error {
 	if message == "" {
 		return fmt.Errorf("commit message cannot be empty")
 	}
 
-	_, err := c.execGit("commit", "--amend", "-m", message)
+	args := []string{"commit", "--amend", "-m", message}
+	if resetDate {
+		args = append(args, "--date=now")
+	}
+
+	_, err := c.execGit(args...)
 	if err != nil {
 		return fmt.Errorf("failed to amend commit: %w", err)
 	}
@@ -85,10 +129,24 @@ func (c *Client) GetHeadCommitInfo() (*CommitInfo, error) {
 	}
 
 	isPushed := false
-	remoteBranch := fmt.Sprintf("origin/%s", branch)
-	output, err := c.execGit("branch", "-r", "--contains", "HEAD")
-	if err == nil && strings.Contains(output, remoteBranch) {
-		isPushed = true
+	if branch != DetachedHead {
+		remoteBranch := fmt.Sprintf("origin/%s", branch)
+		output, err := c.execGit("branch", "-r", "--contains", "HEAD")
+		if err == nil && strings.Contains(output, remoteBranch) {
+			isPushed = true
+		}
+	}
+
+	hasParent, err := c.HasParent("HEAD")
+	if err != nil {
+		return nil, err
+	}
+
+	// A failed check shouldn't hide HEAD; show it as unsigned
+	signature, err := c.VerifyCommit("HEAD")
+	if err != nil {
+		logger.Debug("signature check failed", "err", err)
+		signature = SigNone
 	}
 
 	return &CommitInfo{
@@ -98,6 +156,8 @@ func (c *Client) GetHeadCommitInfo() (*CommitInfo, error) {
 		Author:    author,
 		Date:      date,
 		IsPushed:  isPushed,
+		IsRoot:    !hasParent,
+		Signature: signature,
 	}, nil
 }
 
@@ -125,15 +185,85 @@ func ValidateCommitDate(dateStr string) (string, error) {
 	return "", fmt.Errorf("invalid date format: %s (use YYYY-MM-DD or YYYY-MM-DD HH:MM:SS)", dateStr)
 }
 
-// SoftResetHead resets HEAD to HEAD~1 but keeps changes staged
+// HasParent reports whether ref has a parent commit, i.e. isn't a root commit
+func (c *Client) HasParent(ref string) (bool, error) {
+	if _, err := c.execGit("rev-parse", "--verify", "--quiet", ref+"^"); err != nil {
+		// --quiet exits 1 without output when the parent doesn't exist
+		if strings.Contains(err.Error(), "exit status 1") {
+			return false, nil
+		}
+		return false, fmt.Errorf("failed to look up parent of %s: %w", ref, err)
+	}
+	return true, nil
+}
+
+// SoftResetHead resets HEAD to HEAD~1 but keeps changes staged. The root
+// commit has no HEAD~1, so its branch is unwound to have no commits at all
+// (`git update-ref -d HEAD`), again leaving everything staged
 func (c *Client) SoftResetHead() error {
-	_, err := c.execGit("reset", "--soft", "HEAD~1")
+	hasParent, err := c.HasParent("HEAD")
+	if err != nil {
+		return err
+	}
+
+	if hasParent {
+		_, err = c.execGit("reset", "--soft", "HEAD~1")
+	} else {
+		_, err = c.execGit("update-ref", "-d", "HEAD")
+	}
 	if err != nil {
 		return fmt.Errorf("failed to soft reset HEAD: %w", err)
 	}
 	return nil
 }
 
+// ErrConflicts is returned when git stopped part way to let conflicts be
+// resolved in the working tree
+var ErrConflicts = errors.New("stopped on conflicts")
+
+// Revert creates a new commit undoing ref, leaving history intact. Without
+// noEdit git opens the editor for the message, which needs a terminal. When
+// the changes conflict git stops with the conflicts in the working tree and
+// ErrConflicts is returned; commit once they're resolved, or RevertAbort
+func (c *Client) Revert(ref string, noEdit bool) error {
+	args := []string{"revert"}
+	if noEdit {
+		args = append(args, "--no-edit")
+	}
+	args = append(args, ref)
+
+	if _, err := c.execGit(args...); err != nil {
+		if reverting, _ := c.RevertInProgress(); reverting {
+			return fmt.Errorf("revert of %s %w", ref, ErrConflicts)
+		}
+		return fmt.Errorf("failed to revert %s: %w", ref, err)
+	}
+	return nil
+}
+
+// RevertInProgress reports whether a revert stopped on conflicts is waiting
+// to be committed or aborted
+func (c *Client) RevertInProgress() (bool, error) {
+	path, err := c.GitPath("REVERT_HEAD")
+	if err != nil {
+		return false, err
+	}
+	_, err = os.Stat(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+// RevertAbort gives up on a revert stopped on conflicts, putting the working
+// tree and index back as they were before it
+func (c *Client) RevertAbort() error {
+	if _, err := c.execGit("revert", "--abort"); err != nil {
+		return fmt.Errorf("failed to abort revert: %w", err)
+	}
+	return nil
+}
+
 // ShowCommit shows the full commit details
 func (c *Client) ShowCommit(ref string) (string, error) {
 	output, err := c.execGit("show", "--color=always", ref)
@@ -142,3 +272,37 @@ func (c *Client) ShowCommit(ref string) (string, error) {
 	}
 	return output, nil
 }
+
+// CommitFiles lists the files ref changed against its first parent, or all
+// of them for a root commit. StatusSymbol holds git's change letter (A, M,
+// D, R...) and renames carry their OrigPath. A merge lists what it brought
+// into the first parent
+func (c *Client) CommitFiles(ref string) ([]FileItem, error) {
+	output, err := c.execGit("diff-tree", "-r", "-z", "-M", "--diff-merges=first-parent", "--root", "--no-commit-id", "--name-status", ref)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list files of %s: %w", ref, err)
+	}
+
+	var files []FileItem
+	fields := strings.Split(strings.TrimSuffix(output, "\x00"), "\x00")
+	for i := 0; i+1 < len(fields); i += 2 {
+		item := FileItem{Status: StatusStaged, StatusSymbol: fields[i][:1], Path: fields[i+1]}
+		// Renames and copies name the original path first
+		if (item.StatusSymbol == "R" || item.StatusSymbol == "C") && i+2 < len(fields) {
+			item.OrigPath, item.Path = fields[i+1], fields[i+2]
+			i++
+		}
+		files = append(files, item)
+	}
+	return files, nil
+}
+
+// ShowCommitFile shows the diff ref made to a single file, without the
+// commit header. Like CommitFiles, a merge is diffed against its first parent
+func (c *Client) ShowCommitFile(ref, file string) (string, error) {
+	output, err := c.execGit("show", "--color=always", "--format=", "--diff-merges=first-parent", ref, "--", file)
+	if err != nil {
+		return "", fmt.Errorf("failed to show %s in %s: %w", file, ref, err)
+	}
+	return output, nil
+}
@@ -0,0 +1,236 @@
+package git
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestCommit(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.git("add", "a.txt")
+
+	if err := r.client.Commit("", ""); err == nil {
+		t.Error("Commit with an empty message succeeded")
+	}
+	if err := r.client.Commit("first commit", "2020-01-02 03:04:05"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+
+	if subject := strings.TrimSpace(r.git("log", "-1", "--format=%s")); subject != "first commit" {
+		t.Errorf("subject = %q, want %q", subject, "first commit")
+	}
+	if date := strings.TrimSpace(r.git("log", "-1", "--format=%ad", "--date=format:%Y-%m-%d %H:%M:%S")); date != "2020-01-02 03:04:05" {
+		t.Errorf("author date = %q, want 2020-01-02 03:04:05", date)
+	}
+	if !r.status().IsClean {
+		t.Error("repository isn't clean after committing everything")
+	}
+}
+
+func TestCommitPaths(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.write("b.txt", "one\n")
+	r.commit("initial")
+
+	r.write("a.txt", "two\n")
+	r.write("b.txt", "two\n")
+	r.git("add", "b.txt")
+
+	// Only a.txt is committed, staged or not; b.txt stays staged
+	if err := r.client.Commit("just a", "", "a.txt"); err != nil {
+		t.Fatalf("Commit: %v", err)
+	}
+	if files := strings.Fields(r.git("show", "--name-only", "--format=", "HEAD")); len(files) != 1 || files[0] != "a.txt" {
+		t.Errorf("committed files = %q, want [a.txt]", files)
+	}
+	status := r.status()
+	assertPaths(t, "Staged", status.Staged, "b.txt")
+	assertPaths(t, "Unstaged", status.Unstaged)
+}
+
+func TestAmendMessage(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.commit("initial")
+	r.write("a.txt", "two\n")
+	r.commit("tpyo")
+	r.git("commit", "-q", "--amend", "--no-edit", "--date=2020-01-02T03:04:05")
+
+	// Staged changes are folded into the amended commit
+	r.write("b.txt", "new\n")
+	r.git("add", "b.txt")
+
+	if err := r.client.AmendMessage("", false); err == nil {
+		t.Error("AmendMessage with an empty message succeeded")
+	}
+	if err := r.client.AmendMessage("typo", false); err != nil {
+		t.Fatalf("AmendMessage: %v", err)
+	}
+
+	if subject := strings.TrimSpace(r.git("log", "-1", "--format=%s")); subject != "typo" {
+		t.Errorf("subject = %q, want typo", subject)
+	}
+	if count := strings.TrimSpace(r.git("rev-list", "--count", "HEAD")); count != "2" {
+		t.Errorf("commit count = %s, want 2", count)
+	}
+	if files := r.git("show", "--name-only", "--format=", "HEAD"); !strings.Contains(files, "b.txt") {
+		t.Errorf("amended commit doesn't include the staged file: %q", files)
+	}
+	if date := strings.TrimSpace(r.git("log", "-1", "--format=%ad", "--date=short")); date != "2020-01-02" {
+		t.Errorf("author date = %s, want it kept at 2020-01-02", date)
+	}
+
+	if err := r.client.AmendMessage("typo", true); err != nil {
+		t.Fatalf("AmendMessage resetting the date: %v", err)
+	}
+	if date := strings.TrimSpace(r.git("log", "-1", "--format=%ad", "--date=short")); date == "2020-01-02" {
+		t.Error("author date wasn't reset")
+	}
+}
+
+func TestSoftResetHead(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.commit("initial")
+	first := strings.TrimSpace(r.git("rev-parse", "HEAD"))
+	r.write("a.txt", "two\n")
+	r.commit("second")
+
+	if err := r.client.SoftResetHead(); err != nil {
+		t.Fatalf("SoftResetHead: %v", err)
+	}
+	if head := strings.TrimSpace(r.git("rev-parse", "HEAD")); head != first {
+		t.Errorf("HEAD = %s, want the first commit %s", head, first)
+	}
+	// The undone commit's changes are left staged
+	status := r.status()
+	assertPaths(t, "Staged", status.Staged, "a.txt")
+	assertPaths(t, "Unstaged", status.Unstaged)
+}
+
+// TestSoftResetHeadRootCommit undoes the only commit, which has no parent
+// to reset to, leaving HEAD unborn and the commit's files staged
+func TestSoftResetHeadRootCommit(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.write("b.txt", "one\n")
+	r.commit("initial")
+
+	if err := r.client.SoftResetHead(); err != nil {
+		t.Fatalf("SoftResetHead: %v", err)
+	}
+	if head := strings.TrimSpace(r.git("symbolic-ref", "HEAD")); head != "refs/heads/main" {
+		t.Errorf("HEAD = %s, want to stay on main", head)
+	}
+	if refs := r.git("for-each-ref"); refs != "" {
+		t.Errorf("refs left after undoing the root commit, want main unborn:\n%s", refs)
+	}
+	status := r.status()
+	assertPaths(t, "Staged", status.Staged, "a.txt", "b.txt")
+	assertPaths(t, "Unstaged", status.Unstaged)
+	assertPaths(t, "Untracked", status.Untracked)
+}
+
+func TestGetHeadCommitInfo(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.commit("initial")
+
+	info, err := r.client.GetHeadCommitInfo()
+	if err != nil {
+		t.Fatalf("GetHeadCommitInfo: %v", err)
+	}
+	hash := strings.TrimSpace(r.git("rev-parse", "HEAD"))
+	if info.Hash != hash {
+		t.Errorf("Hash = %s, want %s", info.Hash, hash)
+	}
+	if info.ShortHash == "" || !strings.HasPrefix(hash, info.ShortHash) {
+		t.Errorf("ShortHash = %q, not a prefix of %s", info.ShortHash, hash)
+	}
+	if info.Message != "initial" {
+		t.Errorf("Message = %q, want initial", info.Message)
+	}
+	if info.Author != "Test User" {
+		t.Errorf("Author = %q, want Test User", info.Author)
+	}
+	if info.Date == "" {
+		t.Error("Date is empty")
+	}
+	if !info.IsRoot {
+		t.Error("IsRoot = false for the first commit")
+	}
+	if info.IsPushed {
+		t.Error("IsPushed = true without a remote")
+	}
+	if info.Signature != SigNone {
+		t.Errorf("Signature = %v, want unsigned", info.Signature)
+	}
+
+	r.write("a.txt", "two\n")
+	r.commit("second\n\nbody text")
+	info, err = r.client.GetHeadCommitInfo()
+	if err != nil {
+		t.Fatalf("GetHeadCommitInfo: %v", err)
+	}
+	if info.Message != "second" {
+		t.Errorf("Message = %q, want only the subject", info.Message)
+	}
+	if info.IsRoot {
+		t.Error("IsRoot = true for a commit with a parent")
+	}
+}
+
+func TestGetHeadCommitInfoSignatureCheckFails(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.commit("initial")
+
+	// Checking the signature fails, e.g. when gpg is broken
+	fakeGit(t, `if [ "$1" = log ] && [ "$3" = "--format=%G?" ]; then
+	echo "error: cannot run gpg" >&2
+	exit 1
+fi`)
+	client, err := NewClient(r.dir)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	info, err := client.GetHeadCommitInfo()
+	if err != nil {
+		t.Fatalf("GetHeadCommitInfo: %v", err)
+	}
+	if info.Message != "initial" || info.Signature != SigNone {
+		t.Errorf("info = %q, %v, want initial unsigned", info.Message, info.Signature)
+	}
+}
+
+func TestCommitFilesMerge(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.commit("initial")
+	r.git("checkout", "-q", "-b", "topic")
+	r.write("b.txt", "topic\n")
+	r.commit("topic")
+	r.git("checkout", "-q", "main")
+	r.write("a.txt", "two\n")
+	r.commit("main")
+	r.git("merge", "-q", "--no-ff", "--no-edit", "topic")
+
+	files, err := r.client.CommitFiles("HEAD")
+	if err != nil {
+		t.Fatalf("CommitFiles: %v", err)
+	}
+	if len(files) != 1 || files[0].Path != "b.txt" || files[0].StatusSymbol != "A" {
+		t.Fatalf("CommitFiles = %+v, want b.txt added", files)
+	}
+
+	diff, err := r.client.ShowCommitFile("HEAD", "b.txt")
+	if err != nil {
+		t.Fatalf("ShowCommitFile: %v", err)
+	}
+	if !strings.Contains(stripSGR(diff), "+topic") {
+		t.Errorf("ShowCommitFile = %q, want b.txt's addition", diff)
+	}
+}
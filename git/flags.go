@@ -0,0 +1,86 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+	"unicode"
+)
+
+// FlaggedFile is a tracked file whose changes git has been told to ignore
+type FlaggedFile struct {
+	Path            string
+	AssumeUnchanged bool // update-index --assume-unchanged: git skips checking the file for changes
+	SkipWorktree    bool // update-index --skip-worktree: git keeps the index version, whatever the file holds
+}
+
+// Flags describes the file's bits as they're named on the command line
+func (f FlaggedFile) Flags() string {
+	var flags []string
+	if f.AssumeUnchanged {
+		flags = append(flags, "assume-unchanged")
+	}
+	if f.SkipWorktree {
+		flags = append(flags, "skip-worktree")
+	}
+	return strings.Join(flags, ", ")
+}
+
+// AssumeUnchanged sets or clears file's assume-unchanged bit. While it's
+// set, changes to file don't show up in status or diffs
+func (c *Client) AssumeUnchanged(file string, on bool) error {
+	if err := c.updateIndexFlag("assume-unchanged", file, on); err != nil {
+		return fmt.Errorf("failed to update assume-unchanged for %s: %w", file, err)
+	}
+	return nil
+}
+
+// SkipWorktree sets or clears file's skip-worktree bit. Unlike
+// assume-unchanged it survives operations that rewrite the index, which
+// makes it the one meant for local edits to tracked files
+func (c *Client) SkipWorktree(file string, on bool) error {
+	if err := c.updateIndexFlag("skip-worktree", file, on); err != nil {
+		return fmt.Errorf("failed to update skip-worktree for %s: %w", file, err)
+	}
+	return nil
+}
+
+// updateIndexFlag runs update-index --[no-]flag on file
+func (c *Client) updateIndexFlag(flag, file string, on bool) error {
+	if !on {
+		flag = "no-" + flag
+	}
+	_, err := c.execGit("update-index", "--"+flag, "--", file)
+	return err
+}
+
+// FlaggedFiles lists the files with the assume-unchanged or skip-worktree
+// bit set, which status no longer shows
+func (c *Client) FlaggedFiles() ([]FlaggedFile, error) {
+	output, err := c.execGit("ls-files", "-v", "-z")
+	if err != nil {
+		return nil, fmt.Errorf("failed to list flagged files: %w", err)
+	}
+	return parseFlaggedFiles(output), nil
+}
+
+// parseFlaggedFiles picks the flagged files out of `ls-files -v -z` output.
+// Each entry is a tag letter, a space and the path; the tag is S for
+// skip-worktree, and lowercase when assume-unchanged is set
+func parseFlaggedFiles(output string) []FlaggedFile {
+	var files []FlaggedFile
+	for _, entry := range strings.Split(output, "\x00") {
+		if len(entry) < 3 || entry[1] != ' ' {
+			continue
+		}
+		tag := rune(entry[0])
+		f := FlaggedFile{
+			Path:            entry[2:],
+			AssumeUnchanged: unicode.IsLower(tag),
+			SkipWorktree:    unicode.ToUpper(tag) == 'S',
+		}
+		if f.AssumeUnchanged || f.SkipWorktree {
+			files = append(files, f)
+		}
+	}
+	return files
+}
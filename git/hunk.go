@@ -0,0 +1,226 @@
+package git
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// hunkHeaderPattern matches "@@ -OLD[,COUNT] +NEW[,COUNT] @@ CONTEXT"
+var hunkHeaderPattern = regexp.MustCompile(`^@@ -(\d+)(?:,(\d+))? \+(\d+)(?:,(\d+))? @@(.*)$`)
+
+// Hunk is a single @@ section of a file diff
+type Hunk struct {
+	OldStart int
+	OldLines int
+	NewStart int
+	NewLines int
+	Context  string   // Text after the closing @@, usually the enclosing function
+	Lines    []string // Body lines prefixed with ' ', '+', '-' or '\'
+	Offset   int      // Line index of the @@ header within the parsed diff
+}
+
+// ParseHunks parses the hunks of an uncolored single-file diff
+func ParseHunks(diff string) []Hunk {
+	var hunks []Hunk
+	var current *Hunk
+
+	for i, line := range strings.Split(diff, "\n") {
+		if match := hunkHeaderPattern.FindStringSubmatch(line); match != nil {
+			hunks = append(hunks, Hunk{
+				OldStart: atoiDefault(match[1], 0),
+				OldLines: atoiDefault(match[2], 1),
+				NewStart: atoiDefault(match[3], 0),
+				NewLines: atoiDefault(match[4], 1),
+				Context:  match[5],
+				Offset:   i,
+			})
+			current = &hunks[len(hunks)-1]
+			continue
+		}
+
+		if current == nil {
+			continue // Still in the file header
+		}
+
+		switch {
+		case line == "":
+			// Some tools strip the trailing space of blank context lines
+			if current.remaining() > 0 {
+				current.Lines = append(current.Lines, " ")
+			}
+		case line[0] == ' ' || line[0] == '+' || line[0] == '-' || line[0] == '\\':
+			current.Lines = append(current.Lines, line)
+		default:
+			current = nil // Next file header
+		}
+	}
+
+	return hunks
+}
+
+// atoiDefault parses s, returning def when s is empty
+func atoiDefault(s string, def int) int {
+	if s == "" {
+		return def
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return def
+	}
+	return n
+}
+
+// remaining returns how many body lines the header announces but are not yet parsed
+func (h *Hunk) remaining() int {
+	var oldSeen, newSeen int
+	for _, line := range h.Lines {
+		switch line[0] {
+		case ' ':
+			oldSeen++
+			newSeen++
+		case '-':
+			oldSeen++
+		case '+':
+			newSeen++
+		}
+	}
+	return max(h.OldLines-oldSeen, h.NewLines-newSeen)
+}
+
+// Header returns the hunk's @@ line
+func (h Hunk) Header() string {
+	return fmt.Sprintf("@@ -%d,%d +%d,%d @@%s", h.OldStart, h.OldLines, h.NewStart, h.NewLines, h.Context)
+}
+
+// Contains reports whether a diff line index falls within the hunk, header included
+func (h Hunk) Contains(line int) bool {
+	return line >= h.Offset && line <= h.Offset+len(h.Lines)
+}
+
+// Patch renders the hunk as a patch against file, suitable for `git apply`
+func (h Hunk) Patch(file string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "diff --git a/%s b/%s\n", file, file)
+	fmt.Fprintf(&b, "--- a/%s\n", file)
+	fmt.Fprintf(&b, "+++ b/%s\n", file)
+	b.WriteString(h.Header())
+	b.WriteString("\n")
+	for _, line := range h.Lines {
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+// SelectLines returns a copy of the hunk that only carries the changed lines
+// in [from, to] (indices into Lines). Unselected changes are rewritten so the
+// patch still applies to its target: when staging, unselected removals stay
+// as context and unselected additions are dropped; when reverse is set (the
+// hunk comes from the staged diff and will be applied with --reverse) it's
+// the other way around
+func (h Hunk) SelectLines(from, to int, reverse bool) (Hunk, error) {
+	if from > to {
+		from, to = to, from
+	}
+	return h.selectWhere(func(i int) bool { return i >= from && i <= to }, reverse)
+}
+
+// SelectRanges is SelectLines for several [from, to] ranges of Lines at once
+func (h Hunk) SelectRanges(ranges [][2]int, reverse bool) (Hunk, error) {
+	return h.selectWhere(func(i int) bool {
+		for _, r := range ranges {
+			if i >= r[0] && i <= r[1] {
+				return true
+			}
+		}
+		return false
+	}, reverse)
+}
+
+// ChangeGroups returns the [from, to] ranges of Lines holding each run of
+// changes, split apart by context lines. These are the pieces `git add -p`
+// splits a hunk into. A "\ No newline" marker belongs to the line before it,
+// so it stays in an open group but never starts one
+func (h Hunk) ChangeGroups() [][2]int {
+	var groups [][2]int
+	start := -1
+	for i, line := range h.Lines {
+		switch line[0] {
+		case ' ':
+			if start >= 0 {
+				groups = append(groups, [2]int{start, i - 1})
+				start = -1
+			}
+			continue
+		case '\\':
+			continue
+		}
+		if start < 0 {
+			start = i
+		}
+	}
+	if start >= 0 {
+		groups = append(groups, [2]int{start, len(h.Lines) - 1})
+	}
+	return groups
+}
+
+// selectWhere keeps the changed lines at the indices keep reports, see
+// SelectLines
+func (h Hunk) selectWhere(keep func(i int) bool, reverse bool) (Hunk, error) {
+	selected := Hunk{
+		OldStart: h.OldStart,
+		NewStart: h.NewStart,
+		Context:  h.Context,
+		Offset:   h.Offset,
+	}
+
+	changed := false
+	kept := false // Whether the previous line survived, for "\ No newline" markers
+	for i, line := range h.Lines {
+		switch line[0] {
+		case ' ':
+			selected.Lines = append(selected.Lines, line)
+			kept = true
+		case '+', '-':
+			if keep(i) {
+				selected.Lines = append(selected.Lines, line)
+				changed = true
+				kept = true
+				continue
+			}
+			// Lines already present in the target become context
+			if (line[0] == '-') != reverse {
+				selected.Lines = append(selected.Lines, " "+line[1:])
+				kept = true
+			} else {
+				kept = false
+			}
+		case '\\':
+			if kept {
+				selected.Lines = append(selected.Lines, line)
+			}
+		}
+	}
+
+	if !changed {
+		return Hunk{}, fmt.Errorf("no changed lines selected")
+	}
+
+	// Recount the body so the header matches
+	for _, line := range selected.Lines {
+		switch line[0] {
+		case ' ':
+			selected.OldLines++
+			selected.NewLines++
+		case '-':
+			selected.OldLines++
+		case '+':
+			selected.NewLines++
+		}
+	}
+
+	return selected, nil
+}
@@ -0,0 +1,60 @@
+package git
+
+import (
+	"slices"
+	"testing"
+)
+
+func TestChangeGroups(t *testing.T) {
+	const noNewline = "\\ No newline at end of file"
+	tests := []struct {
+		name  string
+		lines []string
+		want  [][2]int
+	}{
+		{"one run", []string{" a", "-b", "+B", " c"}, [][2]int{{1, 2}}},
+		{"split by context", []string{"-a", "+A", " b", "-c", "+C"}, [][2]int{{0, 1}, {3, 4}}},
+		{"marker after context", []string{" a", "-b", "+B", " c", noNewline}, [][2]int{{1, 2}}},
+		{"marker in a run", []string{" a", "-b", noNewline, "+B", noNewline}, [][2]int{{1, 4}}},
+		{"no changes", []string{" a", " b"}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			h := Hunk{Lines: tt.lines}
+			groups := h.ChangeGroups()
+			if !slices.Equal(groups, tt.want) {
+				t.Fatalf("ChangeGroups = %v, want %v", groups, tt.want)
+			}
+			// Every piece offered has changes to select
+			for _, g := range groups {
+				if _, err := h.SelectRanges([][2]int{g}, false); err != nil {
+					t.Errorf("SelectRanges(%v): %v", g, err)
+				}
+			}
+		})
+	}
+}
+
+func TestSelectRanges(t *testing.T) {
+	h := Hunk{
+		OldStart: 1, OldLines: 4, NewStart: 1, NewLines: 4,
+		Lines: []string{"-a", "+A", " b", "-c", "+C", " d"},
+	}
+
+	selected, err := h.SelectRanges([][2]int{{3, 4}}, false)
+	if err != nil {
+		t.Fatalf("SelectRanges: %v", err)
+	}
+	// The unselected removal stays as context, its addition is dropped
+	want := []string{" a", " b", "-c", "+C", " d"}
+	if !slices.Equal(selected.Lines, want) {
+		t.Errorf("Lines = %q, want %q", selected.Lines, want)
+	}
+	if selected.OldLines != 4 || selected.NewLines != 4 {
+		t.Errorf("counts = -%d +%d, want -4 +4", selected.OldLines, selected.NewLines)
+	}
+
+	if _, err := h.SelectRanges([][2]int{{2, 2}, {5, 5}}, false); err == nil {
+		t.Error("SelectRanges over context only succeeded")
+	}
+}
@@ -2,17 +2,23 @@ package git
 
 import (
 	"fmt"
+	"os"
+	"os/exec"
+	"strconv"
 	"strings"
 )
 
+// pathBatchSize is the number of paths passed per git invocation, keeping
+// huge selections under the OS argument length limit
+const pathBatchSize = 500
+
 // Stage stages files for commit
 func (c *Client) Stage(files ...string) error {
 	if len(files) == 0 {
 		return nil
 	}
 
-	args := append([]string{"add", "--"}, files...)
-	_, err := c.execGit(args...)
+	err := c.execGitPaths([]string{"add"}, files)
 	if err != nil {
 		return fmt.Errorf("failed to stage files: %w", err)
 	}
@@ -20,14 +26,30 @@ func (c *Client) Stage(files ...string) error {
 	return nil
 }
 
+// StageTrackedModifications stages every change to tracked files, deletions
+// included, leaving untracked files alone (`git add -u`). Paths in exclude,
+// such as submodules, are left unstaged
+func (c *Client) StageTrackedModifications(exclude ...string) error {
+	args := []string{"add", "--update"}
+	if len(exclude) > 0 {
+		args = append(args, "--", ".")
+		for _, path := range exclude {
+			args = append(args, ":(exclude,literal)"+path)
+		}
+	}
+	if _, err := c.execGit(args...); err != nil {
+		return fmt.Errorf("failed to stage tracked changes: %w", err)
+	}
+	return nil
+}
+
 // Unstage unstages files
 func (c *Client) Unstage(files ...string) error {
 	if len(files) == 0 {
 		return nil
 	}
 
-	args := append([]string{"reset", "HEAD", "--"}, files...)
-	_, err := c.execGit(args...)
+	err := c.execGitPaths([]string{"reset", "HEAD"}, files)
 	if err != nil {
 		return fmt.Errorf("failed to unstage files: %w", err)
 	}
@@ -35,13 +57,61 @@ func (c *Client) Unstage(files ...string) error {
 	return nil
 }
 
-// Diff returns the diff for a file
-func (c *Client) Diff(file string, staged bool) (string, error) {
+// IntentToAdd records untracked files in the index without their content
+// (`git add -N`), so they show up in `git diff` as new files
+func (c *Client) IntentToAdd(files ...string) error {
+	if len(files) == 0 {
+		return nil
+	}
+
+	err := c.execGitPaths([]string{"add", "--intent-to-add"}, files)
+	if err != nil {
+		return fmt.Errorf("failed to mark files as intent to add: %w", err)
+	}
+
+	return nil
+}
+
+// execGitPaths runs a git command followed by "--" and the paths, split into
+// as many invocations as needed to stay within pathBatchSize
+func (c *Client) execGitPaths(args []string, paths []string) error {
+	for len(paths) > 0 {
+		n := min(pathBatchSize, len(paths))
+
+		batch := append(append([]string{}, args...), "--")
+		batch = append(batch, paths[:n]...)
+		if _, err := c.execGit(batch...); err != nil {
+			return err
+		}
+
+		paths = paths[n:]
+	}
+	return nil
+}
+
+// HasUnstagedChanges reports whether a file differs between the index and
+// the working tree
+func (c *Client) HasUnstagedChanges(file string) (bool, error) {
+	_, err := c.execGit("diff", "--quiet", "--", file)
+	if err != nil {
+		// --quiet exits with 1 when there are differences
+		if strings.Contains(err.Error(), "exit status 1") {
+			return true, nil
+		}
+		return false, fmt.Errorf("failed to check unstaged changes: %w", err)
+	}
+	return false, nil
+}
+
+// Diff returns the diff for the given files
+func (c *Client) Diff(staged bool, opts DiffOptions, files ...string) (string, error) {
 	args := []string{"diff", "--color=always"}
 	if staged {
 		args = append(args, "--cached")
 	}
-	args = append(args, "--", file)
+	args = append(args, opts.Args()...)
+	args = append(args, "--")
+	args = append(args, files...)
 
 	output, err := c.execGit(args...)
 	if err != nil {
@@ -55,6 +125,330 @@ func (c *Client) Diff(file string, staged bool) (string, error) {
 	return output, nil
 }
 
+// StagedDiff returns the patch the next commit would record, all staged
+// files (or only paths, when given) after a summary of their line counts
+func (c *Client) StagedDiff(paths ...string) (string, error) {
+	args := append([]string{"diff", "--cached", "--color=always", "--patch-with-stat", "--"}, paths...)
+	output, err := c.execGit(args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to get staged diff: %w", err)
+	}
+	return output, nil
+}
+
+// PagerCommand returns a command that shows file's diff through git's
+// configured pager (GIT_PAGER, core.pager, PAGER, then less), so tools such
+// as delta render it. Untracked files are shown as all added
+func (c *Client) PagerCommand(file FileItem, opts DiffOptions) *exec.Cmd {
+	args := []string{"--paginate", "diff"}
+	switch file.Status {
+	case StatusStaged:
+		args = append(args, "--cached")
+		args = append(args, opts.Args()...)
+		args = append(args, "--")
+		args = append(args, file.Paths()...)
+	case StatusUntracked:
+		args = append(args, "--no-index")
+		args = append(args, opts.Args()...)
+		args = append(args, "--", os.DevNull, file.Path)
+	default:
+		args = append(args, opts.Args()...)
+		args = append(args, "--", file.Path)
+	}
+
+	cmd := exec.Command(c.gitPath, args...)
+	cmd.Dir = c.workDir
+	return cmd
+}
+
+// RawDiff returns the uncolored diff for the given files, suitable for
+// parsing into hunks. Only the options that keep it a valid patch are used,
+// so its lines match a preview generated with the same options
+func (c *Client) RawDiff(staged bool, opts DiffOptions, files ...string) (string, error) {
+	args := []string{"diff", "--no-color"}
+	if staged {
+		args = append(args, "--cached")
+	}
+	args = append(args, opts.patchArgs()...)
+	args = append(args, "--")
+	args = append(args, files...)
+
+	output, err := c.execGit(args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to get diff: %w", err)
+	}
+	return output, nil
+}
+
+// ExportPatch returns the changes to files as a patch `git apply` accepts:
+// their staged changes, or their unstaged ones with untracked files added
+// in full. Binary changes are included. No files means every changed file
+func (c *Client) ExportPatch(files []string, staged bool) (string, error) {
+	// Fixed prefixes keep diff.noprefix and the like from breaking the patch
+	diff := []string{"diff", "--no-color", "--no-ext-diff", "--binary", "--src-prefix=a/", "--dst-prefix=b/"}
+	args := diff
+	if staged {
+		args = append(args, "--cached")
+	}
+	args = append(append(args, "--"), files...)
+	patch, err := c.execGit(args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to export patch: %w", err)
+	}
+	if staged {
+		return patch, nil
+	}
+
+	// Untracked files only show up diffed against nothing
+	output, err := c.execGit(append([]string{"ls-files", "--others", "--exclude-standard", "-z", "--"}, files...)...)
+	if err != nil {
+		return "", fmt.Errorf("failed to list untracked files: %w", err)
+	}
+	for _, path := range strings.Split(output, "\x00") {
+		if path == "" {
+			continue
+		}
+		added, err := c.execGit(append(diff, "--no-index", "--", os.DevNull, path)...)
+		// Like diff, --no-index exits with 1 when there are differences
+		if err != nil && !strings.Contains(err.Error(), "exit status 1") {
+			return "", fmt.Errorf("failed to export %s: %w", path, err)
+		}
+		patch += added
+	}
+	return patch, nil
+}
+
+// StageHunk stages a single hunk (possibly reduced with SelectLines) of a
+// file's unstaged changes
+func (c *Client) StageHunk(file string, hunk Hunk) error {
+	if err := c.applyPatch(hunk.Patch(file), "--cached"); err != nil {
+		return fmt.Errorf("failed to stage hunk: %w", err)
+	}
+	return nil
+}
+
+// UnstageHunk removes a single hunk (possibly reduced with SelectLines) of a
+// file's staged changes from the index, leaving the working tree alone
+func (c *Client) UnstageHunk(file string, hunk Hunk) error {
+	if err := c.applyPatch(hunk.Patch(file), "--cached", "--reverse"); err != nil {
+		return fmt.Errorf("failed to unstage hunk: %w", err)
+	}
+	return nil
+}
+
+// RestoreFromRef replaces files in the index and working tree with their
+// content at ref, a commit, branch or tag. Their uncommitted changes are lost
+func (c *Client) RestoreFromRef(ref string, files ...string) error {
+	if _, err := c.execGit("rev-parse", "--verify", "--quiet", ref+"^{commit}"); err != nil {
+		return fmt.Errorf("unknown commit %q", ref)
+	}
+	args := append([]string{"checkout", ref, "--"}, files...)
+	if _, err := c.execGit(args...); err != nil {
+		return fmt.Errorf("failed to restore from %s: %w", ref, err)
+	}
+	return nil
+}
+
+// DiscardHunk reverts a single hunk (possibly reduced with SelectLines) of a
+// file's unstaged changes in the working tree. The changes are lost
+func (c *Client) DiscardHunk(file string, hunk Hunk) error {
+	if err := c.applyPatch(hunk.Patch(file), "--reverse"); err != nil {
+		return fmt.Errorf("failed to discard hunk: %w", err)
+	}
+	return nil
+}
+
+// applyPatch feeds a patch to `git apply` with the given flags. The patch is
+// validated with --check first, so a malformed patch is reported without
+// touching the index or working tree
+func (c *Client) applyPatch(patch string, flags ...string) error {
+	args := append([]string{"apply"}, flags...)
+
+	checkArgs := append(append([]string{}, args...), "--check", "-")
+	if _, err := c.execGitStdin(patch, checkArgs...); err != nil {
+		return fmt.Errorf("patch does not apply: %w", err)
+	}
+
+	_, err := c.execGitStdin(patch, append(args, "-")...)
+	return err
+}
+
+// Args returns the git diff flags for the options
+func (o DiffOptions) Args() []string {
+	var args []string
+	if o.IgnoreWhitespace {
+		args = append(args, "--ignore-all-space")
+	}
+	if o.HighlightWhitespace {
+		args = append(args, "--ws-error-highlight=all")
+	}
+	if o.WordDiff {
+		args = append(args, "--word-diff=color")
+	}
+	switch o.Renames {
+	case RenamesCopies:
+		args = append(args, "--find-renames", "--find-copies")
+	case RenamesOff:
+		args = append(args, "--no-renames")
+	}
+	args = append(args, o.patchArgs()...)
+	// After patchArgs so it overrides any expanded context. Hunks without
+	// context only apply with --unidiff-zero, so it isn't a patch arg
+	if o.HideContext {
+		args = append(args, "--unified=0")
+	}
+	return args
+}
+
+// patchArgs returns the options that change which lines a diff shows but
+// still produce a patch git can apply
+func (o DiffOptions) patchArgs() []string {
+	var args []string
+	if o.Algorithm != DiffAlgorithmDefault {
+		args = append(args, "--diff-algorithm="+string(o.Algorithm))
+	}
+	if o.Context > 0 {
+		args = append(args, fmt.Sprintf("--unified=%d", o.Context))
+	}
+	return args
+}
+
+// CacheKey returns a string identifying the options, for keying cached diffs
+func (o DiffOptions) CacheKey() string {
+	return strings.Join(o.Args(), " ")
+}
+
+// DescribeStagedChange summarizes staged changes that carry no content hunks,
+// such as pure renames and mode changes, from the diff's extended header
+func (c *Client) DescribeStagedChange(files ...string) (string, error) {
+	args := append([]string{"diff", "--cached", "-M", "--no-color", "--"}, files...)
+	output, err := c.execGit(args...)
+	if err != nil {
+		return "", fmt.Errorf("failed to describe staged change: %w", err)
+	}
+
+	var oldMode, newMode, renamedFrom, similarity string
+	for _, line := range strings.Split(output, "\n") {
+		if strings.HasPrefix(line, "@@") {
+			break // Header is over
+		}
+		switch {
+		case strings.HasPrefix(line, "old mode "):
+			oldMode = strings.TrimPrefix(line, "old mode ")
+		case strings.HasPrefix(line, "new mode "):
+			newMode = strings.TrimPrefix(line, "new mode ")
+		case strings.HasPrefix(line, "rename from "):
+			renamedFrom = strings.TrimPrefix(line, "rename from ")
+		case strings.HasPrefix(line, "similarity index "):
+			similarity = strings.TrimPrefix(line, "similarity index ")
+		}
+	}
+
+	var lines []string
+	if renamedFrom != "" {
+		lines = append(lines, fmt.Sprintf("Renamed from %s (%s similar)", renamedFrom, similarity))
+	}
+	if oldMode != "" && newMode != "" {
+		lines = append(lines, fmt.Sprintf("Mode changed: %s → %s", oldMode, newMode))
+	}
+
+	return strings.Join(lines, "\n"), nil
+}
+
+// DiffStat returns per-file added/deleted line counts keyed by path
+func (c *Client) DiffStat(staged bool) (map[string]DiffStat, error) {
+	args := []string{"diff", "--numstat"}
+	if staged {
+		args = append(args, "--cached")
+	}
+
+	output, err := c.execGit(args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get diff stats: %w", err)
+	}
+
+	return parseNumStat(output), nil
+}
+
+// parseNumStat parses the output of `git diff --numstat`
+// Format: ADDED<TAB>DELETED<TAB>PATH, with "-" counts for binary files
+func parseNumStat(output string) map[string]DiffStat {
+	stats := make(map[string]DiffStat)
+
+	for _, line := range strings.Split(strings.TrimSpace(output), "\n") {
+		fields := strings.SplitN(line, "\t", 3)
+		if len(fields) != 3 {
+			continue // Invalid line
+		}
+
+		stat := DiffStat{Path: fields[2]}
+		if fields[0] == "-" && fields[1] == "-" {
+			stat.Binary = true
+		} else {
+			stat.Added, _ = strconv.Atoi(fields[0])
+			stat.Deleted, _ = strconv.Atoi(fields[1])
+		}
+		stats[stat.Path] = stat
+	}
+
+	return stats
+}
+
+// CheckPatch checks whether a patch file applies to the working tree, or to
+// the index when cached, without applying it. It returns git's summary of
+// the files the patch touches; when it doesn't apply, the error carries
+// git's report of the failing hunks
+func (c *Client) CheckPatch(path string, cached bool) (string, error) {
+	args := []string{"apply", "--check", "--stat", "--summary"}
+	if cached {
+		args = append(args, "--cached")
+	}
+
+	output, err := c.execGit(append(args, path)...)
+	if err != nil {
+		return "", fmt.Errorf("patch does not apply: %w", err)
+	}
+	return output, nil
+}
+
+// ApplyPatch applies a patch file to the working tree, or to the index when cached
+func (c *Client) ApplyPatch(path string, cached bool) error {
+	args := []string{"apply"}
+	if cached {
+		args = append(args, "--cached")
+	}
+
+	if _, err := c.execGit(append(args, path)...); err != nil {
+		return fmt.Errorf("failed to apply patch: %w", err)
+	}
+	return nil
+}
+
+// CheckoutOurs resolves a conflicted file by taking our side of the merge
+// and staging the result
+func (c *Client) CheckoutOurs(file string) error {
+	return c.resolveConflict("--ours", file)
+}
+
+// CheckoutTheirs resolves a conflicted file by taking their side of the
+// merge and staging the result
+func (c *Client) CheckoutTheirs(file string) error {
+	return c.resolveConflict("--theirs", file)
+}
+
+// resolveConflict checks out one side of a conflicted file and marks it resolved
+func (c *Client) resolveConflict(side, file string) error {
+	if _, err := c.execGit("checkout", side, "--", file); err != nil {
+		return fmt.Errorf("failed to check out %s version: %w", strings.TrimPrefix(side, "--"), err)
+	}
+
+	if _, err := c.execGit("add", "--", file); err != nil {
+		return fmt.Errorf("failed to mark file as resolved: %w", err)
+	}
+
+	return nil
+}
+
 // StageAll stages all unstaged and untracked files
 func (c *Client) StageAll() error {
 	_, err := c.execGit("add", ".")
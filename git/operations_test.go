@@ -0,0 +1,244 @@
+package git
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStageUnstage(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.write("b.txt", "one\n")
+	r.commit("initial")
+
+	r.write("a.txt", "two\n")
+	r.write("b.txt", "two\n")
+	r.write("new.txt", "new\n")
+
+	if err := r.client.Stage("a.txt", "new.txt"); err != nil {
+		t.Fatalf("Stage: %v", err)
+	}
+	status := r.status()
+	assertPaths(t, "Staged after Stage", status.Staged, "a.txt", "new.txt")
+	assertPaths(t, "Unstaged after Stage", status.Unstaged, "b.txt")
+	assertPaths(t, "Untracked after Stage", status.Untracked)
+
+	if err := r.client.Unstage("a.txt", "new.txt"); err != nil {
+		t.Fatalf("Unstage: %v", err)
+	}
+	status = r.status()
+	assertPaths(t, "Staged after Unstage", status.Staged)
+	assertPaths(t, "Unstaged after Unstage", status.Unstaged, "a.txt", "b.txt")
+	assertPaths(t, "Untracked after Unstage", status.Untracked, "new.txt")
+}
+
+func TestStageNothing(t *testing.T) {
+	r := newTestRepo(t)
+	if err := r.client.Stage(); err != nil {
+		t.Errorf("Stage(): %v", err)
+	}
+	if err := r.client.Unstage(); err != nil {
+		t.Errorf("Unstage(): %v", err)
+	}
+}
+
+func TestDiff(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\ntwo\n")
+	r.commit("initial")
+	r.write("a.txt", "one\nchanged\n")
+
+	unstaged, err := r.client.Diff(false, DiffOptions{}, "a.txt")
+	if err != nil {
+		t.Fatalf("Diff: %v", err)
+	}
+	plain := stripSGR(unstaged)
+	if !strings.Contains(plain, "-two") || !strings.Contains(plain, "+changed") {
+		t.Errorf("unstaged diff is missing the change:\n%s", plain)
+	}
+
+	staged, err := r.client.Diff(true, DiffOptions{}, "a.txt")
+	if err != nil {
+		t.Fatalf("Diff staged: %v", err)
+	}
+	if staged != "" {
+		t.Errorf("staged diff before staging = %q, want empty", staged)
+	}
+
+	r.git("add", "a.txt")
+	staged, err = r.client.Diff(true, DiffOptions{}, "a.txt")
+	if err != nil {
+		t.Fatalf("Diff staged: %v", err)
+	}
+	if !strings.Contains(stripSGR(staged), "+changed") {
+		t.Errorf("staged diff is missing the change:\n%s", stripSGR(staged))
+	}
+}
+
+// stripSGR drops the color codes of `git diff --color=always` output
+func stripSGR(s string) string {
+	var b strings.Builder
+	for {
+		start := strings.Index(s, "\x1b[")
+		if start < 0 {
+			b.WriteString(s)
+			return b.String()
+		}
+		b.WriteString(s[:start])
+		end := strings.IndexByte(s[start:], 'm')
+		if end < 0 {
+			return b.String()
+		}
+		s = s[start+end+1:]
+	}
+}
+
+func TestDescribeStagedChange(t *testing.T) {
+	tests := []struct {
+		name   string
+		change func(r *testRepo)
+		files  []string
+		want   string
+	}{
+		{
+			name: "chmod",
+			change: func(r *testRepo) {
+				r.git("update-index", "--chmod=+x", "script.sh")
+			},
+			files: []string{"script.sh"},
+			want:  "Mode changed: 100644 → 100755",
+		},
+		{
+			name: "rename",
+			change: func(r *testRepo) {
+				r.git("mv", "script.sh", "renamed.sh")
+			},
+			files: []string{"renamed.sh", "script.sh"},
+			want:  "Renamed from script.sh (100% similar)",
+		},
+		{
+			name: "rename and chmod",
+			change: func(r *testRepo) {
+				r.git("mv", "script.sh", "renamed.sh")
+				r.git("update-index", "--chmod=+x", "renamed.sh")
+			},
+			files: []string{"renamed.sh", "script.sh"},
+			want:  "Renamed from script.sh (100% similar)\nMode changed: 100644 → 100755",
+		},
+		{
+			name: "content change",
+			change: func(r *testRepo) {
+				r.write("script.sh", "echo changed\n")
+				r.git("add", "script.sh")
+			},
+			files: []string{"script.sh"},
+			want:  "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newTestRepo(t)
+			r.write("script.sh", "echo one\necho two\necho three\n")
+			r.commit("initial")
+			tt.change(r)
+
+			got, err := r.client.DescribeStagedChange(tt.files...)
+			if err != nil {
+				t.Fatalf("DescribeStagedChange: %v", err)
+			}
+			if got != tt.want {
+				t.Errorf("DescribeStagedChange = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStageUnstageBatches(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("README", "keeps HEAD valid for unstaging\n")
+	r.commit("initial")
+
+	// Enough paths for three batches, the last one partial
+	const count = 2*pathBatchSize + 203
+	files := make([]string, count)
+	for i := range files {
+		files[i] = fmt.Sprintf("dir%d/file%04d.txt", i%7, i)
+		r.write(files[i], "content\n")
+	}
+
+	// Record how many paths each add and reset invocation got
+	log := filepath.Join(t.TempDir(), "invocations")
+	fakeGit(t, `case "$1" in add|reset)
+	n=0; seen=
+	for arg; do
+		if [ -n "$seen" ]; then n=$((n+1)); fi
+		if [ "$arg" = -- ]; then seen=1; fi
+	done
+	echo "$1 $n" >> `+log+`
+esac`)
+	client, err := NewClient(r.dir)
+	if err != nil {
+		t.Fatalf("NewClient: %v", err)
+	}
+
+	if err := client.Stage(files...); err != nil {
+		t.Fatalf("Stage: %v", err)
+	}
+	if staged := r.status().StagedCount(); staged != count {
+		t.Errorf("%d files staged, want %d", staged, count)
+	}
+	if err := client.Unstage(files...); err != nil {
+		t.Fatalf("Unstage: %v", err)
+	}
+	if staged := r.status().StagedCount(); staged != 0 {
+		t.Errorf("%d files still staged after Unstage", staged)
+	}
+
+	content, err := os.ReadFile(log)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := []string{
+		fmt.Sprintf("add %d", pathBatchSize), fmt.Sprintf("add %d", pathBatchSize), "add 203",
+		fmt.Sprintf("reset %d", pathBatchSize), fmt.Sprintf("reset %d", pathBatchSize), "reset 203",
+	}
+	if got := strings.Split(strings.TrimSpace(string(content)), "\n"); !slices.Equal(got, want) {
+		t.Errorf("invocations = %q, want %q", got, want)
+	}
+}
+
+func TestApplyPatchRejected(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\ntwo\n")
+	r.write("b.txt", "one\ntwo\n")
+	r.commit("initial")
+	r.write("a.txt", "one\nchanged\n")
+
+	// a.txt's half applies on its own; b.txt's context doesn't match
+	stale := "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n one\n-two\n+changed\n" +
+		"diff --git a/b.txt b/b.txt\n--- a/b.txt\n+++ b/b.txt\n@@ -1,2 +1,2 @@\n one\n-three\n+four\n"
+
+	tests := []struct {
+		name  string
+		patch string
+	}{
+		{"malformed", "diff --git a/a.txt b/a.txt\n--- a/a.txt\n+++ b/a.txt\n@@ -1,2 +1,2 @@\n one\n?two\n"},
+		{"does not apply", stale},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := r.client.applyPatch(tt.patch, "--cached")
+			if err == nil || !strings.HasPrefix(err.Error(), "patch does not apply") {
+				t.Fatalf("applyPatch = %v, want the --check error", err)
+			}
+			if staged := r.git("diff", "--cached", "--name-only"); staged != "" {
+				t.Errorf("index changed: %q staged", staged)
+			}
+		})
+	}
+}
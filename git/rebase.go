@@ -0,0 +1,135 @@
+package git
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+)
+
+// markFirstEdit is a sequence editor turning the first pick of the rebase
+// todo into an edit, so the rebase stops right after replaying that commit.
+// git runs it through the shell with the todo path appended
+const markFirstEdit = `f() { sed '1s/^pick /edit /' "$1" > "$1.igit" && mv "$1.igit" "$1"; }; f`
+
+// CommitSummary returns ref's short hash and subject, like "1a2b3c4 Fix typo"
+func (c *Client) CommitSummary(ref string) (string, error) {
+	output, err := c.execGit("log", "-1", "--format=%h %s", ref+"^{commit}", "--")
+	if err != nil {
+		return "", fmt.Errorf("failed to look up %s: %w", ref, err)
+	}
+	return strings.TrimSpace(output), nil
+}
+
+// IsPushedCommit reports whether ref is contained in any remote-tracking
+// branch, so rewriting it would rewrite published history
+func (c *Client) IsPushedCommit(ref string) (bool, error) {
+	output, err := c.execGit("branch", "-r", "--contains", ref)
+	if err != nil {
+		return false, fmt.Errorf("failed to check whether %s is pushed: %w", ref, err)
+	}
+	return strings.TrimSpace(output) != "", nil
+}
+
+// RedateCommit sets the author and committer date of ref, an earlier commit
+// of the current branch, by rebasing onto its parent with a stop at ref to
+// amend it. The commits after it are replayed unchanged, though as with any
+// rebase they get a new committer date. Uncommitted changes are stashed
+// around the rebase. If any step fails the rebase is aborted, leaving the
+// branch as it was
+func (c *Client) RedateCommit(ref, date string) error {
+	if rebasing, err := c.RebaseInProgress(); err != nil {
+		return err
+	} else if rebasing {
+		return fmt.Errorf("a rebase is already in progress; finish or abort it first")
+	}
+
+	output, err := c.execGit("rev-parse", "--verify", ref+"^{commit}")
+	if err != nil {
+		return fmt.Errorf("%s is not a commit: %w", ref, err)
+	}
+	hash := strings.TrimSpace(output)
+
+	if _, err := c.execGit("merge-base", "--is-ancestor", hash, "HEAD"); err != nil {
+		return fmt.Errorf("%s is not on the current branch", ref)
+	}
+	// A plain rebase flattens merges, so only linear history is rewritten
+	merges, err := c.execGit("rev-list", "--merges", "HEAD", "--not", hash+"^@")
+	if err != nil {
+		return fmt.Errorf("failed to look for merges after %s: %w", ref, err)
+	}
+	if strings.TrimSpace(merges) != "" {
+		return fmt.Errorf("can't redate %s: it or a later commit is a merge", ref)
+	}
+
+	hasParent, err := c.HasParent(hash)
+	if err != nil {
+		return err
+	}
+	args := []string{"rebase", "--interactive", "--autostash", "--no-autosquash"}
+	if hasParent {
+		args = append(args, hash+"^")
+	} else {
+		args = append(args, "--root")
+	}
+	// GIT_EDITOR keeps git from opening an editor for anything else
+	env := []string{"GIT_SEQUENCE_EDITOR=" + markFirstEdit, "GIT_EDITOR=true"}
+	if _, err := c.execGitEnv(env, args...); err != nil {
+		c.abortFailedRebase()
+		return fmt.Errorf("failed to start rebase: %w", err)
+	}
+
+	// The stop must be at ref itself before anything is amended
+	output, err = c.execGit("rev-parse", "HEAD")
+	if err != nil || strings.TrimSpace(output) != hash {
+		c.abortFailedRebase()
+		return fmt.Errorf("rebase did not stop at %s", ref)
+	}
+
+	amend := []string{"commit", "--amend", "--no-edit", "--allow-empty", "--no-verify", "--date", date}
+	if _, err := c.execGitEnv([]string{"GIT_COMMITTER_DATE=" + date}, amend...); err != nil {
+		c.abortFailedRebase()
+		return fmt.Errorf("failed to amend date of %s: %w", ref, err)
+	}
+
+	if _, err := c.execGitEnv([]string{"GIT_EDITOR=true"}, "rebase", "--continue"); err != nil {
+		c.abortFailedRebase()
+		return fmt.Errorf("failed to replay commits after %s: %w", ref, err)
+	}
+	return nil
+}
+
+// abortFailedRebase aborts a rebase left behind by a failed step, if any
+func (c *Client) abortFailedRebase() {
+	if rebasing, _ := c.RebaseInProgress(); rebasing {
+		_ = c.RebaseAbort()
+	}
+}
+
+// RebaseInProgress reports whether a rebase is stopped, waiting to be
+// continued or aborted
+func (c *Client) RebaseInProgress() (bool, error) {
+	for _, name := range []string{"rebase-merge", "rebase-apply"} {
+		path, err := c.GitPath(name)
+		if err != nil {
+			return false, err
+		}
+		_, err = os.Stat(path)
+		if err == nil {
+			return true, nil
+		}
+		if !errors.Is(err, os.ErrNotExist) {
+			return false, err
+		}
+	}
+	return false, nil
+}
+
+// RebaseAbort gives up on a stopped rebase, putting the branch back as it
+// was before it started
+func (c *Client) RebaseAbort() error {
+	if _, err := c.execGit("rebase", "--abort"); err != nil {
+		return fmt.Errorf("failed to abort rebase: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package git
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+// WebURL returns the address of path at ref on origin's web interface, or
+// of the commit ref when path is empty. An empty ref means the current
+// branch, or HEAD's commit when detached. GitHub, GitLab and Bitbucket
+// remotes are supported
+func (c *Client) WebURL(path, ref string) (string, error) {
+	output, err := c.execGit("remote", "get-url", "origin")
+	if err != nil {
+		return "", fmt.Errorf("failed to get origin URL: %w", err)
+	}
+	host, repo, err := parseRemoteURL(strings.TrimSpace(output))
+	if err != nil {
+		return "", err
+	}
+
+	if ref == "" {
+		ref, err = c.CurrentBranch()
+		if err != nil {
+			return "", fmt.Errorf("failed to get current branch: %w", err)
+		}
+	}
+	// Commits and detached checkouts are only addressable by hash on the web
+	if path == "" || ref == DetachedHead {
+		output, err := c.execGit("rev-parse", "--verify", ref+"^{commit}")
+		if err != nil {
+			return "", fmt.Errorf("failed to resolve %s: %w", ref, err)
+		}
+		ref = strings.TrimSpace(output)
+	}
+
+	base := "https://" + host + "/" + repo
+	var kind string
+	switch {
+	case strings.Contains(host, "github"):
+		kind = "blob"
+		if path == "" {
+			kind = "commit"
+		}
+	case strings.Contains(host, "gitlab"):
+		kind = "-/blob"
+		if path == "" {
+			kind = "-/commit"
+		}
+	case strings.Contains(host, "bitbucket"):
+		kind = "src"
+		if path == "" {
+			kind = "commits"
+		}
+	default:
+		return "", fmt.Errorf("unsupported remote host: %s", host)
+	}
+
+	if path == "" {
+		return base + "/" + kind + "/" + ref, nil
+	}
+	return base + "/" + kind + "/" + escapePath(ref) + "/" + escapePath(path), nil
+}
+
+// parseRemoteURL extracts the host and repository path from a remote URL in
+// any of the forms git accepts: https://host/org/repo.git,
+// ssh://git@host:22/org/repo.git or the scp-like git@host:org/repo.git
+func parseRemoteURL(remote string) (host, repo string, err error) {
+	if !strings.Contains(remote, "://") {
+		// scp-like syntax, [user@]host:path
+		hostPart, pathPart, ok := strings.Cut(remote, ":")
+		if !ok {
+			return "", "", fmt.Errorf("unrecognized remote URL: %s", remote)
+		}
+		if _, h, found := strings.Cut(hostPart, "@"); found {
+			hostPart = h
+		}
+		host, repo = hostPart, pathPart
+	} else {
+		u, err := url.Parse(remote)
+		if err != nil {
+			return "", "", fmt.Errorf("unrecognized remote URL: %s", remote)
+		}
+		// The web interface doesn't share the SSH port
+		host = u.Host
+		if u.Scheme != "http" && u.Scheme != "https" {
+			host = u.Hostname()
+		}
+		repo = u.Path
+	}
+
+	repo = strings.TrimSuffix(strings.Trim(repo, "/"), ".git")
+	if host == "" || repo == "" {
+		return "", "", fmt.Errorf("unrecognized remote URL: %s", remote)
+	}
+	return host, repo, nil
+}
+
+// escapePath escapes each segment of a slash-separated path for use in a URL
+func escapePath(path string) string {
+	segments := strings.Split(path, "/")
+	for i, s := range segments {
+		segments[i] = url.PathEscape(s)
+	}
+	return strings.Join(segments, "/")
+}
+
+// Push pushes the current branch to its upstream. Credentials must come
+// from a helper or agent, since there's no terminal to prompt on
+func (c *Client) Push() error {
+	if _, err := c.execGitEnv([]string{"GIT_TERMINAL_PROMPT=0"}, "push"); err != nil {
+		return fmt.Errorf("failed to push: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,116 @@
+package git
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+// testRepo is a throwaway repository in its own t.TempDir(), with a Client
+// opened on it
+type testRepo struct {
+	t      *testing.T
+	dir    string
+	client *Client
+}
+
+// newTestRepo initializes an empty repository with an identity to commit
+// as. The user's and system's git config are kept out of it, so settings
+// like commit signing or a default branch name can't change the outcome
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+
+	r := &testRepo{t: t, dir: t.TempDir()}
+	r.git("init", "-q", "-b", "main")
+	r.git("config", "user.name", "Test User")
+	r.git("config", "user.email", "test@example.com")
+	r.git("config", "commit.gpgsign", "false")
+
+	client, err := NewClient(r.dir)
+	if err != nil {
+		t.Fatalf("NewClient(%s): %v", r.dir, err)
+	}
+	r.client = client
+	return r
+}
+
+// git runs a git command in the repository, failing the test if it fails,
+// and returns its output
+func (r *testRepo) git(args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return string(output)
+}
+
+// write creates or replaces a file in the working tree, creating its
+// directories as needed
+func (r *testRepo) write(path, content string) {
+	r.t.Helper()
+	full := filepath.Join(r.dir, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		r.t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// commit stages everything in the working tree and commits it
+func (r *testRepo) commit(message string) {
+	r.t.Helper()
+	r.git("add", "-A")
+	r.git("commit", "-q", "-m", message)
+}
+
+// status returns the client's view of the repository, failing the test on
+// error
+func (r *testRepo) status() GitStatus {
+	r.t.Helper()
+	status, err := r.client.Status(UntrackedNormal)
+	if err != nil {
+		r.t.Fatalf("Status: %v", err)
+	}
+	return status
+}
+
+// fakeGit makes clients created during the test run a wrapper script around
+// the real git. The script is sh code run first, before the real git gets
+// the same arguments; $GIT names the real git, so the script can call it
+func fakeGit(t *testing.T, script string) {
+	t.Helper()
+	real, err := exec.LookPath("git")
+	if err != nil {
+		t.Fatal(err)
+	}
+	path := filepath.Join(t.TempDir(), "fake-git")
+	wrapper := "#!/bin/sh\nGIT=" + real + "\n" + script + "\nexec \"$GIT\" \"$@\"\n"
+	if err := os.WriteFile(path, []byte(wrapper), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	previous := executable
+	SetExecutable(path)
+	t.Cleanup(func() { SetExecutable(previous) })
+}
+
+// assertPaths fails the test unless got lists exactly want, in any order
+func assertPaths(t *testing.T, what string, got []string, want ...string) {
+	t.Helper()
+	got = slices.Clone(got)
+	want = slices.Clone(want)
+	slices.Sort(got)
+	slices.Sort(want)
+	if !slices.Equal(got, want) {
+		t.Errorf("%s = %q, want %q", what, got, want)
+	}
+}
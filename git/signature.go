@@ -0,0 +1,53 @@
+package git
+
+import (
+	"fmt"
+	"strings"
+)
+
+// SigStatus is the outcome of checking a commit's signature
+type SigStatus int
+
+const (
+	SigNone    SigStatus = iota // Not signed
+	SigGood                     // Valid signature from a trusted key
+	SigBad                      // Signature doesn't match the content
+	SigUnknown                  // Signed, but unverifiable: missing, untrusted, expired or revoked key
+)
+
+// String returns a short description for badges
+func (s SigStatus) String() string {
+	switch s {
+	case SigGood:
+		return "good signature"
+	case SigBad:
+		return "BAD signature"
+	case SigUnknown:
+		return "unverified signature"
+	default:
+		return "unsigned"
+	}
+}
+
+// VerifyCommit checks the signature of the commit at ref
+func (c *Client) VerifyCommit(ref string) (SigStatus, error) {
+	output, err := c.execGit("log", "-1", "--format=%G?", ref, "--")
+	if err != nil {
+		return SigNone, fmt.Errorf("failed to verify %s: %w", ref, err)
+	}
+	return parseSigCode(strings.TrimSpace(output)), nil
+}
+
+// parseSigCode maps git's %G? signature code to a SigStatus
+func parseSigCode(code string) SigStatus {
+	switch code {
+	case "G":
+		return SigGood
+	case "B":
+		return SigBad
+	case "U", "X", "Y", "R", "E":
+		return SigUnknown
+	default:
+		return SigNone
+	}
+}
@@ -1,12 +1,14 @@
 package git
 
 import (
+	"fmt"
+	"os"
 	"strings"
 )
 
-// Status returns the current git status
-func (c *Client) Status() (GitStatus, error) {
-	output, err := c.execGit("status", "--porcelain", "-u")
+// Status returns the current git status, listing untracked files per mode
+func (c *Client) Status(untracked UntrackedMode) (GitStatus, error) {
+	output, err := c.execGit("status", "--porcelain", "--untracked-files="+string(untracked))
 	if err != nil {
 		return GitStatus{}, err
 	}
@@ -16,19 +18,123 @@ func (c *Client) Status() (GitStatus, error) {
 	// Get current branch
 	branch, _ := c.CurrentBranch()
 	status.Branch = branch
+	status.Upstream, status.Ahead, status.Behind = c.aheadBehind()
+
+	// Only repositories with submodules pay for looking them up
+	if _, err := os.Stat(c.FullPath(".gitmodules")); err == nil {
+		status.Submodules, _ = c.submoduleChanges(false)
+		status.StagedSubmodules, _ = c.submoduleChanges(true)
+	}
+
+	// The porcelain T code doesn't say what the type changed from or to
+	if len(status.TypeChanges) > 0 {
+		c.fillTypeChanges(status.TypeChanges, false)
+	}
+	if len(status.StagedTypeChanges) > 0 {
+		c.fillTypeChanges(status.StagedTypeChanges, true)
+	}
 
 	// Check if clean
-	status.IsClean = len(status.Staged) == 0 && len(status.Unstaged) == 0 && len(status.Untracked) == 0
+	status.IsClean = len(status.Staged) == 0 && len(status.Unstaged) == 0 && len(status.Untracked) == 0 &&
+		len(status.Conflicted) == 0
 
 	return status, nil
 }
 
+// aheadBehind returns the upstream of the current branch and how many
+// commits HEAD is ahead of and behind it. Without an upstream, as on a
+// detached HEAD or a local-only branch, upstream is ""
+func (c *Client) aheadBehind() (upstream string, ahead, behind int) {
+	output, err := c.execGit("rev-parse", "--abbrev-ref", "--symbolic-full-name", "@{upstream}")
+	if err != nil {
+		return "", 0, 0
+	}
+	upstream = strings.TrimSpace(output)
+
+	output, err = c.execGit("rev-list", "--left-right", "--count", "HEAD...@{upstream}")
+	if err != nil {
+		return upstream, 0, 0
+	}
+	fmt.Sscan(output, &ahead, &behind)
+	return upstream, ahead, behind
+}
+
+// submoduleChanges returns the submodule pointer changes in the index, or in
+// the working tree when cached is false
+func (c *Client) submoduleChanges(cached bool) (map[string]SubmoduleChange, error) {
+	args := []string{"diff", "--raw", "--abbrev", "--no-renames", "--ignore-submodules=none"}
+	if cached {
+		args = append(args, "--cached")
+	}
+	output, err := c.execGit(args...)
+	if err != nil {
+		return nil, fmt.Errorf("failed to list submodule changes: %w", err)
+	}
+
+	changes := make(map[string]SubmoduleChange)
+	// ":OLDMODE NEWMODE OLDSHA NEWSHA STATUS\tPATH", mode 160000 for submodules
+	for _, line := range strings.Split(output, "\n") {
+		meta, path, ok := strings.Cut(line, "\t")
+		fields := strings.Fields(meta)
+		if !ok || len(fields) < 4 || (fields[0] != ":160000" && fields[1] != "160000") {
+			continue
+		}
+		change := SubmoduleChange{Old: fields[2], New: fields[3]}
+		// The working tree side isn't hashed; the submodule's HEAD is what
+		// staging it would record
+		if strings.Trim(change.New, "0") == "" && fields[1] == "160000" {
+			if head, err := c.execGit("-C", path, "rev-parse", "--short", "HEAD"); err == nil {
+				change.New = strings.TrimSpace(head)
+			}
+		}
+		changes[path] = change
+	}
+	return changes, nil
+}
+
+// fillTypeChanges looks up the old and new modes of the type changes in the
+// index, or in the working tree when cached is false. Changes it can't find
+// keep their empty modes
+func (c *Client) fillTypeChanges(changes map[string]TypeChange, cached bool) {
+	args := []string{"diff", "--raw", "--no-renames", "--diff-filter=T"}
+	if cached {
+		args = append(args, "--cached")
+	}
+	output, err := c.execGit(args...)
+	if err != nil {
+		return
+	}
+
+	// ":OLDMODE NEWMODE OLDSHA NEWSHA T\tPATH"
+	for _, line := range strings.Split(output, "\n") {
+		meta, path, ok := strings.Cut(line, "\t")
+		fields := strings.Fields(meta)
+		if _, tracked := changes[path]; !ok || !tracked || len(fields) < 2 {
+			continue
+		}
+		changes[path] = TypeChange{OldMode: strings.TrimPrefix(fields[0], ":"), NewMode: fields[1]}
+	}
+}
+
 // parseStatusOutput parses the output of `git status --porcelain`
 // Format: XY PATH where X is index status, Y is work tree status
+//
+// Entries are categorized as:
+//
+//	??            Untracked
+//	unmerged      Conflicted (UU, AA, DD, AU, UA, DU, UD)
+//	X not blank   Staged (M, A, D, R, C, T)
+//	Y not blank   Unstaged ( M,  D,  T)
+//
+// Entries changed in both the index and the work tree (MM, AM, RM) land in
+// both Staged and Unstaged so each part can be acted on. Type changes (T) are
+// also noted in TypeChanges or StagedTypeChanges, without their modes
 func parseStatusOutput(output string) GitStatus {
 	var status GitStatus
 
-	lines := strings.Split(strings.TrimSpace(output), "\n")
+	// Only the trailing newline goes; a leading space is the first entry's
+	// index status
+	lines := strings.Split(strings.TrimRight(output, "\n"), "\n")
 	for _, line := range lines {
 		if line == "" {
 			continue
@@ -42,26 +148,68 @@ func parseStatusOutput(output string) GitStatus {
 		y := line[1] // Work tree status
 		filepath := line[3:]
 
+		// Renames and copies are reported as "ORIG -> PATH"
+		var origPath string
+		if x == 'R' || x == 'C' {
+			if parts := strings.SplitN(filepath, " -> ", 2); len(parts) == 2 {
+				origPath = strings.Trim(parts[0], "\"")
+				filepath = parts[1]
+			}
+		}
+
 		// Remove quotes if present
 		filepath = strings.Trim(filepath, "\"")
 
-		// Categorize based on status codes
+		if origPath != "" {
+			if status.Renames == nil {
+				status.Renames = make(map[string]string)
+			}
+			status.Renames[filepath] = origPath
+		}
+
+		if x == 'T' {
+			if status.StagedTypeChanges == nil {
+				status.StagedTypeChanges = make(map[string]TypeChange)
+			}
+			status.StagedTypeChanges[filepath] = TypeChange{}
+		}
+		if y == 'T' {
+			if status.TypeChanges == nil {
+				status.TypeChanges = make(map[string]TypeChange)
+			}
+			status.TypeChanges[filepath] = TypeChange{}
+		}
+
+		// Categorize based on status codes. Untracked must be checked first,
+		// since "??" would otherwise match the work tree case
 		switch {
-		case x != ' ' && x != '?':
+		case x == '?' && y == '?':
+			// Untracked
+			status.Untracked = append(status.Untracked, filepath)
+		case isUnmerged(x, y):
+			// Merge conflict, resolved by staging the file
+			status.Conflicted = append(status.Conflicted, filepath)
+		case x != ' ':
 			// Index has changes (staged)
 			status.Staged = append(status.Staged, filepath)
+			// The work tree may have further changes on top (MM, AM, RM)
+			if y != ' ' {
+				status.Unstaged = append(status.Unstaged, filepath)
+			}
 		case y != ' ':
 			// Work tree has changes (unstaged)
 			status.Unstaged = append(status.Unstaged, filepath)
-		case x == '?' && y == '?':
-			// Untracked
-			status.Untracked = append(status.Untracked, filepath)
 		}
 	}
 
 	return status
 }
 
+// isUnmerged reports whether a porcelain XY code denotes a merge conflict
+func isUnmerged(x, y byte) bool {
+	return x == 'U' || y == 'U' || (x == 'A' && y == 'A') || (x == 'D' && y == 'D')
+}
+
 // StagedCount returns the number of staged files
 func (s GitStatus) StagedCount() int {
 	return len(s.Staged)
@@ -77,18 +225,43 @@ func (s GitStatus) UntrackedCount() int {
 	return len(s.Untracked)
 }
 
+// ConflictedCount returns the number of conflicted files
+func (s GitStatus) ConflictedCount() int {
+	return len(s.Conflicted)
+}
+
 // AllFiles returns all files organized by status
 func (s GitStatus) AllFiles() []FileItem {
 	var items []FileItem
 
+	// Add conflicted files first, they block the merge (marked with !)
+	for _, f := range s.Conflicted {
+		items = append(items, NewFileItem(f, StatusConflicted))
+	}
+
 	// Add unstaged files (marked with -)
 	for _, f := range s.Unstaged {
-		items = append(items, NewFileItem(f, StatusUnstaged))
+		item := NewFileItem(f, StatusUnstaged)
+		if change, ok := s.Submodules[f]; ok {
+			item.Submodule = change.String()
+		}
+		if change, ok := s.TypeChanges[f]; ok {
+			item.TypeChange = change.String()
+		}
+		items = append(items, item)
 	}
 
 	// Add staged files (marked with +)
 	for _, f := range s.Staged {
-		items = append(items, NewFileItem(f, StatusStaged))
+		item := NewFileItem(f, StatusStaged)
+		item.OrigPath = s.Renames[f]
+		if change, ok := s.StagedSubmodules[f]; ok {
+			item.Submodule = change.String()
+		}
+		if change, ok := s.StagedTypeChanges[f]; ok {
+			item.TypeChange = change.String()
+		}
+		items = append(items, item)
 	}
 
 	// Add untracked files (marked with ?)
@@ -102,9 +275,20 @@ func (s GitStatus) AllFiles() []FileItem {
 // FileItem represents a file in the git status
 type FileItem struct {
 	Path         string
+	OrigPath     string // Original path of a staged rename or copy
 	Status       FileStatus
 	StatusSymbol string
 	Selected     bool
+	Submodule    string // Pointer change of a submodule, like "1a2b3c4→5d6e7f8"
+	TypeChange   string // Change of file type, like "regular file → symlink"
+}
+
+// Paths returns the item's path along with its original path, if renamed
+func (f FileItem) Paths() []string {
+	if f.OrigPath != "" {
+		return []string{f.Path, f.OrigPath}
+	}
+	return []string{f.Path}
 }
 
 // NewFileItem creates a new FileItem
@@ -121,6 +305,8 @@ func NewFileItem(path string, status FileStatus) FileItem {
 		item.StatusSymbol = "-"
 	case StatusUntracked:
 		item.StatusSymbol = "?"
+	case StatusConflicted:
+		item.StatusSymbol = "!"
 	}
 
 	return item
@@ -0,0 +1,215 @@
+package git
+
+import (
+	"maps"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestStatus(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("modified.txt", "one\n")
+	r.write("staged.txt", "one\n")
+	r.write("deleted.txt", "one\n")
+	r.commit("initial")
+
+	r.write("modified.txt", "two\n")
+	r.write("staged.txt", "two\n")
+	r.git("add", "staged.txt")
+	r.git("rm", "-q", "--cached", "deleted.txt")
+	r.write("untracked.txt", "new\n")
+
+	status := r.status()
+	assertPaths(t, "Staged", status.Staged, "staged.txt", "deleted.txt")
+	assertPaths(t, "Unstaged", status.Unstaged, "modified.txt")
+	// The file removed from the index is still in the working tree
+	assertPaths(t, "Untracked", status.Untracked, "deleted.txt", "untracked.txt")
+	if status.Branch != "main" {
+		t.Errorf("Branch = %q, want main", status.Branch)
+	}
+	if status.IsClean {
+		t.Error("IsClean = true for a repository with changes")
+	}
+}
+
+func TestStatusClean(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.commit("initial")
+
+	status := r.status()
+	if !status.IsClean {
+		t.Errorf("IsClean = false, status %+v", status)
+	}
+}
+
+func TestParseStatusOutput(t *testing.T) {
+	tests := []struct {
+		name       string
+		output     string
+		staged     []string
+		unstaged   []string
+		untracked  []string
+		conflicted []string
+		renames    map[string]string
+		// Paths noted as changing type in the index and the working tree
+		stagedTypes []string
+		types       []string
+	}{
+		{name: "modified in index", output: "M  a.txt\n", staged: []string{"a.txt"}},
+		{name: "modified in worktree", output: " M a.txt\n", unstaged: []string{"a.txt"}},
+		{name: "modified in both", output: "MM a.txt\n", staged: []string{"a.txt"}, unstaged: []string{"a.txt"}},
+		{name: "added", output: "A  a.txt\n", staged: []string{"a.txt"}},
+		{name: "untracked", output: "?? a.txt\n", untracked: []string{"a.txt"}},
+		{name: "untracked directory", output: "?? dir/\n", untracked: []string{"dir/"}},
+		{
+			name:    "renamed",
+			output:  "R  old.txt -> new.txt\n",
+			staged:  []string{"new.txt"},
+			renames: map[string]string{"new.txt": "old.txt"},
+		},
+		{
+			name:    "copied",
+			output:  "C  a.txt -> copy.txt\n",
+			staged:  []string{"copy.txt"},
+			renames: map[string]string{"copy.txt": "a.txt"},
+		},
+		{name: "both modified", output: "UU a.txt\n", conflicted: []string{"a.txt"}},
+		{name: "both added", output: "AA a.txt\n", conflicted: []string{"a.txt"}},
+		{name: "both deleted", output: "DD a.txt\n", conflicted: []string{"a.txt"}},
+		{name: "deleted by them", output: "UD a.txt\n", conflicted: []string{"a.txt"}},
+		{name: "deleted in index", output: "D  a.txt\n", staged: []string{"a.txt"}},
+		{name: "deleted in worktree", output: " D a.txt\n", unstaged: []string{"a.txt"}},
+		{name: "type changed in index", output: "T  a.txt\n", staged: []string{"a.txt"}, stagedTypes: []string{"a.txt"}},
+		{name: "type changed in worktree", output: " T a.txt\n", unstaged: []string{"a.txt"}, types: []string{"a.txt"}},
+		{
+			name:        "type changed, then modified",
+			output:      "TM a.txt\n",
+			staged:      []string{"a.txt"},
+			unstaged:    []string{"a.txt"},
+			stagedTypes: []string{"a.txt"},
+		},
+		{name: "quoted path", output: "M  \"with space.txt\"\n", staged: []string{"with space.txt"}},
+		{
+			name:    "renamed quoted paths",
+			output:  "R  \"old name.txt\" -> \"new name.txt\"\n",
+			staged:  []string{"new name.txt"},
+			renames: map[string]string{"new name.txt": "old name.txt"},
+		},
+		{
+			name:      "several entries",
+			output:    " M first.txt\nA  second.txt\n?? third.txt\n",
+			staged:    []string{"second.txt"},
+			unstaged:  []string{"first.txt"},
+			untracked: []string{"third.txt"},
+		},
+		{name: "empty", output: ""},
+		{name: "short line ignored", output: "M \n"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := parseStatusOutput(tt.output)
+			assertPaths(t, "Staged", status.Staged, tt.staged...)
+			assertPaths(t, "Unstaged", status.Unstaged, tt.unstaged...)
+			assertPaths(t, "Untracked", status.Untracked, tt.untracked...)
+			assertPaths(t, "Conflicted", status.Conflicted, tt.conflicted...)
+			if len(status.Renames) != len(tt.renames) {
+				t.Errorf("Renames = %v, want %v", status.Renames, tt.renames)
+			}
+			for path, orig := range tt.renames {
+				if status.Renames[path] != orig {
+					t.Errorf("Renames[%s] = %q, want %q", path, status.Renames[path], orig)
+				}
+			}
+			assertPaths(t, "StagedTypeChanges", slices.Collect(maps.Keys(status.StagedTypeChanges)), tt.stagedTypes...)
+			assertPaths(t, "TypeChanges", slices.Collect(maps.Keys(status.TypeChanges)), tt.types...)
+		})
+	}
+}
+
+// TestStatusIndexAndWorktreeChanges checks files changed both in the index
+// and after it land in both Staged and Unstaged, so each part can be acted on
+func TestStatusIndexAndWorktreeChanges(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("modified.txt", "one\n")
+	r.write("old.txt", "a file long enough to be detected as renamed\n")
+	r.commit("initial")
+
+	// MM: staged a change, then changed it again
+	r.write("modified.txt", "two\n")
+	r.git("add", "modified.txt")
+	r.write("modified.txt", "three\n")
+	// AM: added, then changed
+	r.write("added.txt", "one\n")
+	r.git("add", "added.txt")
+	r.write("added.txt", "two\n")
+	// RM: renamed, then changed
+	r.git("mv", "old.txt", "renamed.txt")
+	r.write("renamed.txt", "a file long enough to be detected as renamed\nand changed\n")
+
+	porcelain := r.git("status", "--porcelain")
+	for _, code := range []string{"MM modified.txt", "AM added.txt", "RM old.txt -> renamed.txt"} {
+		if !strings.Contains(porcelain, code) {
+			t.Fatalf("setup didn't produce %q:\n%s", code, porcelain)
+		}
+	}
+
+	status := r.status()
+	assertPaths(t, "Staged", status.Staged, "modified.txt", "added.txt", "renamed.txt")
+	assertPaths(t, "Unstaged", status.Unstaged, "modified.txt", "added.txt", "renamed.txt")
+	if orig := status.Renames["renamed.txt"]; orig != "old.txt" {
+		t.Errorf("Renames[renamed.txt] = %q, want old.txt", orig)
+	}
+
+	// Each file is listed once per part
+	counts := make(map[FileStatus]int)
+	for _, item := range status.AllFiles() {
+		counts[item.Status]++
+	}
+	if counts[StatusStaged] != 3 || counts[StatusUnstaged] != 3 {
+		t.Errorf("AllFiles lists %d staged and %d unstaged items, want 3 of each", counts[StatusStaged], counts[StatusUnstaged])
+	}
+}
+
+// TestStatusTypeChange replaces files with symlinks and the other way round,
+// checking the list names both kinds
+func TestStatusTypeChange(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("file.txt", "one\n")
+	r.write("target.txt", "target\n")
+	if err := os.Symlink("target.txt", filepath.Join(r.dir, "link.txt")); err != nil {
+		t.Skipf("can't create symlinks: %v", err)
+	}
+	r.commit("initial")
+
+	// file.txt becomes a symlink, left unstaged; link.txt a file, staged
+	if err := os.Remove(filepath.Join(r.dir, "file.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Symlink("target.txt", filepath.Join(r.dir, "file.txt")); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.Remove(filepath.Join(r.dir, "link.txt")); err != nil {
+		t.Fatal(err)
+	}
+	r.write("link.txt", "now a file\n")
+	r.git("add", "link.txt")
+
+	status := r.status()
+	assertPaths(t, "Unstaged", status.Unstaged, "file.txt")
+	assertPaths(t, "Staged", status.Staged, "link.txt")
+
+	want := map[string]string{
+		"file.txt": "regular file → symlink",
+		"link.txt": "symlink → regular file",
+	}
+	for _, item := range status.AllFiles() {
+		if item.TypeChange != want[item.Path] {
+			t.Errorf("%s TypeChange = %q, want %q", item.Path, item.TypeChange, want[item.Path])
+		}
+	}
+}
@@ -1,5 +1,11 @@
 package git
 
+import (
+	"fmt"
+	"strings"
+	"time"
+)
+
 // FileStatus represents the git status of a file
 type FileStatus int
 
@@ -7,6 +13,7 @@ const (
 	StatusStaged FileStatus = iota
 	StatusUnstaged
 	StatusUntracked
+	StatusConflicted
 )
 
 func (s FileStatus) String() string {
@@ -17,6 +24,8 @@ func (s FileStatus) String() string {
 		return "unstaged"
 	case StatusUntracked:
 		return "untracked"
+	case StatusConflicted:
+		return "conflicted"
 	default:
 		return "unknown"
 	}
@@ -27,7 +36,16 @@ type GitStatus struct {
 	Staged      []string
 	Unstaged    []string
 	Untracked   []string
+	Conflicted  []string // Unmerged paths of an interrupted merge, rebase, etc.
+	Renames     map[string]string // New path -> original path for staged renames/copies
+	Submodules  map[string]SubmoduleChange // Path -> unstaged submodule pointer change
+	StagedSubmodules map[string]SubmoduleChange // Path -> staged submodule pointer change
+	TypeChanges       map[string]TypeChange // Path -> unstaged change of file type
+	StagedTypeChanges map[string]TypeChange // Path -> staged change of file type
 	Branch      string
+	Upstream    string // Branch the current one tracks, "" when none
+	Ahead       int    // Commits on HEAD not yet on Upstream
+	Behind      int    // Commits on Upstream not yet on HEAD
 	IsClean     bool
 }
 
@@ -39,4 +57,208 @@ type CommitInfo struct {
 	Author    string
 	Date      string
 	IsPushed  bool
+	IsRoot    bool // The repository's first commit, with no parent
+	Signature SigStatus
+}
+
+// SubmoduleChange is a submodule's recorded commit moving from Old to New,
+// both abbreviated
+type SubmoduleChange struct {
+	Old string
+	New string
+}
+
+// String formats the change like "1a2b3c4→5d6e7f8". A submodule still on
+// its recorded commit only has changes inside it
+func (s SubmoduleChange) String() string {
+	if s.Old == s.New {
+		return "modified content"
+	}
+	return s.Old + "→" + s.New
+}
+
+// TypeChange is a path changing kind, such as a regular file replaced by a
+// symlink, given as the old and new git modes
+type TypeChange struct {
+	OldMode string
+	NewMode string
+}
+
+// String formats the change like "regular file → symlink", or "type
+// changed" when the modes aren't known
+func (t TypeChange) String() string {
+	if t.OldMode == "" || t.NewMode == "" {
+		return "type changed"
+	}
+	return modeKind(t.OldMode) + " → " + modeKind(t.NewMode)
+}
+
+// modeKind names the kind of entry a git mode denotes
+func modeKind(mode string) string {
+	switch mode {
+	case "100644":
+		return "regular file"
+	case "100755":
+		return "executable file"
+	case "120000":
+		return "symlink"
+	case "160000":
+		return "submodule"
+	default:
+		return "mode " + mode
+	}
+}
+
+// BlameInfo describes the commit that last changed a line
+type BlameInfo struct {
+	Hash    string // All zeros for changes not committed yet
+	Author  string
+	Time    time.Time
+	Summary string
+}
+
+// Committed reports whether the line comes from a commit rather than the
+// index or working tree
+func (b BlameInfo) Committed() bool {
+	return strings.Trim(b.Hash, "0") != ""
+}
+
+// ShortHash returns the abbreviated commit hash
+func (b BlameInfo) ShortHash() string {
+	if len(b.Hash) > 7 {
+		return b.Hash[:7]
+	}
+	return b.Hash
+}
+
+// DiffStat holds the line counts of a file's changes from `git diff --numstat`
+type DiffStat struct {
+	Path    string
+	Added   int
+	Deleted int
+	Binary  bool
+}
+
+// DiffTotals adds up the line counts of several files' changes
+type DiffTotals struct {
+	Added   int
+	Deleted int
+	Binary  int // Binary files, which have no line counts
+}
+
+// SumDiffStats totals per-file diff stats
+func SumDiffStats(stats map[string]DiffStat) DiffTotals {
+	var t DiffTotals
+	for _, stat := range stats {
+		if stat.Binary {
+			t.Binary++
+			continue
+		}
+		t.Added += stat.Added
+		t.Deleted += stat.Deleted
+	}
+	return t
+}
+
+// String formats the totals like "+12 -3", with a "bin: N" count when
+// binary files changed
+func (t DiffTotals) String() string {
+	s := fmt.Sprintf("+%d -%d", t.Added, t.Deleted)
+	if t.Binary > 0 {
+		s += fmt.Sprintf(" bin: %d", t.Binary)
+	}
+	return s
+}
+
+// UntrackedMode controls how `git status` lists untracked files
+type UntrackedMode string
+
+const (
+	UntrackedAll    UntrackedMode = "all"    // Every untracked file, recursing into directories
+	UntrackedNormal UntrackedMode = "normal" // Untracked directories as a single entry
+	UntrackedNo     UntrackedMode = "no"     // No untracked files at all
+)
+
+// Next returns the mode after m, cycling normal -> all -> no
+func (m UntrackedMode) Next() UntrackedMode {
+	switch m {
+	case UntrackedNormal:
+		return UntrackedAll
+	case UntrackedAll:
+		return UntrackedNo
+	default:
+		return UntrackedNormal
+	}
+}
+
+// DiffOptions controls how diffs are generated
+type DiffOptions struct {
+	IgnoreWhitespace    bool // --ignore-all-space
+	HighlightWhitespace bool // --ws-error-highlight=all
+	WordDiff            bool // --word-diff=color
+	Algorithm           DiffAlgorithm
+	Renames             RenameDetection
+	Context             int  // Lines of context around changes (-U), zero for git's default
+	HideContext         bool // --unified=0, only the changed lines under each hunk header
+}
+
+// RenameDetection controls how diffs pair up moved and copied files
+type RenameDetection string
+
+const (
+	RenamesDefault RenameDetection = ""       // git's own setting (diff.renames)
+	RenamesCopies  RenameDetection = "copies" // --find-renames --find-copies
+	RenamesOff     RenameDetection = "off"    // --no-renames, show a delete and an add
+)
+
+// Next returns the setting after r, cycling back to the default
+func (r RenameDetection) Next() RenameDetection {
+	switch r {
+	case RenamesDefault:
+		return RenamesCopies
+	case RenamesCopies:
+		return RenamesOff
+	default:
+		return RenamesDefault
+	}
+}
+
+// String returns the setting's name, "default" for git's own choice
+func (r RenameDetection) String() string {
+	if r == RenamesDefault {
+		return "default"
+	}
+	return string(r)
+}
+
+// DiffAlgorithm selects git's --diff-algorithm; the zero value is git's default
+type DiffAlgorithm string
+
+const (
+	DiffAlgorithmDefault   DiffAlgorithm = ""
+	DiffAlgorithmPatience  DiffAlgorithm = "patience"
+	DiffAlgorithmHistogram DiffAlgorithm = "histogram"
+	DiffAlgorithmMinimal   DiffAlgorithm = "minimal"
+)
+
+// Next returns the algorithm after a, cycling back to the default
+func (a DiffAlgorithm) Next() DiffAlgorithm {
+	switch a {
+	case DiffAlgorithmDefault:
+		return DiffAlgorithmHistogram
+	case DiffAlgorithmHistogram:
+		return DiffAlgorithmPatience
+	case DiffAlgorithmPatience:
+		return DiffAlgorithmMinimal
+	default:
+		return DiffAlgorithmDefault
+	}
+}
+
+// String returns the algorithm's name, "default" for git's own choice
+func (a DiffAlgorithm) String() string {
+	if a == DiffAlgorithmDefault {
+		return "default"
+	}
+	return string(a)
 }
@@ -0,0 +1,98 @@
+package main
+
+import (
+	"fmt"
+	"strings"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// Macro steps, as named in the settings
+const (
+	macroStageTracked = "stage-tracked" // Stage all modified tracked files
+	macroCommit       = "commit"        // Commit the staged files, pausing for the message
+	macroPush         = "push"          // Push the current branch
+)
+
+// defaultMacro is run by the macro key when the settings don't name steps
+var defaultMacro = []string{macroStageTracked, macroCommit, macroPush}
+
+// macroRun is a macro part way through. Each step starts the next one from
+// the message reporting its success; a failure or cancel drops the rest
+type macroRun struct {
+	steps  []string // Steps not started yet
+	staged bool     // An earlier step staged files the status doesn't show yet
+}
+
+type gitPushMsg struct {
+	err error
+}
+
+// pushCmd pushes the current branch
+func (m *Model) pushCmd() tea.Cmd {
+	return func() tea.Msg {
+		return gitPushMsg{err: m.gitClient.Push()}
+	}
+}
+
+// startMacro runs the configured macro from its first step
+func (m *Model) startMacro() tea.Cmd {
+	steps := m.settings.Macro
+	if len(steps) == 0 {
+		steps = defaultMacro
+	}
+	for _, step := range steps {
+		switch step {
+		case macroStageTracked, macroCommit, macroPush:
+		default:
+			m.err = fmt.Sprintf("Unknown macro step %q in settings", step)
+			return m.clearError()
+		}
+	}
+	m.macro = &macroRun{steps: append([]string{}, steps...)}
+	m.status = "Macro: " + strings.Join(steps, " → ")
+	return tea.Batch(m.nextMacroStep(), m.clearStatus())
+}
+
+// nextMacroStep starts the macro's next step, if a macro is running
+func (m *Model) nextMacroStep() tea.Cmd {
+	if m.macro == nil {
+		return nil
+	}
+	if len(m.macro.steps) == 0 {
+		m.macro = nil
+		return nil
+	}
+	step := m.macro.steps[0]
+	m.macro.steps = m.macro.steps[1:]
+
+	switch step {
+	case macroStageTracked:
+		if m.gitStatus.ConflictedCount() > 0 {
+			return m.stopMacro("Macro stopped: resolve conflicts before staging")
+		}
+		// Nothing to stage isn't a failure; what's staged already still counts
+		if m.gitStatus.UnstagedCount() == 0 {
+			return m.nextMacroStep()
+		}
+		return m.runOperation("git add -u", m.stageTrackedCmd())
+
+	case macroCommit:
+		if m.gitStatus.StagedCount() == 0 && !m.macro.staged {
+			return m.stopMacro("Macro stopped: no files staged")
+		}
+		// Paused until the commit lands or is cancelled
+		return m.enterCommitMode()
+
+	case macroPush:
+		return m.runOperation("git push", m.pushCmd())
+	}
+	return nil
+}
+
+// stopMacro drops the rest of a running macro, saying why
+func (m *Model) stopMacro(reason string) tea.Cmd {
+	m.macro = nil
+	m.status = reason
+	return m.clearStatus()
+}
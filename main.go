@@ -1,8 +1,13 @@
 package main
 
 import (
+	"errors"
+	"flag"
 	"fmt"
+	"log/slog"
 	"os"
+	"path/filepath"
+	"runtime/debug"
 
 	tea "github.com/charmbracelet/bubbletea"
 
@@ -10,21 +15,113 @@ import (
 )
 
 func main() {
-	// Check if we're in a git repository
-	if !git.IsRepo(".") {
-		fmt.Fprintln(os.Stderr, "Error: Not in a git repository")
+	noAltScreen := flag.Bool("no-alt-screen", false, "render inline so the final frame stays in the scrollback")
+	debugLog := flag.Bool("debug", false, "write debug logs to "+debugLogPath()+" (or set IGIT_DEBUG)")
+	refresh := flag.Duration("refresh", 0, "poll git status at this interval, e.g. 5s (0 disables polling)")
+	previewLines := flag.Int("preview-lines", defaultPreviewLimit, "truncate previews after this many lines (0 shows everything)")
+	showVersion := flag.Bool("version", false, "print version information and exit")
+	useDiskCache := flag.Bool("disk-cache", false, "keep previews in the user cache directory between sessions")
+	gitPath := flag.String("git", os.Getenv("IGIT_GIT"), "git executable to run (default \"git\" from PATH, or set IGIT_GIT)")
+	flag.Usage = func() {
+		fmt.Fprintf(flag.CommandLine.Output(), "Usage: %s [flags] [repository]\n", filepath.Base(os.Args[0]))
+		flag.PrintDefaults()
+	}
+	flag.Parse()
+
+	if *showVersion {
+		fmt.Println(versionString())
+		return
+	}
+
+	// Logging is opt-in; by default records would end up on the terminal
+	slog.SetDefault(slog.New(slog.DiscardHandler))
+
+	// Send log output to a file, since the TUI owns the terminal
+	if *debugLog || os.Getenv("IGIT_DEBUG") != "" {
+		logFile, err := tea.LogToFile(debugLogPath(), "igit")
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+		defer logFile.Close()
+
+		logger := slog.New(slog.NewTextHandler(logFile, &slog.HandlerOptions{
+			Level: slog.LevelDebug,
+		}))
+		slog.SetDefault(logger)
+		git.SetLogger(logger)
+	}
+
+	if *gitPath != "" {
+		git.SetExecutable(*gitPath)
+	}
+
+	// Open the repository given on the command line
+	if dir := flag.Arg(0); dir != "" {
+		if err := os.Chdir(dir); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Check that git is installed and we're in a git repository
+	client, err := git.NewClient(".")
+	if errors.Is(err, git.ErrNotRepository) && isTerminal(os.Stdin) {
+		// Launched from the wrong directory; offer to open one instead
+		fmt.Fprintf(os.Stderr, "%v\n", err)
+		if dir := pickRepo(os.Stdin, os.Stderr, "."); dir != "" {
+			if chdirErr := os.Chdir(dir); chdirErr != nil {
+				err = chdirErr
+			} else {
+				client, err = git.NewClient(".")
+			}
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		if hint := startupHint(err); hint != "" {
+			fmt.Fprintln(os.Stderr, hint)
+		}
 		os.Exit(1)
 	}
 
+	// Warn about git releases that predate flags we rely on
+	if version, err := client.GitVersion(); err == nil && !version.AtLeast(git.MinVersion) {
+		fmt.Fprintf(os.Stderr, "Warning: git %s is older than %s; some features may not work\n",
+			version, git.MinVersion)
+	}
+
 	// Create the initial model
 	m := NewModel()
+	m.refreshInterval = *refresh
+	m.previewLimit = *previewLines
+	if *useDiskCache && m.gitClient != nil {
+		cache, err := newDiskCache(m.gitClient, defaultDiskCacheSize)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: disk cache disabled: %v\n", err)
+		} else {
+			m.diskCache = cache
+		}
+	}
 
 	// Create a Bubble Tea program
-	p := tea.NewProgram(
-		m,
-		tea.WithAltScreen(),
-		tea.WithMouseCellMotion(),
-	)
+	opts := []tea.ProgramOption{tea.WithMouseCellMotion(), tea.WithoutCatchPanics()}
+	if !*noAltScreen {
+		opts = append(opts, tea.WithAltScreen())
+	}
+	p := tea.NewProgram(m, opts...)
+
+	// Update recovers on its own; this catches anything else (e.g. in View)
+	// and puts the terminal back before reporting it
+	defer func() {
+		if r := recover(); r != nil {
+			_ = p.ReleaseTerminal()
+			stack := debug.Stack()
+			slog.Error("panic", "panic", r, "stack", string(stack))
+			fmt.Fprintf(os.Stderr, "Caught panic: %v\n\n%s", r, stack)
+			os.Exit(2)
+		}
+	}()
 
 	// Run the program
 	if _, err := p.Run(); err != nil {
@@ -32,3 +129,25 @@ func main() {
 		os.Exit(1)
 	}
 }
+
+// startupHint suggests how to fix err, an error opening the repository, or
+// returns "" when the error says it all
+func startupHint(err error) string {
+	switch {
+	case errors.Is(err, git.ErrGitNotFound):
+		return "Install git, or point -git or IGIT_GIT at the executable"
+	case errors.Is(err, git.ErrUnsafeRepository):
+		return "If you trust it, allow it with: git config --global --add safe.directory <repository>"
+	case errors.Is(err, git.ErrNoWorkTree):
+		return "Open a checkout of the repository instead"
+	case errors.Is(err, git.ErrNoAccess):
+		return "Check the permissions of the directory and its .git"
+	}
+	return ""
+}
+
+// debugLogPath returns where --debug writes its log; outside the repository
+// so the log never shows up as an untracked file
+func debugLogPath() string {
+	return filepath.Join(os.TempDir(), "igit-debug.log")
+}
@@ -3,19 +3,30 @@ package main
 import (
 	"fmt"
 	"io"
+	"os"
+	"path/filepath"
+	"strings"
 	"time"
+	"unicode/utf8"
 
-	tea "github.com/charmbracelet/bubbletea"
+	"github.com/charmbracelet/bubbles/help"
+	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
+	"github.com/charmbracelet/bubbles/progress"
 	"github.com/charmbracelet/bubbles/textarea"
 	"github.com/charmbracelet/bubbles/textinput"
 	"github.com/charmbracelet/bubbles/viewport"
+	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/lipgloss"
+	"github.com/muesli/termenv"
 
 	"github.com/rai/interactive-git/git"
 	"github.com/rai/interactive-git/ui"
 )
 
+// defaultPreviewLimit keeps huge diffs and files from slowing down navigation
+const defaultPreviewLimit = 5000
+
 // AppState represents the current state of the application
 type AppState int
 
@@ -25,6 +36,15 @@ const (
 	StateCommitDate
 	StateModifyHead
 	StateHelp
+	StateApplyPatch
+	StatePalette
+	StateRestoreRef
+	StateReview
+	StatePatchMode
+	StateCommitFiles
+	StateFlaggedFiles
+	StateExportPatch
+	StateDiff
 )
 
 // CommitState represents the current commit input state
@@ -40,9 +60,18 @@ const (
 type HeadModifyState int
 
 const (
-	HeadModifyStateMenu HeadModifyState = iota
-	HeadModifyStateAmendMessage
-	HeadModifyStateAmendFiles
+	HeadModifyStateMenu   HeadModifyState = iota
+	HeadModifyStateAmend                  // Edit the message and fold in staged changes
+	HeadModifyStateRedate                 // Pick an earlier commit and its new date
+)
+
+// Pane identifies the part of the screen that navigation keys act on
+type Pane int
+
+const (
+	PaneList          Pane = iota
+	PanePreview            // The preview, or the staged side of the split preview
+	PaneSplitUnstaged      // The unstaged side of the split preview
 )
 
 // Model holds the application state
@@ -53,8 +82,40 @@ type Model struct {
 	height     int
 	ready      bool
 	err        string
+	errSticky  bool           // err is multi-line and stays in its own view until dismissed
+	errorView  viewport.Model // Scrolls a sticky error
 	status     string
 	processing bool
+	confirm    *confirmation // Pending yes/no question, answered before any other key
+
+	refreshInterval time.Duration // Polls git status this often; zero disables it
+	untrackedMode   git.UntrackedMode
+	refreshPending  bool // A refresh was held back while typing
+
+	// Slow operation toast
+	processingID    int    // Bumped by every startProcessing, so stale ticks are ignored
+	processingLabel string // Git command shown in the toast, e.g. "git add"
+	slowOperation   bool   // Set once the running operation outlives slowOperationDelay
+
+	// Retrying a failed operation
+	lastOperation *operation // Most recent operation started with runOperation
+	retryable     bool       // lastOperation ended in an error and can be run again
+
+	// Macro
+	macro *macroRun // Running macro, nil when none is
+
+	// Copying paths
+	copiedPath string   // File whose path was copied last
+	copyForm   pathForm // Form it was copied in, advanced by repeated copies
+
+	// Key hints popup
+	keyHintsID   int  // Bumped by every key press, so hints only show after a pause
+	showKeyHints bool // The current mode's keys are popped up
+
+	// Bulk operation progress
+	progress      progress.Model
+	progressDone  int
+	progressTotal int // Zero when no bulk operation is running
 
 	// Git data
 	gitClient *git.Client
@@ -62,22 +123,53 @@ type Model struct {
 	gitStatus git.GitStatus
 
 	// UI Components
-	list       list.Model
-	viewport   viewport.Model
-	keys       ui.KeyMap
-	delegate   *FileDelegate
+	list     list.Model
+	palette  list.Model // Command palette, while StatePalette is open
+	viewport viewport.Model
+	keys     ui.KeyMap
+	help     help.Model
+	delegate *FileDelegate
+	settings settings // Preferences saved between sessions
 
 	// UI State
 	selectedFiles   map[int]bool
 	showPreview     bool
-	previewFocused  bool // Track if preview pane has focus
-	lastStatusMsg   time.Time
-	lastFileIndex   int // Track last fetched file to avoid redundant diffs
+	focus           Pane      // Pane that navigation keys act on
+	maximizePreview bool      // Show the preview full screen while keys still move through the list
+	lastStatusMsg   time.Time // When the current status was set, to match its clear timer
+	lastErrorMsg    time.Time // When the current error was set, to match its clear timer
+	errFlashAt      time.Time // When the error line started flashing, zero once it stops
+	lastFileIndex   int       // Index in files of the last fetched file, to avoid redundant diffs
 
 	// Preview/Layout
-	previewContent string
-	diffCache      map[string]string // Cache file diffs
-	layout         ui.Layout
+	previewContent    string
+	previewTitle      string         // Overrides the file title when showing non-file content
+	previewRef        string         // Commit shown in the preview, if any
+	previewSig        git.SigStatus  // Signature of previewRef
+	previewFile       string         // Path of the file whose diff is in the preview
+	previewLimit      int            // Lines shown before a preview is truncated; zero shows all
+	previewFull       string         // File whose preview was loaded past the limit
+	previewHidden     int            // Lines cut off the current preview by previewLimit
+	previewTranscoded bool           // The preview wasn't UTF-8 and is shown best-effort
+	splitPreview      bool           // Show partially staged files' staged and unstaged diffs side by side
+	splitFile         string         // File the split diffs belong to
+	splitStaged       []string       // Lines of the staged (--cached) diff
+	splitUnstaged     []string       // Lines of the unstaged diff
+	splitStagedAt     int            // First line shown of the staged split diff
+	splitUnstagedAt   int            // First line shown of the unstaged split diff
+	fileContext       map[string]int // Extra context lines per file, grown with ExpandContext
+	keepLine          int            // New-file line to keep in place when the preview reloads
+	keepRow           int            // Viewport row keepLine was shown at
+	previewRows       []int          // First viewport row of each preview line
+	wrapPreview       bool
+	diffCursor        int               // Preview line under the cursor while focused
+	diffAnchor        int               // Start of a line selection, or -1
+	diffCache         map[string]string // Cache file diffs, keyed by diffCacheKey
+	followPath        string            // File staged from the cursor; its staged entry is selected once listed
+	diskCache         *diskCache        // Keeps diffs between sessions, enabled with --disk-cache
+	diffOptions       git.DiffOptions
+	colorProfile      termenv.Profile // What the terminal can display of git's colors
+	layout            ui.Layout
 
 	// Commit UI
 	commitTextarea textarea.Model
@@ -85,24 +177,146 @@ type Model struct {
 	commitMessage  string
 	commitDate     string
 	commitState    CommitState
+	commitPaths    []string // Limits the commit to these paths when set
+	stagedStats    map[string]git.DiffStat
+	unstagedTotals git.DiffTotals // Lines added and deleted across unstaged files
 
 	// HEAD Modification
-	headInfo           *git.CommitInfo
-	headModifyState    HeadModifyState
+	headInfo            *git.CommitInfo
+	headModifyState     HeadModifyState
 	headMessageTextarea textarea.Model
+	amendResetDate      bool // Give the amended commit a new author date instead of keeping it
+	reverting           bool // A revert stopped on conflicts, so it can be aborted
+	rebasing            bool // A rebase is stopped, so it can be aborted
+	redateInput         textinput.Model
+	redateRef           string // Commit being redated, once chosen; the input then takes its date
+
+	// Patch application
+	patchInput   textinput.Model
+	patchCached  bool   // Apply to the index instead of the working tree
+	patchCheck   string // Result of the last check, shown below the input
+	patchChecked string // Path that passed the check; applying needs a fresh one
+
+	// Reviewing the staged patch
+	reviewView viewport.Model
+	reviewFrom AppState // State to return to when the review closes
+
+	// Patch mode
+	patch     *patchSession
+	patchView viewport.Model
+
+	// Browsing the files of a commit
+	commitFilesRef string
+	commitFiles    list.Model
+	commitFileView viewport.Model
+
+	// Files with assume-unchanged or skip-worktree set
+	flagged list.Model
+
+	// Exporting a patch
+	exportInput  textinput.Model
+	exportFiles  []string // Files to export, chosen when the prompt opened; none means all
+	exportStaged bool     // Export staged changes instead of unstaged ones
+
+	// Reading one file's diff full screen
+	diffView      viewport.Model
+	diffViewFile  string
+	diffViewText  string   // The colored diff, laid out again when the width changes
+	diffViewLines []string // The diff's lines without colors, for search and hunk jumps
+	diffViewRows  []int    // First viewport row of each line, which wrap
+	diffViewAt    int      // Line the last search or hunk jump landed on, -1 for none
+	diffSearch    textinput.Model
+	diffQuery     string // Last search, repeated by NextMatch and PrevMatch
+
+	// Restoring files from a ref
+	restoreInput textinput.Model
+	restorePaths []string // Files to restore, chosen when the prompt opened
+}
+
+// confirmation is a yes/no question asked in the footer before a
+// destructive action
+type confirmation struct {
+	prompt      string
+	onConfirm   func(m *Model) tea.Cmd
+	destructive bool // Loses work or rewrites history, so Enter answers no
 }
 
 // FileDelegate is a custom delegate for rendering file items
 type FileDelegate struct {
-	styles FileStyles
+	styles      FileStyles
+	root        string // Repository root the file paths are relative to
+	cwd         string // Directory igit was started in
+	cwdRelative bool   // Show paths relative to cwd rather than root
+	glyphs      ui.Glyphs
+}
+
+// statusGlyph returns the symbol drawn for a file status
+func (d *FileDelegate) statusGlyph(status git.FileStatus) string {
+	switch status {
+	case git.StatusStaged:
+		return d.glyphs.Staged
+	case git.StatusUnstaged:
+		return d.glyphs.Unstaged
+	case git.StatusUntracked:
+		return d.glyphs.Untracked
+	case git.StatusConflicted:
+		return d.glyphs.Conflicted
+	default:
+		return " "
+	}
+}
+
+// displayPath returns how a file's path is shown in the list
+func (d *FileDelegate) displayPath(path string) string {
+	if !d.cwdRelative || d.cwd == d.root {
+		return path
+	}
+	rel, err := filepath.Rel(d.cwd, filepath.Join(d.root, path))
+	if err != nil {
+		return path
+	}
+	rel = filepath.ToSlash(rel)
+	// Untracked directories keep their trailing slash
+	if strings.HasSuffix(path, "/") {
+		rel += "/"
+	}
+	return rel
+}
+
+// filterMatches returns the rune positions in display, the path as shown,
+// that the list's filter matched. The filter runs on the repository path, so
+// the positions shift when one path is a suffix of the other, as cwd-relative
+// paths are; matches outside the shared part aren't shown
+func (d *FileDelegate) filterMatches(m list.Model, index int, path, display string) []int {
+	if m.FilterState() == list.Unfiltered || m.FilterValue() == "" {
+		return nil
+	}
+	matches := m.MatchesForItem(index)
+	if display == path {
+		return matches
+	}
+	if !strings.HasSuffix(path, display) && !strings.HasSuffix(display, path) {
+		return nil
+	}
+
+	offset := utf8.RuneCountInString(display) - utf8.RuneCountInString(path)
+	var shifted []int
+	for _, i := range matches {
+		if i+offset >= 0 {
+			shifted = append(shifted, i+offset)
+		}
+	}
+	return shifted
 }
 
 type FileStyles struct {
-	Normal   lipgloss.Style
-	Selected lipgloss.Style
-	Staged   lipgloss.Style
-	Unstaged lipgloss.Style
-	Untracked lipgloss.Style
+	Normal     lipgloss.Style
+	Selected   lipgloss.Style
+	Staged     lipgloss.Style
+	Unstaged   lipgloss.Style
+	Untracked  lipgloss.Style
+	Conflicted lipgloss.Style
+	Match      lipgloss.Style // Characters the list filter matched
 }
 
 // Height returns the height of a list item
@@ -133,42 +347,80 @@ func (d *FileDelegate) Render(w io.Writer, m list.Model, index int, item list.It
 			style = d.styles.Unstaged
 		case git.StatusUntracked:
 			style = d.styles.Untracked
+		case git.StatusConflicted:
+			style = d.styles.Conflicted
 		default:
 			style = d.styles.Normal
 		}
 	}
 
 	// Build display string
-	checkbox := " "
+	checkbox := d.glyphs.Unchecked
 	if fileItem.Selected {
-		checkbox = "X"
+		checkbox = d.glyphs.Checked
 	}
 
 	statusColor := ui.FileStatusColor(fileItem.StatusSymbol)
-	statusStr := lipgloss.NewStyle().Foreground(statusColor).Bold(true).Render(fileItem.StatusSymbol)
+	statusStr := lipgloss.NewStyle().Foreground(statusColor).Bold(true).Render(d.statusGlyph(fileItem.Status))
+
+	// Underline what the filter matched, as the default delegate does
+	path := d.displayPath(fileItem.Path)
+	if matches := d.filterMatches(m, index, fileItem.Path, path); len(matches) > 0 {
+		unmatched := style.Copy().Inline(true)
+		matched := unmatched.Copy().Inherit(d.styles.Match)
+		path = lipgloss.StyleRunes(path, matches, matched, unmatched)
+	}
 
-	line := fmt.Sprintf("[%s] %s %s", checkbox, statusStr, fileItem.Path)
+	line := fmt.Sprintf("%s %s %s", checkbox, statusStr, path)
+	// Submodule bumps are easy to commit by accident, so they say so
+	if fileItem.Submodule != "" {
+		line += fmt.Sprintf(" (submodule %s)", fileItem.Submodule)
+	}
+	// The diff of a type change reads as a delete and an add, so say what it is
+	if fileItem.TypeChange != "" {
+		line += fmt.Sprintf(" (%s)", fileItem.TypeChange)
+	}
 	fmt.Fprint(w, style.Render(line))
 }
 
+// realPath resolves symlinks in path, so paths git reports (which are
+// resolved) compare with the working directory the shell gave us
+func realPath(path string) string {
+	if resolved, err := filepath.EvalSymlinks(path); err == nil {
+		return resolved
+	}
+	return path
+}
+
 // NewModel creates a new model
 func NewModel() Model {
 	// Initialize git client
 	gitClient, err := git.NewClient(".")
 	if err != nil {
 		return Model{
-			err: fmt.Sprintf("Error: %v", err),
+			err:       fmt.Sprintf("Error: %v", err),
+			errSticky: true,
+			errorView: viewport.New(0, 0),
+			keys:      ui.DefaultKeyMap(),
 		}
 	}
 
+	settings := loadSettings()
+
 	// Create list
 	delegate := &FileDelegate{
+		root:        realPath(gitClient.WorkDir()),
+		cwd:         realPath(git.GetCurrentWorkingDir()),
+		cwdRelative: settings.CwdRelativePaths,
+		glyphs:      ui.DefaultGlyphs.With(settings.Glyphs),
 		styles: FileStyles{
-			Normal:    ui.ListItemNormalStyle,
-			Selected:  ui.ListItemSelectedStyle,
-			Staged:    ui.StagedStyle,
-			Unstaged:  ui.UnstagedStyle,
-			Untracked: ui.UntrackedStyle,
+			Normal:     ui.ListItemNormalStyle,
+			Selected:   ui.ListItemSelectedStyle,
+			Staged:     ui.StagedStyle,
+			Unstaged:   ui.UnstagedStyle,
+			Untracked:  ui.UntrackedStyle,
+			Conflicted: ui.ConflictedStyle,
+			Match:      ui.FilterMatchStyle,
 		},
 	}
 
@@ -176,7 +428,6 @@ func NewModel() Model {
 	l.SetShowTitle(true)
 	l.SetShowStatusBar(false)
 	l.SetFilteringEnabled(true)
-	l.SetShowFilter(false)
 	l.SetShowHelp(false)
 	l.Styles.Title = ui.TitleStyle
 
@@ -190,6 +441,7 @@ func NewModel() Model {
 	ta.SetWidth(60)
 	ta.SetHeight(5)
 	ta.ShowLineNumbers = false
+	ta.CharLimit = 0 // Pasted messages with a long body must not be cut short
 
 	// Create commit date input
 	ti := textinput.New()
@@ -197,12 +449,35 @@ func NewModel() Model {
 	ti.CharLimit = 50
 	ti.Width = 50
 
+	// Create patch path input
+	exportInput := textinput.New()
+	exportInput.Placeholder = "path/to/change.patch, or empty to copy"
+	exportInput.Width = 60
+
+	patchInput := textinput.New()
+	patchInput.Placeholder = "path/to/change.patch"
+	patchInput.Width = 60
+
+	// Create redate input; its placeholder changes with the step
+	redateInput := textinput.New()
+	redateInput.Width = 60
+
+	// Create the fullscreen diff's search prompt
+	diffSearch := textinput.New()
+	diffSearch.Prompt = "/"
+
+	// Create restore ref input
+	restoreInput := textinput.New()
+	restoreInput.Placeholder = "commit, branch or tag"
+	restoreInput.Width = 60
+
 	// Create HEAD message textarea for amending
 	headTA := textarea.New()
 	headTA.Placeholder = "Enter new commit message..."
 	headTA.SetWidth(60)
 	headTA.SetHeight(5)
 	headTA.ShowLineNumbers = false
+	headTA.CharLimit = 0
 
 	m := Model{
 		state:               StateFileList,
@@ -210,13 +485,21 @@ func NewModel() Model {
 		list:                l,
 		viewport:            vp,
 		keys:                ui.DefaultKeyMap(),
+		help:                help.New(),
+		progress:            progress.New(progress.WithDefaultGradient()),
 		delegate:            delegate,
+		settings:            settings,
 		selectedFiles:       make(map[int]bool),
 		showPreview:         true,
-		previewFocused:      false,
+		focus:               PaneList,
 		ready:               false,
 		lastFileIndex:       -1,
+		diffAnchor:          -1,
 		diffCache:           make(map[string]string),
+		fileContext:         make(map[string]int),
+		colorProfile:        lipgloss.ColorProfile(),
+		previewLimit:        defaultPreviewLimit,
+		untrackedMode:       git.UntrackedNormal,
 		layout:              ui.NewLayout(80, 24), // Default size, will be updated on first render
 		commitTextarea:      ta,
 		commitInput:         ti,
@@ -224,6 +507,22 @@ func NewModel() Model {
 		headInfo:            nil,
 		headModifyState:     HeadModifyStateMenu,
 		headMessageTextarea: headTA,
+		redateInput:         redateInput,
+		patchInput:          patchInput,
+		exportInput:         exportInput,
+		restoreInput:        restoreInput,
+		reviewView:          viewport.New(0, 0),
+		patchView:           viewport.New(0, 0),
+		commitFileView:      viewport.New(0, 0),
+		diffView:            viewport.New(0, 0),
+		diffSearch:          diffSearch,
+		errorView:           viewport.New(0, 0),
+	}
+	if len(settings.Macro) > 0 {
+		m.keys.Macro.SetHelp(m.keys.Macro.Help().Key, "run macro ("+strings.Join(settings.Macro, ", ")+")")
+	}
+	if settings.EnterCommits {
+		m.keys.Apply.SetHelp(m.keys.Apply.Help().Key, "stage/unstage, or commit if none selected")
 	}
 
 	return m
@@ -231,13 +530,26 @@ func NewModel() Model {
 
 // Init initializes the model
 func (m Model) Init() tea.Cmd {
-	return m.fetchGitStatus()
+	if m.gitClient == nil {
+		return nil // Only the error from NewModel is shown
+	}
+	return tea.Batch(m.fetchGitStatus(), m.scheduleAutoRefresh())
+}
+
+// scheduleAutoRefresh queues the next status poll, if polling is enabled
+func (m Model) scheduleAutoRefresh() tea.Cmd {
+	if m.refreshInterval <= 0 {
+		return nil
+	}
+	return tea.Tick(m.refreshInterval, func(t time.Time) tea.Msg {
+		return autoRefreshMsg{}
+	})
 }
 
 // fetchGitStatus fetches the current git status
 func (m Model) fetchGitStatus() tea.Cmd {
 	return func() tea.Msg {
-		status, err := m.gitClient.Status()
+		status, err := m.gitClient.Status(m.untrackedMode)
 		if err != nil {
 			return errorMsg{err: fmt.Sprintf("Failed to get git status: %v", err)}
 		}
@@ -251,7 +563,9 @@ type gitStatusMsg struct {
 }
 
 type gitHeadInfoMsg struct {
-	info *git.CommitInfo
+	info      *git.CommitInfo
+	reverting bool // A revert stopped on conflicts
+	rebasing  bool // A rebase is stopped part way
 }
 
 type errorMsg struct {
@@ -262,6 +576,24 @@ type statusMsg struct {
 	msg string
 }
 
+// clearStatusMsg clears the status set at setAt, unless it's been replaced since
+type clearStatusMsg struct {
+	setAt time.Time
+}
+
+// clearErrorMsg clears the error set at setAt, unless it's been replaced since
+type clearErrorMsg struct {
+	setAt time.Time
+}
+
+// errorFlashMsg ends the flash started at setAt, unless a newer one took over
+type errorFlashMsg struct {
+	setAt time.Time
+}
+
+// errorFlashDuration is how long a new error's line is shown highlighted
+const errorFlashDuration = 400 * time.Millisecond
+
 // toggleSelection toggles the selection of a file at the given index
 func (m *Model) toggleSelection(index int) {
 	if index < 0 || index >= len(m.files) {
@@ -269,41 +601,134 @@ func (m *Model) toggleSelection(index int) {
 	}
 	m.selectedFiles[index] = !m.selectedFiles[index]
 	m.files[index].Selected = m.selectedFiles[index]
-
-	// Update the list item
-	items := make([]list.Item, len(m.files))
-	for i, f := range m.files {
-		items[i] = f
+	if !m.selectedFiles[index] {
+		delete(m.selectedFiles, index)
 	}
-	m.list.SetItems(items)
+	m.syncListItems()
 }
 
-// selectAll selects all files
-func (m *Model) selectAll() {
-	for i := range m.files {
+// selectAll selects every listed file, or only the ones matching the filter
+// while one is applied. It returns how many files it selected
+func (m *Model) selectAll() int {
+	visible := m.visibleFiles()
+	for _, i := range visible {
 		m.selectedFiles[i] = true
 		m.files[i].Selected = true
 	}
+	m.syncListItems()
+	return len(visible)
+}
 
+// deselectAll deselects every listed file, or only the ones matching the
+// filter while one is applied, leaving hidden selections alone. It returns
+// how many files it deselected
+func (m *Model) deselectAll() int {
+	visible := m.visibleFiles()
+	for _, i := range visible {
+		delete(m.selectedFiles, i)
+		m.files[i].Selected = false
+	}
+	m.syncListItems()
+	return len(visible)
+}
+
+// syncListItems puts m.files back into the list. An applied filter is run
+// again on the spot, so the visible files never lag behind m.files
+func (m *Model) syncListItems() {
 	items := make([]list.Item, len(m.files))
 	for i, f := range m.files {
 		items[i] = f
 	}
-	m.list.SetItems(items)
+	if cmd := m.list.SetItems(items); cmd != nil {
+		m.list, _ = m.list.Update(cmd())
+	}
+}
+
+// visibleFiles returns the indices into m.files of the files the list
+// shows: those matching the filter, or all of them when none is applied
+func (m *Model) visibleFiles() []int {
+	if !m.list.IsFiltered() {
+		indices := make([]int, len(m.files))
+		for i := range m.files {
+			indices[i] = i
+		}
+		return indices
+	}
+	var indices []int
+	for _, item := range m.list.VisibleItems() {
+		if i := m.fileIndex(item.(git.FileItem)); i >= 0 {
+			indices = append(indices, i)
+		}
+	}
+	return indices
+}
+
+// fileIndex returns the index of f in m.files, matching on status and path,
+// or -1 when it's not there
+func (m *Model) fileIndex(f git.FileItem) int {
+	for i, file := range m.files {
+		if file.Status == f.Status && file.Path == f.Path {
+			return i
+		}
+	}
+	return -1
 }
 
-// deselectAll deselects all files
-func (m *Model) deselectAll() {
+// cursorIndex returns the index into m.files of the file under the cursor.
+// The list's own index counts only the visible files, so it differs while a
+// filter is applied
+func (m *Model) cursorIndex() int {
+	f, ok := m.list.SelectedItem().(git.FileItem)
+	if !ok {
+		return -1
+	}
+	return m.fileIndex(f)
+}
+
+// carrySelection marks files in a refreshed list that were selected before,
+// matching on path and status since indices shift between refreshes
+func (m *Model) carrySelection(files []git.FileItem) []git.FileItem {
+	selected := make(map[string]bool)
+	for _, f := range m.getSelectedFiles() {
+		selected[f.Status.String()+"\x00"+f.Path] = true
+	}
+
 	m.selectedFiles = make(map[int]bool)
-	for i := range m.files {
-		m.files[i].Selected = false
+	for i := range files {
+		if selected[files[i].Status.String()+"\x00"+files[i].Path] {
+			m.selectedFiles[i] = true
+			files[i].Selected = true
+		}
 	}
+	return files
+}
 
-	items := make([]list.Item, len(m.files))
-	for i, f := range m.files {
-		items[i] = f
+// followStaged moves the cursor to the staged entry of the file being
+// followed, once the status no longer waits on the stage. The list index
+// otherwise stays put and lands on whatever file took its place
+func (m *Model) followStaged() {
+	if m.followPath == "" || m.processing {
+		return
+	}
+	for i, item := range m.list.VisibleItems() {
+		if f := item.(git.FileItem); f.Path == m.followPath && f.Status == git.StatusStaged {
+			m.list.Select(i)
+			break
+		}
+	}
+	m.followPath = ""
+}
+
+// forgetDiffs drops the cached diffs of paths, whatever their status, after
+// they moved between the index and the working tree
+func (m *Model) forgetDiffs(paths ...string) {
+	for _, path := range paths {
+		for key := range m.diffCache {
+			if strings.HasPrefix(key, path+"\x00") {
+				delete(m.diffCache, key)
+			}
+		}
 	}
-	m.list.SetItems(items)
 }
 
 // getSelectedFiles returns the selected files
@@ -317,18 +742,183 @@ func (m *Model) getSelectedFiles() []git.FileItem {
 	return selected
 }
 
+// operation is a git command run in the background, kept so it can be
+// retried after a transient failure such as a held index.lock
+type operation struct {
+	label string
+	cmd   tea.Cmd
+}
+
+// runOperation starts cmd as the running git operation, remembering it for
+// the retry key
+func (m *Model) runOperation(label string, cmd tea.Cmd) tea.Cmd {
+	m.lastOperation = &operation{label: label, cmd: cmd}
+	m.retryable = false
+	return tea.Batch(m.startProcessing(label), cmd)
+}
+
+// slowOperationDelay is how long an operation runs before the toast appears
+const slowOperationDelay = 3 * time.Second
+
+// startProcessing marks a git operation as running and schedules the toast
+// that tells the user it's still going if it hasn't finished in time
+func (m *Model) startProcessing(label string) tea.Cmd {
+	m.processing = true
+	m.processingID++
+	m.processingLabel = label
+	m.slowOperation = false
+
+	id := m.processingID
+	return tea.Tick(slowOperationDelay, func(t time.Time) tea.Msg {
+		return slowOperationMsg{id: id}
+	})
+}
+
+// sectionStart returns the index of the first item of the status section
+// containing index
+func (m *Model) sectionStart(index int) int {
+	items := m.list.VisibleItems()
+	status := items[index].(git.FileItem).Status
+	for index > 0 && items[index-1].(git.FileItem).Status == status {
+		index--
+	}
+	return index
+}
+
+// nextSection returns the first item of the section after (or before) the
+// cursor's, wrapping around the list, or -1 when there's only one section
+func (m *Model) nextSection(forward bool) int {
+	items := m.list.VisibleItems()
+	cursor := m.list.Index()
+	if cursor < 0 || cursor >= len(items) {
+		return -1
+	}
+
+	if forward {
+		status := items[cursor].(git.FileItem).Status
+		for i := 1; i < len(items); i++ {
+			j := (cursor + i) % len(items)
+			if items[j].(git.FileItem).Status != status {
+				return m.sectionStart(j)
+			}
+		}
+		return -1
+	}
+
+	// Step back over the start of the current section first
+	start := m.sectionStart(cursor)
+	prev := (start - 1 + len(items)) % len(items)
+	if items[prev].(git.FileItem).Status == items[cursor].(git.FileItem).Status {
+		return -1
+	}
+	return m.sectionStart(prev)
+}
+
+// nextWithStatus returns the next item after the cursor with the given
+// status, wrapping around the list, or -1 when there is none
+func (m *Model) nextWithStatus(status git.FileStatus) int {
+	items := m.list.VisibleItems()
+	cursor := max(m.list.Index(), 0)
+	for i := 1; i <= len(items); i++ {
+		j := (cursor + i) % len(items)
+		if items[j].(git.FileItem).Status == status {
+			return j
+		}
+	}
+	return -1
+}
+
+// jumpTo moves the cursor to the index'th visible file and fetches its diff
+func (m *Model) jumpTo(index int) tea.Cmd {
+	m.list.Select(index)
+	current := m.cursorIndex()
+	if !m.showPreview || current == m.lastFileIndex {
+		return nil
+	}
+	m.lastFileIndex = current
+	currentFile := m.getCurrentFile()
+	if currentFile == nil {
+		return nil
+	}
+	m.previewContent = ""
+	return m.fetchDiffCmd(*currentFile)
+}
+
+// askConfirm asks prompt in the footer and runs onConfirm if the user agrees
+func (m *Model) askConfirm(prompt string, onConfirm func(m *Model) tea.Cmd) {
+	m.confirm = &confirmation{prompt: prompt, onConfirm: onConfirm}
+}
+
+// askDestructive is askConfirm for actions that lose work or rewrite
+// history. Their default answer, the one Enter gives, is no unless the
+// settings say otherwise
+func (m *Model) askDestructive(prompt string, onConfirm func(m *Model) tea.Cmd) {
+	m.confirm = &confirmation{prompt: prompt, onConfirm: onConfirm, destructive: true}
+}
+
+// defaultsToYes reports whether Enter accepts the pending confirmation
+func (m *Model) defaultsToYes() bool {
+	return !m.confirm.destructive || m.settings.DestructiveDefaultYes
+}
+
+// holdStatus shows a status until the next one replaces it. Stamping it
+// keeps the clear timer of an earlier status from removing it early
+func (m *Model) holdStatus(status string) {
+	m.status = status
+	m.lastStatusMsg = time.Now()
+}
+
 // clearStatus clears the status message after a delay
 func (m *Model) clearStatus() tea.Cmd {
+	setAt := time.Now()
+	m.lastStatusMsg = setAt
 	return tea.Tick(2*time.Second, func(t time.Time) tea.Msg {
-		return statusMsg{msg: ""}
+		return clearStatusMsg{setAt: setAt}
 	})
 }
 
-// clearError clears the error message after a delay
+// clearError clears the error message after a delay. Multi-line errors,
+// such as hook output or conflict reports, can't be read in that time, so
+// they stay in the error view until dismissed instead
 func (m *Model) clearError() tea.Cmd {
-	return tea.Tick(3*time.Second, func(t time.Time) tea.Msg {
-		return errorMsg{err: ""}
-	})
+	cue := m.errorCue()
+	if strings.Contains(strings.TrimSpace(m.err), "\n") {
+		m.errSticky = true
+		m.errorView.SetContent(m.err)
+		m.errorView.GotoTop()
+		return cue
+	}
+
+	m.errSticky = false
+	setAt := time.Now()
+	m.lastErrorMsg = setAt
+	return tea.Batch(cue, tea.Tick(3*time.Second, func(t time.Time) tea.Msg {
+		return clearErrorMsg{setAt: setAt}
+	}))
+}
+
+// errorCue draws attention to a new error as the settings ask: a brief
+// flash of the error line unless turned off, and a terminal bell if wanted
+func (m *Model) errorCue() tea.Cmd {
+	var cmds []tea.Cmd
+	if m.settings.ErrorBell {
+		cmds = append(cmds, ringBell)
+	}
+	if !m.settings.NoErrorFlash {
+		setAt := time.Now()
+		m.errFlashAt = setAt
+		cmds = append(cmds, tea.Tick(errorFlashDuration, func(time.Time) tea.Msg {
+			return errorFlashMsg{setAt: setAt}
+		}))
+	}
+	return tea.Batch(cmds...)
+}
+
+// ringBell sounds the terminal bell. It's written to stderr, apart from the
+// frames the renderer writes to stdout, so it can't land inside one
+func ringBell() tea.Msg {
+	fmt.Fprint(os.Stderr, "\a")
+	return nil
 }
 
 // applySelection stages or unstages selected files
@@ -340,49 +930,691 @@ func (m *Model) applySelection() tea.Cmd {
 		}
 	}
 
-	m.processing = true
-	cmd := m.toggleSelectionCmd(selected)
+	// Determine if we're staging or unstaging
+	var staged []string
+	var unstaged []string
+	var submodules int // Unstaged submodule pointer changes left out
+	for _, f := range selected {
+		switch {
+		case f.Status == git.StatusStaged:
+			// Unstage both sides of a rename
+			staged = append(staged, f.Paths()...)
+		case f.Submodule != "" && m.settings.SkipSubmodules:
+			submodules++
+		default:
+			unstaged = append(unstaged, f.Path)
+		}
+	}
 
-	// After the git operation, refresh status and clear selection
-	return tea.Batch(
-		cmd,
-		func() tea.Msg {
-			return gitRefreshMsg{}
-		},
-	)
+	// If we have more unstaged than staged, stage them
+	// Otherwise unstage them
+	m.progressTotal = 0
+	if len(unstaged)+submodules > len(staged) {
+		if len(unstaged) == 0 {
+			return func() tea.Msg {
+				return statusMsg{msg: fmt.Sprintf("Left out %d submodule(s); toggle with %s", submodules, m.keys.SkipSubmodules.Help().Key)}
+			}
+		}
+		return m.runOperation("git add", m.stageBatchCmd(true, unstaged, 0, len(unstaged)))
+	}
+	return m.runOperation("git reset", m.stageBatchCmd(false, staged, 0, len(staged)))
 }
 
 // refreshStatus fetches the latest git status
 func (m *Model) refreshStatus() tea.Cmd {
+	m.refreshPending = false
 	return m.refreshStatusCmd()
 }
 
+// enterApplyPatchMode opens the patch file prompt
+func (m *Model) enterApplyPatchMode() {
+	m.state = StateApplyPatch
+	m.patchCheck = ""
+	m.patchChecked = ""
+	m.patchInput.Reset()
+	m.patchInput.Focus()
+}
+
+// enterRestoreRefMode opens the prompt for the ref to restore files from
+func (m *Model) enterRestoreRefMode(paths []string) {
+	m.state = StateRestoreRef
+	m.restorePaths = paths
+	m.restoreInput.Reset()
+	m.restoreInput.Focus()
+}
+
+// cancelRestoreRef closes the restore ref prompt
+func (m *Model) cancelRestoreRef() {
+	m.state = StateFileList
+	m.restorePaths = nil
+	m.restoreInput.Blur()
+}
+
+// reviewStaged loads the staged patch to review, limited to the files being
+// committed when a commit of selected files is under way
+func (m *Model) reviewStaged() tea.Cmd {
+	if m.gitStatus.StagedCount() == 0 {
+		m.status = "Nothing staged to review"
+		return m.clearStatus()
+	}
+	var paths []string
+	if m.state == StateCommitMessage || m.state == StateCommitDate {
+		paths = m.commitPaths
+	}
+	return m.runOperation("git diff --cached", m.stagedDiffCmd(paths))
+}
+
+// closeReview returns to where the review was opened from
+func (m *Model) closeReview() {
+	m.state = m.reviewFrom
+	m.reviewView.SetContent("")
+}
+
+// cancelApplyPatch closes the patch file prompt
+func (m *Model) cancelApplyPatch() {
+	m.state = StateFileList
+	m.patchInput.Blur()
+}
+
+// isTyping reports whether a text input has focus. Refreshes wait until
+// it's done so the list isn't rebuilt underneath the user
+func (m *Model) isTyping() bool {
+	switch m.state {
+	case StateCommitMessage, StateCommitDate, StateApplyPatch, StateExportPatch, StatePalette, StateRestoreRef:
+		return true
+	case StateModifyHead:
+		return m.headModifyState == HeadModifyStateAmend || m.headModifyState == HeadModifyStateRedate
+	case StateFileList:
+		return m.list.SettingFilter()
+	case StateDiff:
+		return m.diffSearch.Focused()
+	default:
+		return false
+	}
+}
+
+// setFileKeysEnabled turns the keys acting on listed files on or off, so an
+// empty list neither offers nor reacts to them
+func (m *Model) setFileKeysEnabled(enabled bool) {
+	for _, b := range []*key.Binding{
+		&m.keys.Select, &m.keys.SelectAll, &m.keys.Deselect,
+		&m.keys.Apply, &m.keys.StageFile, &m.keys.UnstageFile, &m.keys.StageTracked, &m.keys.HideChanges,
+		&m.keys.IntentToAdd, &m.keys.CommitFile, &m.keys.RestoreFromRef, &m.keys.PatchMode, &m.keys.SelectionDiff,
+	} {
+		b.SetEnabled(enabled)
+	}
+}
+
 // getCurrentFile returns the currently selected file
 func (m *Model) getCurrentFile() *git.FileItem {
-	if m.list.Index() < 0 || m.list.Index() >= len(m.files) {
+	index := m.cursorIndex()
+	if index < 0 {
 		return nil
 	}
-	return &m.files[m.list.Index()]
+	return &m.files[index]
 }
 
 // togglePreview toggles the preview pane visibility
 func (m *Model) togglePreview() {
 	m.showPreview = !m.showPreview
+	// A hidden preview can't hold focus
+	if !m.showPreview {
+		m.focus = PaneList
+	}
 	// Recalculate layout if preview toggle changes the effective width
 	if m.showPreview && !m.layout.HasPreviewPane() {
 		// Try to recalculate with current dimensions
 		m.layout = ui.NewLayout(m.width, m.height)
 	}
+	m.updateComponentSizes()
+}
+
+// updateComponentSizes sizes the list and viewport for the current layout
+func (m *Model) updateComponentSizes() {
+	// Calculate shared pane height for split mode
+	paneHeight := m.layout.ListHeight()
+	// Viewport height: paneHeight - border (2) - title line (1)
+	viewportHeight := paneHeight - 3
+	if viewportHeight < 1 {
+		viewportHeight = 1
+	}
+
+	// Adjust list size based on layout
+	// Subtract 4 for border (2) + padding (2)
+	if m.layout.HasPreviewPane() && m.showPreview && !m.previewFullScreen() {
+		m.list.SetWidth(m.layout.ListWidth - 4)
+		m.viewport.Width = m.layout.PreviewWidth - 4
+	} else {
+		m.list.SetWidth(m.width - 4)
+		m.viewport.Width = m.width - 4
+	}
+	m.list.SetHeight(paneHeight)
+	m.viewport.Height = viewportHeight
+	if m.state == StatePalette {
+		m.palette.SetSize(m.width-4, max(m.height-8, 3))
+	}
+	if m.state == StateCommitFiles {
+		// The commit's files take a third of the width, its diffs the rest
+		m.commitFiles.SetSize(max(m.width/3, 20), max(m.height-8, 3))
+	}
+	if m.state == StateFlaggedFiles {
+		m.flagged.SetSize(m.width-4, max(m.height-8, 3))
+	}
+
+	// The error view leaves room for the header, title and footer
+	m.errorView.Width = m.width - 2
+	m.errorView.Height = max(m.height-10, 3)
+	m.reviewView.Width = m.width - 2
+	m.reviewView.Height = max(m.height-10, 3)
+	m.patchView.Width = m.width - 2
+	m.patchView.Height = max(m.height-11, 3) // One more line for the file name
+	m.commitFileView.Width = max(m.width-m.width/3-4, 20)
+	m.commitFileView.Height = max(m.height-8, 3)
+	m.diffView.Width = m.width - 2
+	m.diffView.Height = max(m.height-10, 3)
+	if m.state == StateDiff {
+		m.setDiffViewContent()
+	}
+
+	// Wrapping depends on the viewport width
+	if m.wrapPreview {
+		m.renderPreviewContent()
+	}
+}
+
+// reloadPreview re-fetches the diff for the current file
+func (m *Model) reloadPreview() tea.Cmd {
+	if !m.showPreview {
+		return nil
+	}
+	currentFile := m.getCurrentFile()
+	if currentFile == nil {
+		return nil
+	}
+	m.lastFileIndex = m.cursorIndex()
+	m.previewContent = ""
+	return m.fetchDiffCmd(*currentFile)
+}
+
+// renderPreviewContent puts the preview content into the viewport, wrapping
+// long lines when enabled and adding a cursor/selection gutter while the
+// preview has focus
+func (m *Model) renderPreviewContent() {
+	focused := m.previewHasFocus()
+	width := m.viewport.Width
+	if focused {
+		width -= 2 // Gutter
+	}
+
+	from, to := m.diffSelection()
+	lines := strings.Split(m.previewContent, "\n")
+	m.previewRows = make([]int, len(lines))
+
+	var rows []string
+	for i, line := range lines {
+		m.previewRows[i] = len(rows)
+
+		wrapped := []string{line}
+		if m.wrapPreview {
+			wrapped = ui.WrapANSI(line, width)
+		}
+
+		for j, row := range wrapped {
+			if focused {
+				gutter := "  "
+				switch {
+				case i == m.diffCursor && j == 0:
+					gutter = ui.DiffCursorStyle.Render("▶ ")
+				case m.diffAnchor >= 0 && i >= from && i <= to:
+					gutter = ui.DiffSelectionStyle.Render("┃ ")
+				}
+				row = gutter + row
+			}
+			rows = append(rows, row)
+		}
+	}
+
+	m.viewport.SetContent(strings.Join(rows, "\n"))
+}
+
+// lineAtRow returns the preview line shown at a viewport row
+func (m *Model) lineAtRow(row int) int {
+	line := 0
+	for i, start := range m.previewRows {
+		if start > row {
+			break
+		}
+		line = i
+	}
+	return line
+}
+
+// diffSelection returns the selected preview line range, which is just the
+// cursor line when no selection is active
+func (m *Model) diffSelection() (int, int) {
+	if m.diffAnchor < 0 {
+		return m.diffCursor, m.diffCursor
+	}
+	return min(m.diffAnchor, m.diffCursor), max(m.diffAnchor, m.diffCursor)
+}
+
+// moveDiffCursor moves the preview cursor, scrolling to keep it visible
+func (m *Model) moveDiffCursor(delta int) {
+	lineCount := len(strings.Split(m.previewContent, "\n"))
+	m.diffCursor = max(0, min(m.diffCursor+delta, lineCount-1))
+	m.renderPreviewContent()
+
+	// Scroll by viewport rows, which differ from lines when wrapping
+	row := m.previewRows[m.diffCursor]
+	if row < m.viewport.YOffset {
+		m.viewport.SetYOffset(row)
+	} else if row >= m.viewport.YOffset+m.viewport.Height {
+		m.viewport.SetYOffset(row - m.viewport.Height + 1)
+	}
+}
+
+// setFocus moves keyboard focus to a pane, placing the preview cursor at
+// the top of the visible content
+func (m *Model) setFocus(p Pane) {
+	m.focus = p
+	m.diffCursor = m.lineAtRow(m.viewport.YOffset)
+	m.diffAnchor = -1
+	// Focusing makes the preview full screen, changing its width
+	m.updateComponentSizes()
+	m.renderPreviewContent()
+}
+
+// previewFullScreen reports whether the preview takes the whole width,
+// either focused or maximized
+func (m *Model) previewFullScreen() bool {
+	return (m.previewFocused() || m.maximizePreview) && m.showPreview
+}
+
+// pathForm is a way of writing a file's path when copying it
+type pathForm int
+
+const (
+	pathRelative pathForm = iota // Relative to the repository root
+	pathAbsolute
+	pathGit // Git's ":/" pathspec, which works from any directory in the repository
+	pathFormCount
+)
+
+// format writes path, relative to the repository at root, in the form f
+func (f pathForm) format(root, path string) string {
+	switch f {
+	case pathAbsolute:
+		return filepath.Join(root, path)
+	case pathGit:
+		return ":/" + path
+	default:
+		return path
+	}
+}
+
+// String returns the form's name
+func (f pathForm) String() string {
+	switch f {
+	case pathAbsolute:
+		return "absolute"
+	case pathGit:
+		return "git pathspec"
+	default:
+		return "relative"
+	}
+}
+
+// copyCurrentPath copies the current file's path to the clipboard. Copying
+// the same file again cycles through the path forms
+func (m *Model) copyCurrentPath() tea.Cmd {
+	file := m.getCurrentFile()
+	if file == nil {
+		return nil
+	}
+
+	if file.Path == m.copiedPath {
+		m.copyForm = (m.copyForm + 1) % pathFormCount
+	} else {
+		m.copiedPath = file.Path
+		m.copyForm = pathRelative
+	}
+
+	text := m.copyForm.format(m.gitClient.WorkDir(), file.Path)
+	copied := fmt.Sprintf("Copied %s path: %s", m.copyForm, text)
+	return clipboardCmd(text, func(err error) tea.Msg {
+		if err != nil {
+			return errorMsg{err: fmt.Sprintf("Failed to copy path: %v", err)}
+		}
+		return statusMsg{msg: copied}
+	})
+}
+
+// keyHintsDelay is how long to wait without input after entering a new mode
+// before popping up its keys
+const keyHintsDelay = 1500 * time.Millisecond
+
+// keyHintBindings returns the keys of the current mode for the key hints
+// popup. The file list has the full help screen instead, so it has none
+func (m *Model) keyHintBindings() []key.Binding {
+	keyMap := m.helpKeyMap()
+	if _, ok := keyMap.(ui.KeyMap); ok {
+		return nil
+	}
+	var bindings []key.Binding
+	for _, group := range keyMap.FullHelp() {
+		for _, b := range group {
+			if b.Enabled() {
+				bindings = append(bindings, b)
+			}
+		}
+	}
+	return bindings
+}
+
+// keyHintsSignature identifies the current mode by its keys
+func (m *Model) keyHintsSignature() string {
+	var keys []string
+	for _, b := range m.keyHintBindings() {
+		keys = append(keys, b.Help().Key+" "+b.Help().Desc)
+	}
+	return strings.Join(keys, "\x00")
+}
+
+// scheduleKeyHints pops up the current mode's keys unless another key is
+// pressed within keyHintsDelay
+func (m *Model) scheduleKeyHints() tea.Cmd {
+	id := m.keyHintsID
+	return tea.Tick(keyHintsDelay, func(time.Time) tea.Msg {
+		return keyHintsMsg{id: id}
+	})
+}
+
+// isPartiallyStaged reports whether path has both staged and unstaged changes
+func (m *Model) isPartiallyStaged(path string) bool {
+	staged, unstaged := false, false
+	for _, f := range m.gitStatus.Staged {
+		staged = staged || f == path
+	}
+	for _, f := range m.gitStatus.Unstaged {
+		unstaged = unstaged || f == path
+	}
+	return staged && unstaged
+}
+
+// splitActive reports whether the preview shows the split diffs of the
+// current file
+func (m *Model) splitActive() bool {
+	file := m.getCurrentFile()
+	return m.splitPreview && m.previewTitle == "" && file != nil &&
+		file.Path == m.splitFile && m.isPartiallyStaged(file.Path)
+}
+
+// splitDiffCmd loads the split diffs of the current file when the split
+// preview applies to it
+func (m *Model) splitDiffCmd() tea.Cmd {
+	file := m.getCurrentFile()
+	if !m.splitPreview || file == nil || !m.isPartiallyStaged(file.Path) {
+		return nil
+	}
+	return m.fetchSplitDiffCmd(file.Path)
+}
+
+// scrollSplit scrolls the focused side of the split preview, keeping both
+// sides within their diffs
+func (m *Model) scrollSplit(delta int) {
+	if m.focus == PaneSplitUnstaged {
+		m.splitUnstagedAt += delta
+	} else {
+		m.splitStagedAt += delta
+	}
+	m.splitStagedAt = max(min(m.splitStagedAt, len(m.splitStaged)-1), 0)
+	m.splitUnstagedAt = max(min(m.splitUnstagedAt, len(m.splitUnstaged)-1), 0)
+}
+
+// defaultContext is git's default number of context lines around a change
+const defaultContext = 3
+
+// contextStep is how many context lines each ExpandContext adds
+const contextStep = 5
+
+// diffOptionsFor returns the diff options for a file, including any context
+// expanded for it alone
+func (m *Model) diffOptionsFor(path string) git.DiffOptions {
+	opts := m.diffOptions
+	if extra := m.fileContext[path]; extra > 0 {
+		opts.Context = defaultContext + extra
+	}
+	return opts
+}
+
+// expandContext changes the extra context shown around the current file's
+// hunks by delta lines (resetting it when delta is zero), keeping the line
+// at the cursor, or at the top of the preview, where it is on screen
+func (m *Model) expandContext(delta int) tea.Cmd {
+	file := m.getCurrentFile()
+	if file == nil || m.previewTitle != "" || !m.showPreview {
+		return nil
+	}
+
+	extra := 0
+	if delta != 0 {
+		extra = max(m.fileContext[file.Path]+delta, 0)
+	}
+	if extra == m.fileContext[file.Path] {
+		return nil
+	}
+	m.fileContext[file.Path] = extra
+
+	index := m.lineAtRow(m.viewport.YOffset)
+	if m.previewHasFocus() {
+		index = m.diffCursor
+	}
+	if index < len(m.previewRows) {
+		_, m.keepLine, _ = lineNumbers(m.previewContent, index)
+		m.keepRow = m.previewRows[index] - m.viewport.YOffset
+	}
+
+	m.status = fmt.Sprintf("Context: %d lines", defaultContext+extra)
+	return tea.Batch(m.fetchDiffCmd(*file), m.clearStatus())
+}
+
+// restoreKeptLine moves the cursor and scroll position back onto the line
+// recorded by expandContext after the preview reloads
+func (m *Model) restoreKeptLine() {
+	if m.keepLine == 0 {
+		return
+	}
+	index := previewIndexOfLine(m.previewContent, m.keepLine)
+	m.keepLine = 0
+	if index < 0 {
+		return
+	}
+
+	m.diffCursor = index
+	m.renderPreviewContent()
+	m.viewport.SetYOffset(max(m.previewRows[index]-m.keepRow, 0))
+}
+
+// lineNumbers returns the old and new file line numbers at a preview line,
+// along with the line's diff prefix ('@' for a hunk header). The prefix is
+// zero when the line isn't within a hunk
+func lineNumbers(content string, index int) (oldLine, newLine int, prefix byte) {
+	for _, h := range git.ParseHunks(ui.StripColors(content)) {
+		if !h.Contains(index) {
+			continue
+		}
+		oldLine, newLine, prefix = h.OldStart, h.NewStart, '@'
+		for _, line := range h.Lines[:max(index-h.Offset-1, 0)] {
+			switch line[0] {
+			case ' ':
+				oldLine++
+				newLine++
+			case '-':
+				oldLine++
+			case '+':
+				newLine++
+			}
+		}
+		if index > h.Offset {
+			prefix = h.Lines[index-h.Offset-1][0]
+		}
+		return oldLine, newLine, prefix
+	}
+	return 0, 0, 0
+}
+
+// previewIndexOfLine returns the preview line showing a new-file line
+// number, or -1 when no hunk covers it
+func previewIndexOfLine(content string, lineNo int) int {
+	for _, h := range git.ParseHunks(ui.StripColors(content)) {
+		n := h.NewStart
+		for i, line := range h.Lines {
+			if line[0] != ' ' && line[0] != '+' {
+				continue
+			}
+			if n == lineNo {
+				return h.Offset + 1 + i
+			}
+			n++
+		}
+	}
+	return -1
+}
+
+// blameCursorLine looks up who last changed the context or removed line
+// under the preview cursor, on the old side of the diff
+func (m *Model) blameCursorLine() tea.Cmd {
+	file := m.getCurrentFile()
+	if file == nil || m.previewTitle != "" ||
+		(file.Status != git.StatusUnstaged && file.Status != git.StatusStaged) {
+		m.status = "Blame works on diffs of tracked files"
+		return m.clearStatus()
+	}
+
+	oldLine, _, prefix := lineNumbers(m.previewContent, m.diffCursor)
+	switch prefix {
+	case ' ', '-':
+	case '+':
+		m.status = "Added lines have no history yet"
+		return m.clearStatus()
+	default:
+		m.status = "Move the cursor onto a context or removed line to blame it"
+		return m.clearStatus()
+	}
+
+	// The old side of an unstaged diff is the index, of a staged one HEAD
+	rev := git.RevIndex
+	path := file.Path
+	if file.Status == git.StatusStaged {
+		rev = "HEAD"
+		if file.OrigPath != "" {
+			path = file.OrigPath
+		}
+	}
+	return m.runOperation("git blame", m.blameCmd(path, rev, oldLine))
+}
+
+// stageSelection stages the hunk under the cursor, or the selected lines,
+// of an unstaged diff; of a staged diff it unstages them
+func (m *Model) stageSelection() tea.Cmd {
+	file := m.getCurrentFile()
+	if file == nil || m.previewTitle != "" ||
+		(file.Status != git.StatusUnstaged && file.Status != git.StatusStaged) {
+		m.status = "Hunk staging works on unstaged and staged diffs"
+		return m.clearStatus()
+	}
+	if file.OrigPath != "" {
+		m.status = "Unstage the whole file to undo a rename"
+		return m.clearStatus()
+	}
+	if file.TypeChange != "" {
+		m.status = "A type change can only be staged as a whole file"
+		return m.clearStatus()
+	}
+	if m.previewHidden > 0 {
+		m.status = "Load the full diff before staging from it"
+		return m.clearStatus()
+	}
+	if m.diffOptions.IgnoreWhitespace || m.diffOptions.WordDiff {
+		m.status = "Turn off word diff and ignore whitespace to stage hunks"
+		return m.clearStatus()
+	}
+	if m.diffOptions.HideContext {
+		m.status = "Show context lines again to stage hunks"
+		return m.clearStatus()
+	}
+
+	from, to := m.diffSelection()
+	return m.runOperation(
+		"git apply",
+		m.stageLinesCmd(*file, from, to, m.diffAnchor < 0, len(strings.Split(m.previewContent, "\n"))),
+	)
+}
+
+// discardSelection asks to throw away the selected lines, or the hunk under
+// the cursor, of the current file's unstaged changes
+func (m *Model) discardSelection() tea.Cmd {
+	file := m.getCurrentFile()
+	if file == nil || m.previewTitle != "" || file.Status != git.StatusUnstaged {
+		m.status = "Hunks can only be discarded from unstaged diffs"
+		return m.clearStatus()
+	}
+	if file.TypeChange != "" {
+		m.status = "A type change can only be discarded as a whole file"
+		return m.clearStatus()
+	}
+	if m.previewHidden > 0 {
+		m.status = "Load the full diff before discarding from it"
+		return m.clearStatus()
+	}
+	if m.diffOptions.IgnoreWhitespace || m.diffOptions.WordDiff {
+		m.status = "Turn off word diff and ignore whitespace to discard hunks"
+		return m.clearStatus()
+	}
+	if m.diffOptions.HideContext {
+		m.status = "Show context lines again to discard hunks"
+		return m.clearStatus()
+	}
+
+	from, to := m.diffSelection()
+	wholeHunk := m.diffAnchor < 0
+	previewLines := len(strings.Split(m.previewContent, "\n"))
+	what := "the selected lines"
+	if wholeHunk {
+		what = "this hunk"
+	}
+	target := *file
+	m.askDestructive(fmt.Sprintf("Discard %s of %s? The changes are lost", what, target.Path), func(m *Model) tea.Cmd {
+		return m.runOperation(
+			"git apply --reverse",
+			m.discardLinesCmd(target, from, to, wholeHunk, previewLines),
+		)
+	})
+	return nil
+}
+
+// previewFocused reports whether the preview, or a side of the split
+// preview, has focus
+func (m *Model) previewFocused() bool {
+	return m.focus != PaneList
+}
+
+// previewHasFocus reports whether navigation keys should scroll the preview
+func (m *Model) previewHasFocus() bool {
+	return m.previewFocused() && m.showPreview
 }
 
 // enterCommitMode enters the commit message input state
-func (m *Model) enterCommitMode() {
+func (m *Model) enterCommitMode() tea.Cmd {
 	m.state = StateCommitMessage
 	m.commitState = CommitStateMessage
 	m.commitMessage = ""
 	m.commitDate = ""
+	m.commitPaths = nil
+	m.stagedStats = nil
 	m.commitTextarea.Reset()
 	m.commitTextarea.Focus()
+	return m.fetchDiffStatsCmd()
 }
 
 // getStagedFilesList returns a formatted list of staged files
@@ -392,11 +1624,53 @@ func (m *Model) getStagedFilesList() string {
 	}
 	var result string
 	for _, f := range m.gitStatus.Staged {
-		result += fmt.Sprintf("  + %s\n", f)
+		if !m.isCommitPath(f) {
+			continue
+		}
+		result += fmt.Sprintf("  %s %s%s\n", m.delegate.glyphs.Staged, f, m.renderStagedStat(f))
 	}
 	return result
 }
 
+// isCommitPath reports whether a staged path is part of the pending commit
+func (m *Model) isCommitPath(path string) bool {
+	if len(m.commitPaths) == 0 {
+		return true
+	}
+	for _, p := range m.commitPaths {
+		if p == path {
+			return true
+		}
+	}
+	return false
+}
+
+// commitTotals adds up the line counts of the staged files being committed
+func (m *Model) commitTotals() git.DiffTotals {
+	stats := make(map[string]git.DiffStat)
+	for path, stat := range m.stagedStats {
+		if m.isCommitPath(path) {
+			stats[path] = stat
+		}
+	}
+	return git.SumDiffStats(stats)
+}
+
+// renderStagedStat returns the colored added/deleted counts for a staged file
+func (m *Model) renderStagedStat(path string) string {
+	stat, ok := m.stagedStats[path]
+	if !ok {
+		return ""
+	}
+	if stat.Binary {
+		return "  " + ui.BinaryStatStyle.Render("bin")
+	}
+	return fmt.Sprintf("  %s %s",
+		ui.AdditionsStyle.Render(fmt.Sprintf("+%d", stat.Added)),
+		ui.DeletionsStyle.Render(fmt.Sprintf("-%d", stat.Deleted)),
+	)
+}
+
 // proceedToDateInput moves to the date input state
 func (m *Model) proceedToDateInput() {
 	m.commitState = CommitStateDate
@@ -407,8 +1681,10 @@ func (m *Model) proceedToDateInput() {
 // cancelCommit cancels the commit and returns to file list
 func (m *Model) cancelCommit() {
 	m.state = StateFileList
+	m.macro = nil
 	m.commitMessage = ""
 	m.commitDate = ""
+	m.commitPaths = nil
 	m.commitTextarea.Blur()
 	m.commitInput.Blur()
 }
@@ -420,7 +1696,10 @@ func (m *Model) fetchHeadInfo() tea.Cmd {
 		if err != nil {
 			return errorMsg{err: fmt.Sprintf("Failed to get HEAD info: %v", err)}
 		}
-		return gitHeadInfoMsg{info: info}
+		// Only decide which revert and redate options are offered
+		reverting, _ := m.gitClient.RevertInProgress()
+		rebasing, _ := m.gitClient.RebaseInProgress()
+		return gitHeadInfoMsg{info: info, reverting: reverting, rebasing: rebasing}
 	}
 }
 
@@ -430,19 +1709,29 @@ func (m *Model) enterModifyHeadMode() {
 	m.headModifyState = HeadModifyStateMenu
 }
 
-// enterAmendMessageMode enters the amend message input state
-func (m *Model) enterAmendMessageMode() {
-	m.headModifyState = HeadModifyStateAmendMessage
+// enterAmendMode enters the combined amend screen
+func (m *Model) enterAmendMode() {
+	m.headModifyState = HeadModifyStateAmend
 	if m.headInfo != nil {
 		m.headMessageTextarea.SetValue(m.headInfo.Message)
 	}
 	m.headMessageTextarea.Focus()
 }
 
-// enterAmendFilesMode enters the amend files (soft reset) mode
-func (m *Model) enterAmendFilesMode() {
-	m.headModifyState = HeadModifyStateAmendFiles
-	m.status = "Performing soft reset..."
+// enterRedateMode asks for the earlier commit to redate, then its date
+func (m *Model) enterRedateMode() {
+	m.headModifyState = HeadModifyStateRedate
+	m.redateRef = ""
+	m.redateInput.Placeholder = "commit, e.g. HEAD~2"
+	m.redateInput.Reset()
+	m.redateInput.Focus()
+}
+
+// cancelRedate returns from the redate prompt to the menu
+func (m *Model) cancelRedate() {
+	m.headModifyState = HeadModifyStateMenu
+	m.redateRef = ""
+	m.redateInput.Blur()
 }
 
 // cancelModifyHead cancels HEAD modification and returns to file list
@@ -450,5 +1739,6 @@ func (m *Model) cancelModifyHead() {
 	m.state = StateFileList
 	m.headModifyState = HeadModifyStateMenu
 	m.headMessageTextarea.Blur()
+	m.redateInput.Blur()
 	m.headInfo = nil
 }
@@ -0,0 +1,239 @@
+package main
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+	"time"
+
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// testRepo is a throwaway repository in its own t.TempDir(), made the
+// working directory so NewModel opens it
+type testRepo struct {
+	t   *testing.T
+	dir string
+}
+
+// newTestRepo initializes an empty repository with an identity to commit
+// as. The user's git config and igit settings are kept out of it
+func newTestRepo(t *testing.T) *testRepo {
+	t.Helper()
+	t.Setenv("GIT_CONFIG_GLOBAL", os.DevNull)
+	t.Setenv("GIT_CONFIG_NOSYSTEM", "1")
+	t.Setenv("HOME", t.TempDir())
+	t.Setenv("XDG_CONFIG_HOME", t.TempDir())
+
+	r := &testRepo{t: t, dir: t.TempDir()}
+	r.git("init", "-q", "-b", "main")
+	r.git("config", "user.name", "Test User")
+	r.git("config", "user.email", "test@example.com")
+	r.git("config", "commit.gpgsign", "false")
+	t.Chdir(r.dir)
+	return r
+}
+
+// git runs a git command in the repository, failing the test if it fails,
+// and returns its output
+func (r *testRepo) git(args ...string) string {
+	r.t.Helper()
+	cmd := exec.Command("git", args...)
+	cmd.Dir = r.dir
+	output, err := cmd.CombinedOutput()
+	if err != nil {
+		r.t.Fatalf("git %s: %v\n%s", strings.Join(args, " "), err, output)
+	}
+	return string(output)
+}
+
+// write creates or replaces a file in the working tree
+func (r *testRepo) write(path, content string) {
+	r.t.Helper()
+	full := filepath.Join(r.dir, path)
+	if err := os.MkdirAll(filepath.Dir(full), 0o755); err != nil {
+		r.t.Fatal(err)
+	}
+	if err := os.WriteFile(full, []byte(content), 0o644); err != nil {
+		r.t.Fatal(err)
+	}
+}
+
+// commit stages everything in the working tree and commits it
+func (r *testRepo) commit(message string) {
+	r.t.Helper()
+	r.git("add", "-A")
+	r.git("commit", "-q", "-m", message)
+}
+
+// openModel creates the model on the working directory's repository and
+// brings it to where the program is after starting: sized, with the status
+// loaded
+func openModel(t *testing.T) Model {
+	t.Helper()
+	m := NewModel()
+	if m.gitClient == nil {
+		t.Fatalf("NewModel: %s", m.err)
+	}
+	m = update(t, m, tea.WindowSizeMsg{Width: 100, Height: 30})
+	return run(t, m, m.Init())
+}
+
+// cmdTimeout is how long run waits for a command. Git commands finish well
+// within it; timers, like the one clearing the status line, don't and are
+// dropped
+const cmdTimeout = 250 * time.Millisecond
+
+// update hands msg to the model and runs the commands it returns
+func update(t *testing.T, m Model, msg tea.Msg) Model {
+	t.Helper()
+	next, cmd := m.Update(msg)
+	return run(t, next.(Model), cmd)
+}
+
+// run runs cmd and feeds the messages it produces back into the model, the
+// way the program would, until no commands are left
+func run(t *testing.T, m Model, cmd tea.Cmd) Model {
+	t.Helper()
+	for _, msg := range collect(cmd) {
+		m = update(t, m, msg)
+	}
+	return m
+}
+
+// collect runs cmd, and the commands of any batch it returns concurrently,
+// gathering the messages that arrive within cmdTimeout
+func collect(cmd tea.Cmd) []tea.Msg {
+	if cmd == nil {
+		return nil
+	}
+	done := make(chan tea.Msg, 1)
+	go func() { done <- cmd() }()
+
+	var msg tea.Msg
+	select {
+	case msg = <-done:
+	case <-time.After(cmdTimeout):
+		return nil
+	}
+
+	switch msg := msg.(type) {
+	case nil:
+		return nil
+	case tea.BatchMsg:
+		results := make([][]tea.Msg, len(msg))
+		finished := make(chan struct{})
+		for i, cmd := range msg {
+			go func() {
+				results[i] = collect(cmd)
+				finished <- struct{}{}
+			}()
+		}
+		for range msg {
+			<-finished
+		}
+		return slices.Concat(results...)
+	default:
+		return []tea.Msg{msg}
+	}
+}
+
+// press sends each key in turn. Keys are named as bubbletea names them,
+// e.g. "enter" or "ctrl+d"; anything else is typed as runes
+func press(t *testing.T, m Model, keys ...string) Model {
+	t.Helper()
+	for _, k := range keys {
+		m = update(t, m, keyMsg(k))
+	}
+	return m
+}
+
+// typeText enters text in one message, as a paste arrives. Typed a rune at
+// a time, each key would leave a cursor blink for run to wait out
+func typeText(t *testing.T, m Model, text string) Model {
+	t.Helper()
+	return update(t, m, tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(text)})
+}
+
+// namedKeys are the keys press understands by name
+var namedKeys = map[string]tea.KeyType{
+	"enter":  tea.KeyEnter,
+	"esc":    tea.KeyEsc,
+	"tab":    tea.KeyTab,
+	"up":     tea.KeyUp,
+	"down":   tea.KeyDown,
+	"ctrl+d": tea.KeyCtrlD,
+}
+
+// keyMsg returns the message for the key named k
+func keyMsg(k string) tea.KeyMsg {
+	if typ, ok := namedKeys[k]; ok {
+		return tea.KeyMsg{Type: typ}
+	}
+	return tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune(k)}
+}
+
+// TestRefreshWhileTypingCommitMessage checks a refresh arriving mid-commit
+// is held back, leaving the message alone, and applied once the commit is
+// cancelled
+func TestRefreshWhileTypingCommitMessage(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.commit("initial")
+	r.write("a.txt", "two\n")
+	r.git("add", "a.txt")
+
+	m := openModel(t)
+	m = press(t, m, "c")
+	if m.state != StateCommitMessage {
+		t.Fatalf("state = %v after c, want the commit message", m.state)
+	}
+	m = typeText(t, m, "half a message")
+
+	// Something changes the repository while the message is typed
+	r.write("new.txt", "new\n")
+	m = update(t, m, gitRefreshMsg{})
+	m = update(t, m, autoRefreshMsg{})
+
+	if m.state != StateCommitMessage {
+		t.Errorf("state = %v after the refresh, want the commit message", m.state)
+	}
+	if got := m.commitTextarea.Value(); got != "half a message" {
+		t.Errorf("message = %q after the refresh, want it kept", got)
+	}
+	if !m.refreshPending {
+		t.Error("refresh wasn't held back")
+	}
+	if len(m.gitStatus.Untracked) != 0 {
+		t.Errorf("status refreshed while typing: untracked %q", m.gitStatus.Untracked)
+	}
+
+	// Still held on the date step, and going back to the message
+	m = press(t, m, "ctrl+d")
+	if m.state != StateCommitMessage || m.commitState != CommitStateDate {
+		t.Fatalf("state = %v/%v after continuing, want the commit date", m.state, m.commitState)
+	}
+	m = update(t, m, gitRefreshMsg{})
+	m = press(t, m, "esc")
+	if m.commitState != CommitStateMessage || m.commitTextarea.Value() != "half a message" {
+		t.Fatalf("back on the message: step %v, message %q", m.commitState, m.commitTextarea.Value())
+	}
+	if len(m.gitStatus.Untracked) != 0 {
+		t.Errorf("status refreshed while typing: untracked %q", m.gitStatus.Untracked)
+	}
+
+	// Cancelling catches up
+	m = press(t, m, "esc")
+	if m.state != StateFileList {
+		t.Fatalf("state = %v after cancelling, want the file list", m.state)
+	}
+	if m.refreshPending {
+		t.Error("held refresh still pending after cancelling")
+	}
+	if !slices.Equal(m.gitStatus.Untracked, []string{"new.txt"}) {
+		t.Errorf("untracked = %q after cancelling, want [new.txt]", m.gitStatus.Untracked)
+	}
+}
@@ -0,0 +1,142 @@
+package main
+
+import (
+	"slices"
+
+	"github.com/charmbracelet/bubbles/key"
+	"github.com/charmbracelet/bubbles/list"
+	tea "github.com/charmbracelet/bubbletea"
+)
+
+// paletteAction is a command palette entry. Actions are the file list's key
+// bindings, so every binding added to the keymap shows up in the palette
+// without being registered twice. Choosing one presses its key, so only the
+// bindings of the focused pane are listed
+type paletteAction struct {
+	binding key.Binding
+}
+
+// FilterValue implements list.Item, matching on the action's name
+func (a paletteAction) FilterValue() string {
+	return a.binding.Help().Desc
+}
+
+// Title implements list.DefaultItem
+func (a paletteAction) Title() string {
+	return a.binding.Help().Desc
+}
+
+// Description implements list.DefaultItem, showing the action's key
+func (a paletteAction) Description() string {
+	return a.binding.Help().Key
+}
+
+// keyMsg returns the key press that triggers the action
+func (a paletteAction) keyMsg() (tea.KeyMsg, bool) {
+	for _, k := range a.binding.Keys() {
+		if msg, ok := keyMsgFor(k); ok {
+			return msg, true
+		}
+	}
+	return tea.KeyMsg{}, false
+}
+
+// keyMsgFor builds the key press whose name is k, such as "s" or "enter"
+func keyMsgFor(k string) (tea.KeyMsg, bool) {
+	if runes := []rune(k); len(runes) == 1 {
+		return tea.KeyMsg{Type: tea.KeyRunes, Runes: runes}, true
+	}
+	// Named keys (up, enter, ctrl+c...) are the KeyTypes that aren't runes
+	for t := tea.KeyType(-100); t <= 127; t++ {
+		if t != tea.KeyRunes && t.String() == k {
+			return tea.KeyMsg{Type: t}, true
+		}
+	}
+	return tea.KeyMsg{}, false
+}
+
+// paletteBindings returns the bindings that act in the focused pane. The
+// preview's own actions reuse the list's keys (s stages a hunk there but a
+// file in the list), so the list doesn't offer them, and the preview only
+// offers list actions whose keys it passes on
+func (m *Model) paletteBindings() []key.Binding {
+	preview := []key.Binding{m.keys.SelectLines, m.keys.StageHunk, m.keys.DiscardHunk, m.keys.Blame}
+	isPreviewAction := func(b key.Binding) bool {
+		return slices.ContainsFunc(preview, func(p key.Binding) bool { return p.Help() == b.Help() })
+	}
+	takenByPreview := func(b key.Binding) bool {
+		return slices.ContainsFunc(b.Keys(), func(k string) bool {
+			return slices.ContainsFunc(preview, func(p key.Binding) bool { return slices.Contains(p.Keys(), k) })
+		})
+	}
+
+	var bindings []key.Binding
+	for _, group := range m.keys.FullHelp() {
+		for _, b := range group {
+			if !b.Enabled() {
+				continue
+			}
+			if m.previewHasFocus() {
+				if !isPreviewAction(b) && takenByPreview(b) {
+					continue
+				}
+			} else if isPreviewAction(b) {
+				continue
+			}
+			bindings = append(bindings, b)
+		}
+	}
+	return bindings
+}
+
+// openPalette shows the command palette, already filtering
+func (m *Model) openPalette() tea.Cmd {
+	var items []list.Item
+	for _, b := range m.paletteBindings() {
+		items = append(items, paletteAction{binding: b})
+	}
+
+	m.palette = list.New(items, list.NewDefaultDelegate(), 0, 0)
+	m.palette.Title = "Commands"
+	m.palette.SetShowHelp(false)
+	m.palette.DisableQuitKeybindings()
+	m.state = StatePalette
+	m.updateComponentSizes()
+
+	// Start with the filter prompt open, so typing searches right away
+	var cmd tea.Cmd
+	m.palette, cmd = m.palette.Update(tea.KeyMsg{Type: tea.KeyRunes, Runes: []rune{'/'}})
+	return cmd
+}
+
+// closePalette returns to the file list
+func (m *Model) closePalette() {
+	m.state = StateFileList
+	m.palette = list.Model{}
+}
+
+// handlePaletteKeys handles keys in the command palette. Choosing an action
+// presses its key in the file list
+func (m Model) handlePaletteKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Confirm):
+		action, ok := m.palette.SelectedItem().(paletteAction)
+		m.closePalette()
+		if !ok {
+			return m, nil
+		}
+		if press, ok := action.keyMsg(); ok {
+			return m.handleFileListKeys(press)
+		}
+		return m, nil
+
+	case key.Matches(msg, m.keys.Cancel):
+		m.closePalette()
+		return m, nil
+
+	default:
+		var cmd tea.Cmd
+		m.palette, cmd = m.palette.Update(msg)
+		return m, cmd
+	}
+}
@@ -0,0 +1,74 @@
+package main
+
+import (
+	"slices"
+	"testing"
+)
+
+// paletteTitles returns the names of the actions the open palette lists
+func paletteTitles(m Model) []string {
+	var titles []string
+	for _, item := range m.palette.Items() {
+		titles = append(titles, item.(paletteAction).Title())
+	}
+	return titles
+}
+
+func TestPaletteListsFocusedPaneActions(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.commit("initial")
+	r.write("a.txt", "two\n")
+
+	m := openModel(t)
+	m = press(t, m, ":")
+	if m.state != StatePalette {
+		t.Fatalf("state = %v after :, want the palette", m.state)
+	}
+	titles := paletteTitles(m)
+	if !slices.Contains(titles, m.keys.StageFile.Help().Desc) {
+		t.Errorf("list palette is missing %q: %q", m.keys.StageFile.Help().Desc, titles)
+	}
+	for _, b := range []string{m.keys.StageHunk.Help().Desc, m.keys.DiscardHunk.Help().Desc, m.keys.Blame.Help().Desc} {
+		if slices.Contains(titles, b) {
+			t.Errorf("list palette offers the preview's %q", b)
+		}
+	}
+	m = press(t, m, "esc")
+
+	m = press(t, m, "p")
+	if !m.previewHasFocus() {
+		t.Fatal("preview doesn't have focus after p")
+	}
+	m = press(t, m, ":")
+	titles = paletteTitles(m)
+	if !slices.Contains(titles, m.keys.StageHunk.Help().Desc) {
+		t.Errorf("preview palette is missing %q: %q", m.keys.StageHunk.Help().Desc, titles)
+	}
+	// s stages a hunk in the preview, so staging the file isn't offered
+	if slices.Contains(titles, m.keys.StageFile.Help().Desc) {
+		t.Errorf("preview palette offers %q, whose key stages a hunk there", m.keys.StageFile.Help().Desc)
+	}
+	if !slices.Contains(titles, m.keys.Commit.Help().Desc) {
+		t.Errorf("preview palette is missing %q: %q", m.keys.Commit.Help().Desc, titles)
+	}
+}
+
+func TestPaletteRunsAction(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.commit("initial")
+	r.write("a.txt", "two\n")
+
+	m := openModel(t)
+	m = press(t, m, ":")
+	m = typeText(t, m, "stage file")
+	m = press(t, m, "enter")
+
+	if m.state != StateFileList {
+		t.Errorf("state = %v after choosing an action, want the file list", m.state)
+	}
+	if !slices.Equal(m.gitStatus.Staged, []string{"a.txt"}) {
+		t.Errorf("staged = %q after choosing stage file, want [a.txt]", m.gitStatus.Staged)
+	}
+}
@@ -0,0 +1,220 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/charmbracelet/bubbles/key"
+	tea "github.com/charmbracelet/bubbletea"
+
+	"github.com/rai/interactive-git/git"
+	"github.com/rai/interactive-git/ui"
+)
+
+// patchFile is an unstaged file offered in patch mode, with its hunks as
+// they were when patch mode started
+type patchFile struct {
+	path  string
+	hunks []git.Hunk
+}
+
+// patchPiece is a part of a hunk offered in patch mode: the whole hunk, or
+// one run of changes once the hunk is split
+type patchPiece struct {
+	file    int    // Index into patchSession.files
+	hunk    int    // Index into the file's hunks
+	lines   [2]int // [from, to] range of the hunk's Lines holding the changes
+	include bool
+}
+
+// patchSession steps through hunks like `git add -p`, staging the chosen
+// ones and committing them at the end
+type patchSession struct {
+	files   []patchFile
+	pieces  []patchPiece
+	current int
+}
+
+// newPatchSession offers every hunk of files whole, in order
+func newPatchSession(files []patchFile) *patchSession {
+	s := &patchSession{files: files}
+	for fi, f := range files {
+		for hi, h := range f.hunks {
+			s.pieces = append(s.pieces, patchPiece{file: fi, hunk: hi, lines: [2]int{0, len(h.Lines) - 1}})
+		}
+	}
+	return s
+}
+
+// piece returns the piece being decided on
+func (s *patchSession) piece() patchPiece {
+	return s.pieces[s.current]
+}
+
+// hunk returns the hunk the current piece belongs to
+func (s *patchSession) hunk() git.Hunk {
+	p := s.piece()
+	return s.files[p.file].hunks[p.hunk]
+}
+
+// chosen returns how many pieces are included so far
+func (s *patchSession) chosen() int {
+	n := 0
+	for _, p := range s.pieces {
+		if p.include {
+			n++
+		}
+	}
+	return n
+}
+
+// split replaces the current piece with one piece per run of changes in it.
+// It reports false when there is nothing to split
+func (s *patchSession) split() bool {
+	p := s.piece()
+	var groups []patchPiece
+	for _, g := range s.hunk().ChangeGroups() {
+		if g[0] >= p.lines[0] && g[1] <= p.lines[1] {
+			groups = append(groups, patchPiece{file: p.file, hunk: p.hunk, lines: g})
+		}
+	}
+	if len(groups) < 2 {
+		return false
+	}
+
+	pieces := append([]patchPiece{}, s.pieces[:s.current]...)
+	pieces = append(pieces, groups...)
+	s.pieces = append(pieces, s.pieces[s.current+1:]...)
+	return true
+}
+
+// selectedHunks returns the hunks to stage for each file, built from the
+// included pieces. Hunks come last to first, so staging one never moves the
+// lines the next one applies to
+func (s *patchSession) selectedHunks() (map[string][]git.Hunk, error) {
+	ranges := make(map[[2]int][][2]int) // Ranges included per file and hunk
+	for _, p := range s.pieces {
+		if p.include {
+			id := [2]int{p.file, p.hunk}
+			ranges[id] = append(ranges[id], p.lines)
+		}
+	}
+
+	ids := make([][2]int, 0, len(ranges))
+	for id := range ranges {
+		ids = append(ids, id)
+	}
+	sort.Slice(ids, func(i, j int) bool {
+		if ids[i][0] != ids[j][0] {
+			return ids[i][0] < ids[j][0]
+		}
+		return ids[i][1] > ids[j][1]
+	})
+
+	hunks := make(map[string][]git.Hunk)
+	for _, id := range ids {
+		f := s.files[id[0]]
+		h, err := f.hunks[id[1]].SelectRanges(ranges[id], false)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", f.path, err)
+		}
+		hunks[f.path] = append(hunks[f.path], h)
+	}
+	return hunks, nil
+}
+
+// enterPatchMode starts stepping through the loaded hunks
+func (m *Model) enterPatchMode(files []patchFile) {
+	m.patch = newPatchSession(files)
+	m.state = StatePatchMode
+	m.showPatchPiece()
+}
+
+// cancelPatchMode leaves patch mode without staging anything
+func (m *Model) cancelPatchMode() {
+	m.state = StateFileList
+	m.patch = nil
+}
+
+// showPatchPiece renders the current piece into the patch view. Changes of
+// the hunk outside the piece are dimmed
+func (m *Model) showPatchPiece() {
+	p := m.patch.piece()
+	h := m.patch.hunk()
+
+	lines := []string{ui.InfoStyle.Render(h.Header())}
+	for i, line := range h.Lines {
+		switch {
+		case line[0] == ' ':
+			lines = append(lines, line)
+		case i < p.lines[0] || i > p.lines[1]:
+			lines = append(lines, ui.HelpStyle.Render(line))
+		case line[0] == '+':
+			lines = append(lines, ui.SuccessStyle.Render(line))
+		case line[0] == '-':
+			lines = append(lines, ui.ErrorStyle.Render(line))
+		default:
+			lines = append(lines, line)
+		}
+	}
+	m.patchView.SetContent(strings.Join(lines, "\n"))
+	m.patchView.GotoTop()
+}
+
+// handlePatchModeKeys includes, skips or splits the current piece, staging
+// the chosen pieces once every piece is decided
+func (m Model) handlePatchModeKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.IncludeHunk), key.Matches(msg, m.keys.SkipHunk):
+		m.patch.pieces[m.patch.current].include = key.Matches(msg, m.keys.IncludeHunk)
+		if m.patch.current < len(m.patch.pieces)-1 {
+			m.patch.current++
+			m.showPatchPiece()
+			return m, nil
+		}
+		return m, m.finishPatchMode()
+
+	case key.Matches(msg, m.keys.SplitHunk):
+		if !m.patch.split() {
+			m.status = "This hunk can't be split further"
+			return m, m.clearStatus()
+		}
+		m.showPatchPiece()
+		return m, nil
+
+	case key.Matches(msg, m.keys.PreviousHunk):
+		if m.patch.current > 0 {
+			m.patch.current--
+			m.showPatchPiece()
+		}
+		return m, nil
+
+	case key.Matches(msg, m.keys.Cancel):
+		m.cancelPatchMode()
+		m.status = "Patch mode cancelled; nothing was staged"
+		return m, m.clearStatus()
+
+	default:
+		var cmd tea.Cmd
+		m.patchView, cmd = m.patchView.Update(msg)
+		return m, cmd
+	}
+}
+
+// finishPatchMode stages the chosen pieces, after which the commit message
+// is asked for
+func (m *Model) finishPatchMode() tea.Cmd {
+	if m.patch.chosen() == 0 {
+		m.cancelPatchMode()
+		m.status = "No hunks chosen; nothing was staged"
+		return m.clearStatus()
+	}
+	hunks, err := m.patch.selectedHunks()
+	m.cancelPatchMode()
+	if err != nil {
+		m.err = err.Error()
+		return m.clearError()
+	}
+	return m.runOperation("git apply --cached", m.stagePatchHunksCmd(hunks))
+}
@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"github.com/rai/interactive-git/git"
+)
+
+// maxFoundRepos caps how many repositories findRepos offers
+const maxFoundRepos = 20
+
+// findRepos returns the git repositories below dir, up to maxDepth levels
+// deep. Hidden directories and the insides of repositories are skipped
+func findRepos(dir string, maxDepth int) []string {
+	var repos []string
+	root := filepath.Clean(dir)
+	filepath.WalkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if len(repos) >= maxFoundRepos {
+			return fs.SkipAll
+		}
+		if err != nil || !d.IsDir() {
+			return nil
+		}
+		if path != root && strings.HasPrefix(d.Name(), ".") {
+			return fs.SkipDir
+		}
+		if _, err := os.Stat(filepath.Join(path, ".git")); err == nil {
+			repos = append(repos, path)
+			return fs.SkipDir
+		}
+		if strings.Count(path[len(root):], string(filepath.Separator)) >= maxDepth {
+			return fs.SkipDir
+		}
+		return nil
+	})
+	return repos
+}
+
+// pickRepo asks on the terminal which repository to open, offering those
+// found under dir or any path typed in. It returns "" if the user gives up
+func pickRepo(in io.Reader, out io.Writer, dir string) string {
+	repos := findRepos(dir, 3)
+	if len(repos) > 0 {
+		fmt.Fprintln(out, "Repositories found here:")
+		for i, repo := range repos {
+			fmt.Fprintf(out, "  %d) %s\n", i+1, repo)
+		}
+	}
+
+	scanner := bufio.NewScanner(in)
+	for {
+		if len(repos) > 0 {
+			fmt.Fprint(out, "Open a number or a path (empty to quit): ")
+		} else {
+			fmt.Fprint(out, "Open a repository path (empty to quit): ")
+		}
+		if !scanner.Scan() {
+			return ""
+		}
+
+		answer := strings.TrimSpace(scanner.Text())
+		if answer == "" {
+			return ""
+		}
+		if n, err := strconv.Atoi(answer); err == nil && n >= 1 && n <= len(repos) {
+			return repos[n-1]
+		}
+
+		path := expandHome(answer)
+		err := git.CheckRepo(path)
+		if err == nil {
+			return path
+		}
+		fmt.Fprintf(out, "Can't open %s: %v\n", path, err)
+	}
+}
+
+// isTerminal reports whether f is connected to a terminal rather than a
+// pipe or file
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	return err == nil && info.Mode()&os.ModeCharDevice != 0
+}
@@ -0,0 +1,79 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+
+	"github.com/rai/interactive-git/ui"
+)
+
+// settings are preferences kept between sessions in settings.json under the
+// user config directory. Some are changed from within the app, the rest by
+// editing the file
+type settings struct {
+	CwdRelativePaths      bool      `json:"cwdRelativePaths,omitempty"`      // List paths relative to the working directory instead of the repository root
+	Glyphs                ui.Glyphs `json:"glyphs,omitzero"`                 // Replacements for the file list's checkboxes and status symbols
+	ConfirmQuit           bool      `json:"confirmQuit,omitempty"`           // Ask before quitting with staged changes not yet committed
+	ConfirmAmend          bool      `json:"confirmAmend,omitempty"`          // Ask before every amend; pushed commits always ask
+	SkipSubmodules        bool      `json:"skipSubmodules,omitempty"`        // Leave submodule pointer changes out when staging several files at once
+	ConfirmEscCancels     bool      `json:"confirmEscCancels,omitempty"`     // Let Esc answer no to confirmations, which otherwise take y or n
+	DestructiveDefaultYes bool      `json:"destructiveDefaultYes,omitempty"` // Let Enter accept confirmations that lose work, which it otherwise declines
+	Macro                 []string  `json:"macro,omitempty"`                 // Steps the macro key runs in order: stage-tracked, commit, push
+	ErrorBell             bool      `json:"errorBell,omitempty"`             // Ring the terminal bell when an error appears
+	NoErrorFlash          bool      `json:"noErrorFlash,omitempty"`          // Don't briefly highlight the error line when an error appears
+	EnterCommits          bool      `json:"enterCommits,omitempty"`          // Let Enter start a commit when no files are selected and some are staged
+}
+
+// settingsPath returns where settings are stored
+func settingsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", fmt.Errorf("failed to locate config directory: %w", err)
+	}
+	return filepath.Join(dir, "igit", "settings.json"), nil
+}
+
+// loadSettings reads the saved settings. Missing or unreadable settings give
+// the defaults, since none of them is worth refusing to start over
+func loadSettings() settings {
+	var s settings
+	path, err := settingsPath()
+	if err != nil {
+		return s
+	}
+	content, err := os.ReadFile(path)
+	if err != nil {
+		if !errors.Is(err, os.ErrNotExist) {
+			slog.Debug("failed to read settings", "err", err)
+		}
+		return s
+	}
+	if err := json.Unmarshal(content, &s); err != nil {
+		slog.Debug("failed to parse settings", "path", path, "err", err)
+		return settings{}
+	}
+	return s
+}
+
+// save writes the settings, replacing the saved ones
+func (s settings) save() error {
+	path, err := settingsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return fmt.Errorf("failed to create config directory: %w", err)
+	}
+	content, err := json.MarshalIndent(s, "", "  ")
+	if err != nil {
+		return fmt.Errorf("failed to encode settings: %w", err)
+	}
+	if err := os.WriteFile(path, append(content, '\n'), 0o644); err != nil {
+		return fmt.Errorf("failed to save settings: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,78 @@
+package ui
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/muesli/termenv"
+)
+
+// sgrPattern matches SGR (color/attribute) escape sequences
+var sgrPattern = regexp.MustCompile(`\x1b\[([0-9;]*)m`)
+
+// StripColors removes the SGR sequences from s
+func StripColors(s string) string {
+	return sgrPattern.ReplaceAllString(s, "")
+}
+
+// DegradeColors rewrites the SGR sequences in s, as produced by
+// `git --color=always`, for a terminal color profile. 256-color and truecolor
+// codes are mapped to the nearest color the profile supports, and every
+// sequence is dropped when the terminal has no color support at all
+func DegradeColors(s string, profile termenv.Profile) string {
+	switch profile {
+	case termenv.TrueColor:
+		return s
+	case termenv.Ascii:
+		return sgrPattern.ReplaceAllString(s, "")
+	}
+
+	return sgrPattern.ReplaceAllStringFunc(s, func(seq string) string {
+		params := strings.Split(sgrPattern.FindStringSubmatch(seq)[1], ";")
+
+		var out []string
+		for i := 0; i < len(params); i++ {
+			p := params[i]
+			if (p != "38" && p != "48") || i+1 >= len(params) {
+				out = append(out, p)
+				continue
+			}
+
+			// Extended color: 38/48;5;N or 38/48;2;R;G;B
+			var c termenv.Color
+			switch {
+			case params[i+1] == "5" && i+2 < len(params):
+				n, _ := strconv.Atoi(params[i+2])
+				if n < 16 {
+					c = termenv.ANSIColor(n)
+				} else {
+					c = termenv.ANSI256Color(n)
+				}
+				i += 2
+			case params[i+1] == "2" && i+4 < len(params):
+				r, _ := strconv.Atoi(params[i+2])
+				g, _ := strconv.Atoi(params[i+3])
+				b, _ := strconv.Atoi(params[i+4])
+				c = termenv.RGBColor(fmt.Sprintf("#%02x%02x%02x", r, g, b))
+				i += 4
+			default:
+				out = append(out, p)
+				continue
+			}
+
+			if converted := profile.Convert(c); converted != nil {
+				if code := converted.Sequence(p == "48"); code != "" {
+					out = append(out, code)
+				}
+			}
+		}
+
+		// An empty parameter list would mean "reset", which the original didn't
+		if len(out) == 0 {
+			return ""
+		}
+		return "\x1b[" + strings.Join(out, ";") + "m"
+	})
+}
@@ -0,0 +1,40 @@
+package ui
+
+// Glyphs are the symbols drawn in front of each file in the list. Empty
+// fields fall back to the defaults, so a config only names what it changes
+type Glyphs struct {
+	Checked    string `json:"checked,omitempty"`
+	Unchecked  string `json:"unchecked,omitempty"`
+	Staged     string `json:"staged,omitempty"`
+	Unstaged   string `json:"unstaged,omitempty"`
+	Untracked  string `json:"untracked,omitempty"`
+	Conflicted string `json:"conflicted,omitempty"`
+}
+
+// DefaultGlyphs are plain ASCII, so they render on any terminal
+var DefaultGlyphs = Glyphs{
+	Checked:    "[X]",
+	Unchecked:  "[ ]",
+	Staged:     "+",
+	Unstaged:   "-",
+	Untracked:  "?",
+	Conflicted: "!",
+}
+
+// With returns g with every glyph set in over replacing its own
+func (g Glyphs) With(over Glyphs) Glyphs {
+	pick := func(base, o string) string {
+		if o != "" {
+			return o
+		}
+		return base
+	}
+	return Glyphs{
+		Checked:    pick(g.Checked, over.Checked),
+		Unchecked:  pick(g.Unchecked, over.Unchecked),
+		Staged:     pick(g.Staged, over.Staged),
+		Unstaged:   pick(g.Unstaged, over.Unstaged),
+		Untracked:  pick(g.Untracked, over.Untracked),
+		Conflicted: pick(g.Conflicted, over.Conflicted),
+	}
+}
@@ -12,19 +12,114 @@ type KeyMap struct {
 	Home     key.Binding
 	End      key.Binding
 
+	// Jump between status sections
+	NextSection  key.Binding
+	PrevSection  key.Binding
+	NextConflict key.Binding
+
 	// Selection
 	Select    key.Binding
 	SelectAll key.Binding
 	Deselect  key.Binding
 
 	// Actions
-	Apply         key.Binding
-	Commit        key.Binding
-	ModifyHead    key.Binding
-	Search        key.Binding
-	TogglePreview key.Binding
-	ToggleHelp    key.Binding
-	Quit          key.Binding
+	Apply           key.Binding
+	StageFile       key.Binding
+	UnstageFile     key.Binding
+	StageTracked    key.Binding
+	IntentToAdd     key.Binding
+	Commit          key.Binding
+	CommitFile      key.Binding
+	ModifyHead      key.Binding
+	ApplyPatch      key.Binding
+	RestoreFromRef  key.Binding
+	ViewCommit      key.Binding
+	ShowHead        key.Binding
+	Macro           key.Binding
+	HideChanges     key.Binding
+	FlaggedFiles    key.Binding
+	ExportPatch     key.Binding
+	BrowseCommit    key.Binding
+	Retry           key.Binding
+	SelectionDiff   key.Binding
+	OpenWeb         key.Binding
+	OpenPager       key.Binding
+	FullscreenDiff  key.Binding
+	CopyPath        key.Binding
+	Search          key.Binding
+	Palette         key.Binding
+	FocusPreview    key.Binding
+	FocusLeft       key.Binding
+	FocusRight      key.Binding
+	TogglePreview   key.Binding
+	MaximizePreview key.Binding
+	ToggleHelp      key.Binding
+	Quit            key.Binding
+
+	// Files with hidden changes
+	ToggleAssumeUnchanged key.Binding
+	ToggleSkipWorktree    key.Binding
+
+	// Conflict resolution
+	TakeOurs   key.Binding
+	TakeTheirs key.Binding
+
+	// Preview (while focused)
+	SelectLines key.Binding
+	StageHunk   key.Binding
+	DiscardHunk key.Binding
+	Blame       key.Binding
+
+	// Diff options
+	IgnoreWhitespace    key.Binding
+	HighlightWhitespace key.Binding
+	WordDiff            key.Binding
+	WrapLines           key.Binding
+	SplitPreview        key.Binding
+	LoadFullPreview     key.Binding
+	UntrackedMode       key.Binding
+	RelativePaths       key.Binding
+	SkipSubmodules      key.Binding
+	DiffAlgorithm       key.Binding
+	ExpandContext       key.Binding
+	ResetContext        key.Binding
+	HideContext         key.Binding
+	RenameDetection     key.Binding
+
+	// Input
+	OpenEditor   key.Binding
+	ReviewStaged key.Binding
+	Continue     key.Binding
+	Confirm      key.Binding
+	Back         key.Binding
+	Cancel       key.Binding
+	Close        key.Binding
+	Dismiss      key.Binding
+	Yes          key.Binding
+	No           key.Binding
+
+	// HEAD modification
+	Amend           key.Binding
+	SoftReset       key.Binding
+	Revert          key.Binding
+	Redate          key.Binding
+	ResetAuthorDate key.Binding
+
+	// Patch application
+	ToggleCached key.Binding
+
+	// Patch mode
+	PatchMode    key.Binding
+	IncludeHunk  key.Binding
+	SkipHunk     key.Binding
+	SplitHunk    key.Binding
+	PreviousHunk key.Binding
+
+	// Fullscreen diff
+	NextHunk  key.Binding
+	PrevHunk  key.Binding
+	NextMatch key.Binding
+	PrevMatch key.Binding
 }
 
 // DefaultKeyMap returns the default keybindings
@@ -54,6 +149,18 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("end", "G"),
 			key.WithHelp("end/G", "go to bottom"),
 		),
+		NextSection: key.NewBinding(
+			key.WithKeys("]"),
+			key.WithHelp("]", "next status section"),
+		),
+		PrevSection: key.NewBinding(
+			key.WithKeys("["),
+			key.WithHelp("[", "previous status section"),
+		),
+		NextConflict: key.NewBinding(
+			key.WithKeys("!"),
+			key.WithHelp("!", "next conflicted file"),
+		),
 		Select: key.NewBinding(
 			key.WithKeys("space", "tab"),
 			key.WithHelp("space/tab", "select file"),
@@ -70,6 +177,22 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("enter"),
 			key.WithHelp("enter", "stage/unstage"),
 		),
+		StageFile: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "stage file"),
+		),
+		UnstageFile: key.NewBinding(
+			key.WithKeys("S"),
+			key.WithHelp("S", "unstage file"),
+		),
+		StageTracked: key.NewBinding(
+			key.WithKeys("M"),
+			key.WithHelp("M", "stage modified (skip untracked)"),
+		),
+		IntentToAdd: key.NewBinding(
+			key.WithKeys("N"),
+			key.WithHelp("N", "intent to add"),
+		),
 		Commit: key.NewBinding(
 			key.WithKeys("c"),
 			key.WithHelp("c", "commit"),
@@ -78,13 +201,107 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("m"),
 			key.WithHelp("m", "modify HEAD"),
 		),
+		ApplyPatch: key.NewBinding(
+			key.WithKeys("A"),
+			key.WithHelp("A", "apply patch file"),
+		),
+		RestoreFromRef: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "restore file from ref"),
+		),
+		CommitFile: key.NewBinding(
+			key.WithKeys("C"),
+			key.WithHelp("C", "commit file/selection"),
+		),
+		ViewCommit: key.NewBinding(
+			key.WithKeys("o"),
+			key.WithHelp("o", "view new commit"),
+			key.WithDisabled(), // Enabled once a commit has been created
+		),
+		SelectionDiff: key.NewBinding(
+			key.WithKeys("J"),
+			key.WithHelp("J", "diff selected files together"),
+		),
+		Retry: key.NewBinding(
+			key.WithKeys("Y"),
+			key.WithHelp("Y", "retry failed operation"),
+		),
+		ShowHead: key.NewBinding(
+			key.WithKeys("H"),
+			key.WithHelp("H", "show last commit"),
+		),
+		Macro: key.NewBinding(
+			key.WithKeys("t"),
+			key.WithHelp("t", "run macro (stage, commit, push)"),
+		),
+		HideChanges: key.NewBinding(
+			key.WithKeys("h"),
+			key.WithHelp("h", "hide changes (assume unchanged)"),
+		),
+		FlaggedFiles: key.NewBinding(
+			key.WithKeys("Z"),
+			key.WithHelp("Z", "list files with hidden changes"),
+		),
+		ExportPatch: key.NewBinding(
+			key.WithKeys("X"),
+			key.WithHelp("X", "export patch (selected or staged)"),
+		),
+		ToggleAssumeUnchanged: key.NewBinding(
+			key.WithKeys("a"),
+			key.WithHelp("a", "toggle assume-unchanged"),
+		),
+		ToggleSkipWorktree: key.NewBinding(
+			key.WithKeys("w"),
+			key.WithHelp("w", "toggle skip-worktree"),
+		),
+		BrowseCommit: key.NewBinding(
+			key.WithKeys("l"),
+			key.WithHelp("l", "browse commit files"),
+			key.WithDisabled(), // Enabled while a commit is previewed
+		),
+		OpenWeb: key.NewBinding(
+			key.WithKeys("u"),
+			key.WithHelp("u", "open on web"),
+		),
+		OpenPager: key.NewBinding(
+			key.WithKeys("|"),
+			key.WithHelp("|", "view diff in pager"),
+		),
+		FullscreenDiff: key.NewBinding(
+			key.WithKeys("ctrl+f"),
+			key.WithHelp("ctrl+f", "fullscreen diff"),
+		),
+		CopyPath: key.NewBinding(
+			key.WithKeys("y"),
+			key.WithHelp("y", "copy path (again: cycle form)"),
+		),
 		Search: key.NewBinding(
 			key.WithKeys("/"),
 			key.WithHelp("/", "search"),
 		),
+		Palette: key.NewBinding(
+			key.WithKeys(":", "ctrl+p"),
+			key.WithHelp(":", "command palette"),
+		),
+		FocusPreview: key.NewBinding(
+			key.WithKeys("p"),
+			key.WithHelp("p", "focus preview"),
+		),
+		FocusLeft: key.NewBinding(
+			key.WithKeys("left"),
+			key.WithHelp("←", "focus pane to the left"),
+		),
+		FocusRight: key.NewBinding(
+			key.WithKeys("right"),
+			key.WithHelp("→", "focus pane to the right"),
+		),
 		TogglePreview: key.NewBinding(
-			key.WithKeys("p", "P"),
-			key.WithHelp("p", "toggle preview"),
+			key.WithKeys("P"),
+			key.WithHelp("P", "show/hide preview"),
+		),
+		MaximizePreview: key.NewBinding(
+			key.WithKeys("F"),
+			key.WithHelp("F", "maximize preview"),
 		),
 		ToggleHelp: key.NewBinding(
 			key.WithKeys("?"),
@@ -94,20 +311,217 @@ func DefaultKeyMap() KeyMap {
 			key.WithKeys("q", "ctrl+c"),
 			key.WithHelp("q", "quit"),
 		),
+		TakeOurs: key.NewBinding(
+			key.WithKeys("O"),
+			key.WithHelp("O", "resolve with ours"),
+		),
+		TakeTheirs: key.NewBinding(
+			key.WithKeys("T"),
+			key.WithHelp("T", "resolve with theirs"),
+		),
+		SelectLines: key.NewBinding(
+			key.WithKeys("v"),
+			key.WithHelp("v", "select lines"),
+		),
+		StageHunk: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "stage/unstage hunk/lines"),
+		),
+		DiscardHunk: key.NewBinding(
+			key.WithKeys("x"),
+			key.WithHelp("x", "discard hunk/lines"),
+		),
+		Blame: key.NewBinding(
+			key.WithKeys("B"),
+			key.WithHelp("B", "blame line"),
+		),
+		IgnoreWhitespace: key.NewBinding(
+			key.WithKeys("w"),
+			key.WithHelp("w", "ignore whitespace"),
+		),
+		HighlightWhitespace: key.NewBinding(
+			key.WithKeys("W"),
+			key.WithHelp("W", "highlight ws errors"),
+		),
+		WordDiff: key.NewBinding(
+			key.WithKeys("i"),
+			key.WithHelp("i", "word diff"),
+		),
+		WrapLines: key.NewBinding(
+			key.WithKeys("z"),
+			key.WithHelp("z", "wrap lines"),
+		),
+		SplitPreview: key.NewBinding(
+			key.WithKeys("V"),
+			key.WithHelp("V", "staged/unstaged side by side"),
+		),
+		LoadFullPreview: key.NewBinding(
+			key.WithKeys("L"),
+			key.WithHelp("L", "load truncated preview"),
+		),
+		UntrackedMode: key.NewBinding(
+			key.WithKeys("U"),
+			key.WithHelp("U", "untracked: normal/all/none"),
+		),
+		SkipSubmodules: key.NewBinding(
+			key.WithKeys("K"),
+			key.WithHelp("K", "include/skip submodules in bulk staging"),
+		),
+		RelativePaths: key.NewBinding(
+			key.WithKeys("."),
+			key.WithHelp(".", "paths from cwd/repo root"),
+		),
+		ExpandContext: key.NewBinding(
+			key.WithKeys("e"),
+			key.WithHelp("e", "expand context"),
+		),
+		ResetContext: key.NewBinding(
+			key.WithKeys("E"),
+			key.WithHelp("E", "reset context"),
+		),
+		HideContext: key.NewBinding(
+			key.WithKeys("0"),
+			key.WithHelp("0", "changed lines only"),
+		),
+		DiffAlgorithm: key.NewBinding(
+			key.WithKeys("D"),
+			key.WithHelp("D", "cycle diff algorithm"),
+		),
+		RenameDetection: key.NewBinding(
+			key.WithKeys("R"),
+			key.WithHelp("R", "cycle rename detection"),
+		),
+		OpenEditor: key.NewBinding(
+			key.WithKeys("ctrl+o"),
+			key.WithHelp("ctrl+o", "open in $EDITOR"),
+		),
+		ReviewStaged: key.NewBinding(
+			key.WithKeys("ctrl+r"),
+			key.WithHelp("ctrl+r", "review staged patch"),
+		),
+		Continue: key.NewBinding(
+			key.WithKeys("ctrl+d"),
+			key.WithHelp("ctrl+d", "continue"),
+		),
+		Confirm: key.NewBinding(
+			key.WithKeys("enter"),
+			key.WithHelp("enter", "confirm"),
+		),
+		Back: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "back"),
+		),
+		Cancel: key.NewBinding(
+			key.WithKeys("esc"),
+			key.WithHelp("esc", "cancel"),
+		),
+		Dismiss: key.NewBinding(
+			key.WithKeys("enter", "esc"),
+			key.WithHelp("enter/esc", "dismiss"),
+		),
+		Yes: key.NewBinding(
+			key.WithKeys("y"),
+			key.WithHelp("y", "yes"),
+		),
+		No: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "no"),
+		),
+		Close: key.NewBinding(
+			key.WithKeys("esc", "q"),
+			key.WithHelp("esc/q", "close"),
+		),
+		Amend: key.NewBinding(
+			key.WithKeys("m"),
+			key.WithHelp("m", "amend commit"),
+		),
+		SoftReset: key.NewBinding(
+			key.WithKeys("f"),
+			key.WithHelp("f", "soft reset"),
+		),
+		Revert: key.NewBinding(
+			key.WithKeys("r"),
+			key.WithHelp("r", "revert/abort revert"),
+		),
+		Redate: key.NewBinding(
+			key.WithKeys("d"),
+			key.WithHelp("d", "redate earlier commit/abort rebase"),
+		),
+		ResetAuthorDate: key.NewBinding(
+			key.WithKeys("ctrl+t"),
+			key.WithHelp("ctrl+t", "keep/reset author date"),
+		),
+		ToggleCached: key.NewBinding(
+			key.WithKeys("ctrl+t"),
+			key.WithHelp("ctrl+t", "working tree/index"),
+		),
+		PatchMode: key.NewBinding(
+			key.WithKeys("I"),
+			key.WithHelp("I", "pick hunks and commit"),
+		),
+		IncludeHunk: key.NewBinding(
+			key.WithKeys("y"),
+			key.WithHelp("y", "include hunk"),
+		),
+		SkipHunk: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "skip hunk"),
+		),
+		SplitHunk: key.NewBinding(
+			key.WithKeys("s"),
+			key.WithHelp("s", "split hunk"),
+		),
+		PreviousHunk: key.NewBinding(
+			key.WithKeys("backspace"),
+			key.WithHelp("backspace", "previous hunk"),
+		),
+		NextHunk: key.NewBinding(
+			key.WithKeys("]"),
+			key.WithHelp("]", "next hunk"),
+		),
+		PrevHunk: key.NewBinding(
+			key.WithKeys("["),
+			key.WithHelp("[", "previous hunk"),
+		),
+		NextMatch: key.NewBinding(
+			key.WithKeys("n"),
+			key.WithHelp("n", "next match"),
+		),
+		PrevMatch: key.NewBinding(
+			key.WithKeys("N"),
+			key.WithHelp("N", "previous match"),
+		),
 	}
 }
 
 // ShortHelp returns bindings to show in the short help
 func (k KeyMap) ShortHelp() []key.Binding {
-	return []key.Binding{k.Select, k.Apply, k.TogglePreview, k.ToggleHelp, k.Quit}
+	return []key.Binding{k.Select, k.SelectAll, k.Deselect, k.Apply, k.Commit, k.ViewCommit, k.ModifyHead, k.FocusPreview, k.ToggleHelp, k.Quit}
 }
 
 // FullHelp returns all bindings grouped for the full help view
 func (k KeyMap) FullHelp() [][]key.Binding {
 	return [][]key.Binding{
 		{k.Up, k.Down, k.PageUp, k.PageDown, k.Home, k.End},
+		{k.NextSection, k.PrevSection, k.NextConflict},
 		{k.Select, k.SelectAll, k.Deselect},
-		{k.Apply, k.Commit, k.ModifyHead},
-		{k.Search, k.TogglePreview, k.ToggleHelp, k.Quit},
+		{k.Apply, k.StageFile, k.UnstageFile, k.StageTracked, k.IntentToAdd, k.HideChanges, k.FlaggedFiles, k.ReviewStaged, k.SelectionDiff, k.PatchMode, k.Commit, k.CommitFile, k.Macro, k.ViewCommit, k.ShowHead, k.BrowseCommit, k.Retry, k.ModifyHead, k.ApplyPatch, k.ExportPatch, k.RestoreFromRef, k.OpenWeb, k.OpenPager, k.FullscreenDiff, k.CopyPath},
+		{k.Search, k.Palette, k.FocusPreview, k.FocusLeft, k.FocusRight, k.TogglePreview, k.MaximizePreview, k.ToggleHelp, k.Quit},
+		{k.TakeOurs, k.TakeTheirs},
+		{k.SelectLines, k.StageHunk, k.DiscardHunk, k.Blame},
+		{k.IgnoreWhitespace, k.HighlightWhitespace, k.WordDiff, k.WrapLines, k.SplitPreview, k.ExpandContext, k.ResetContext, k.HideContext, k.DiffAlgorithm, k.RenameDetection, k.LoadFullPreview, k.UntrackedMode, k.RelativePaths, k.SkipSubmodules},
 	}
 }
+
+// HelpKeyMap adapts a fixed set of bindings to the help.KeyMap interface
+type HelpKeyMap []key.Binding
+
+// ShortHelp returns the bindings as a single line
+func (h HelpKeyMap) ShortHelp() []key.Binding {
+	return h
+}
+
+// FullHelp returns the bindings as a single group
+func (h HelpKeyMap) FullHelp() [][]key.Binding {
+	return [][]key.Binding{h}
+}
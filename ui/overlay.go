@@ -0,0 +1,36 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/charmbracelet/lipgloss"
+)
+
+// OverlayRight draws box over base, flush with the right edge of a width
+// column screen and starting top lines down. Base lines are cut at the
+// box's left edge without splitting escape sequences, or dropped when the
+// box is as wide as the screen
+func OverlayRight(base, box string, width, top int) string {
+	lines := strings.Split(base, "\n")
+	boxLines := strings.Split(box, "\n")
+	left := max(width-lipgloss.Width(box), 0)
+
+	for i, boxLine := range boxLines {
+		row := top + i
+		for row >= len(lines) {
+			lines = append(lines, "")
+		}
+		// A box as wide as the screen covers the whole line. Otherwise the
+		// kept part is reset, so its colors don't run into the box
+		var cut string
+		if left > 0 {
+			cut = WrapANSI(lines[row], left)[0] + ansiReset
+		}
+		if pad := left - lipgloss.Width(cut); pad > 0 {
+			cut += strings.Repeat(" ", pad)
+		}
+		lines[row] = cut + boxLine
+	}
+
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,73 @@
+package ui
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestOverlayRight(t *testing.T) {
+	tests := []struct {
+		name  string
+		base  string
+		box   string
+		width int
+		top   int
+		want  string
+	}{
+		{
+			name:  "cuts the base",
+			base:  "abcdefgh\n12345678",
+			box:   "XY",
+			width: 8,
+			top:   1,
+			want:  "abcdefgh\n123456" + ansiReset + "XY",
+		},
+		{
+			name:  "pads short lines",
+			base:  "ab",
+			box:   "XY",
+			width: 6,
+			want:  "ab" + ansiReset + "  XY",
+		},
+		{
+			name:  "extends the base",
+			base:  "abc",
+			box:   "XY\nZW",
+			width: 4,
+			want:  "ab" + ansiReset + "XY\n" + ansiReset + "  ZW",
+		},
+		{
+			name:  "colors end at the box",
+			base:  "\x1b[32mgreen text\x1b[m",
+			box:   "XY",
+			width: 6,
+			want:  "\x1b[32mgree\x1b[0m" + ansiReset + "XY",
+		},
+		{
+			name:  "box as wide as the screen",
+			base:  "\x1b[32mgreen\x1b[m\nabc",
+			box:   "XYZW",
+			width: 4,
+			want:  "XYZW\nabc",
+		},
+		{
+			name:  "box wider than the screen",
+			base:  "abc",
+			box:   "XYZW",
+			width: 2,
+			want:  "XYZW",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := OverlayRight(tt.base, tt.box, tt.width, tt.top)
+			if got != tt.want {
+				t.Errorf("OverlayRight = %q, want %q", got, tt.want)
+			}
+			if strings.Contains(StripColors(got), "\x1b") {
+				t.Errorf("OverlayRight left a broken escape sequence: %q", got)
+			}
+		})
+	}
+}
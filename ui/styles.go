@@ -44,6 +44,10 @@ var (
 		Background(ColorGray).
 		Foreground(ColorWhite)
 
+	// Added to the item's own style on the characters a filter matched
+	FilterMatchStyle = lipgloss.NewStyle().
+		Underline(true)
+
 	// Preview styles
 	PreviewStyle = lipgloss.NewStyle().
 		Padding(1)
@@ -52,6 +56,14 @@ var (
 		Bold(true).
 		Foreground(ColorCyan)
 
+	// Diff cursor gutter styles (focused preview)
+	DiffCursorStyle = lipgloss.NewStyle().
+		Foreground(ColorCyan).
+		Bold(true)
+
+	DiffSelectionStyle = lipgloss.NewStyle().
+		Foreground(ColorMagenta)
+
 	// Status bar style
 	StatusBarStyle = lipgloss.NewStyle().
 		Foreground(ColorWhite).
@@ -71,6 +83,20 @@ var (
 		Foreground(ColorYellow).
 		Bold(true)
 
+	ConflictedStyle = lipgloss.NewStyle().
+		Foreground(ColorMagenta).
+		Bold(true)
+
+	// Diff stat styles
+	AdditionsStyle = lipgloss.NewStyle().
+		Foreground(ColorGreen)
+
+	DeletionsStyle = lipgloss.NewStyle().
+		Foreground(ColorRed)
+
+	BinaryStatStyle = lipgloss.NewStyle().
+		Foreground(ColorMagenta)
+
 	// Message styles
 	SuccessStyle = lipgloss.NewStyle().
 		Foreground(ColorGreen).
@@ -92,6 +118,12 @@ var (
 		Faint(true).
 		Foreground(ColorGray)
 
+	// Key hints popup, shown after pausing in a new mode
+	KeyHintsStyle = lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(ColorCyan).
+		Padding(0, 1)
+
 	// Checkbox styles
 	CheckedStyle = lipgloss.NewStyle().
 		Foreground(ColorGreen).
@@ -110,6 +142,8 @@ func FileStatusStyle(statusSymbol string) lipgloss.Style {
 		return UnstagedStyle
 	case "?":
 		return UntrackedStyle
+	case "!":
+		return ConflictedStyle
 	default:
 		return lipgloss.NewStyle()
 	}
@@ -124,6 +158,8 @@ func FileStatusColor(statusSymbol string) lipgloss.Color {
 		return ColorRed
 	case "?":
 		return ColorYellow
+	case "!":
+		return ColorMagenta
 	default:
 		return ColorDefault
 	}
@@ -0,0 +1,81 @@
+package ui
+
+import (
+	"strings"
+
+	"github.com/mattn/go-runewidth"
+)
+
+// ansiReset clears all SGR attributes
+const ansiReset = "\x1b[0m"
+
+// tabWidth is the number of spaces a tab expands to when wrapping
+const tabWidth = 4
+
+// WrapANSI hard-wraps a single line to width columns without splitting ANSI
+// escape sequences. Colors active at a break are reset at the end of the row
+// and re-applied at the start of the next one, so they never bleed into
+// borders or gutters drawn around the row
+func WrapANSI(line string, width int) []string {
+	line = strings.ReplaceAll(line, "\t", strings.Repeat(" ", tabWidth))
+	if width <= 0 {
+		return []string{line}
+	}
+
+	var rows []string
+	var row strings.Builder
+	var active strings.Builder // SGR sequences in effect since the last reset
+	rowWidth := 0
+
+	runes := []rune(line)
+	for i := 0; i < len(runes); i++ {
+		r := runes[i]
+
+		// Copy escape sequences through untouched, tracking SGR state
+		if r == '\x1b' {
+			seq := readEscape(runes, i)
+			i += len([]rune(seq)) - 1
+			row.WriteString(seq)
+			if strings.HasSuffix(seq, "m") {
+				if seq == "\x1b[m" || seq == ansiReset {
+					active.Reset()
+				} else {
+					active.WriteString(seq)
+				}
+			}
+			continue
+		}
+
+		w := runewidth.RuneWidth(r)
+		if rowWidth+w > width && rowWidth > 0 {
+			if active.Len() > 0 {
+				row.WriteString(ansiReset)
+			}
+			rows = append(rows, row.String())
+			row.Reset()
+			row.WriteString(active.String())
+			rowWidth = 0
+		}
+		row.WriteRune(r)
+		rowWidth += w
+	}
+
+	return append(rows, row.String())
+}
+
+// readEscape returns the escape sequence starting at runes[start]
+func readEscape(runes []rune, start int) string {
+	end := start + 1
+	if end < len(runes) && runes[end] == '[' {
+		// CSI: parameters until a final byte in @..~
+		for end++; end < len(runes); end++ {
+			if runes[end] >= '@' && runes[end] <= '~' {
+				end++
+				break
+			}
+		}
+	} else if end < len(runes) {
+		end++ // Two-character escape
+	}
+	return string(runes[start:min(end, len(runes))])
+}
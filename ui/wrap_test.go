@@ -0,0 +1,113 @@
+package ui
+
+import (
+	"regexp"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/mattn/go-runewidth"
+)
+
+func TestWrapANSI(t *testing.T) {
+	tests := []struct {
+		name  string
+		line  string
+		width int
+		want  []string
+	}{
+		{
+			name:  "plain",
+			line:  "abcdefgh",
+			width: 3,
+			want:  []string{"abc", "def", "gh"},
+		},
+		{
+			name:  "fits",
+			line:  "\x1b[32m+ok\x1b[m",
+			width: 10,
+			want:  []string{"\x1b[32m+ok\x1b[m"},
+		},
+		{
+			name:  "color carried across rows",
+			line:  "\x1b[32m+hello world\x1b[m",
+			width: 6,
+			want:  []string{"\x1b[32m+hello\x1b[0m", "\x1b[32m world\x1b[m"},
+		},
+		{
+			name:  "stacked attributes",
+			line:  "\x1b[1m\x1b[31m-abcd\x1b[m",
+			width: 3,
+			want:  []string{"\x1b[1m\x1b[31m-ab\x1b[0m", "\x1b[1m\x1b[31mcd\x1b[m"},
+		},
+		{
+			name:  "reset before the break",
+			line:  "\x1b[36m@@\x1b[m context",
+			width: 4,
+			want:  []string{"\x1b[36m@@\x1b[m c", "onte", "xt"},
+		},
+		{
+			name:  "tabs expand",
+			line:  "\tx",
+			width: 3,
+			want:  []string{"   ", " x"},
+		},
+		{
+			name:  "wide runes aren't split",
+			line:  "日本語",
+			width: 5,
+			want:  []string{"日本", "語"},
+		},
+		{
+			name:  "no width",
+			line:  "\x1b[32m+abc\x1b[m",
+			width: 0,
+			want:  []string{"\x1b[32m+abc\x1b[m"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got := WrapANSI(tt.line, tt.width)
+			if !slices.Equal(got, tt.want) {
+				t.Errorf("WrapANSI(%q, %d) = %q, want %q", tt.line, tt.width, got, tt.want)
+			}
+		})
+	}
+}
+
+// escapePattern matches a whole SGR sequence; any other ESC would be a
+// sequence cut apart
+var escapePattern = regexp.MustCompile(`\x1b\[[0-9;]*m`)
+
+// TestWrapANSIColoredDiff wraps a line of `git diff --color=always` output
+// at every width, checking the escape sequences survive intact, each row
+// fits and leaves no color active, and the text reads the same
+func TestWrapANSIColoredDiff(t *testing.T) {
+	line := "\x1b[32m+\x1b[m\x1b[32m\tfmt.Println(\"wrapped\")\x1b[m\x1b[41m \x1b[m"
+	text := strings.ReplaceAll(StripColors(line), "\t", strings.Repeat(" ", tabWidth))
+
+	for width := 1; width <= runewidth.StringWidth(text)+1; width++ {
+		rows := WrapANSI(line, width)
+
+		var joined strings.Builder
+		for i, row := range rows {
+			if strings.Count(row, "\x1b") != len(escapePattern.FindAllString(row, -1)) {
+				t.Fatalf("width %d row %d has a broken escape sequence: %q", width, i, row)
+			}
+			plain := StripColors(row)
+			if w := runewidth.StringWidth(plain); w > width {
+				t.Errorf("width %d row %d is %d columns: %q", width, i, w, row)
+			}
+			if seqs := escapePattern.FindAllString(row, -1); len(seqs) > 0 {
+				if last := seqs[len(seqs)-1]; last != ansiReset && last != "\x1b[m" {
+					t.Errorf("width %d row %d leaves %q active: %q", width, i, last, row)
+				}
+			}
+			joined.WriteString(plain)
+		}
+		if joined.String() != text {
+			t.Errorf("width %d: rows read %q, want %q", width, joined.String(), text)
+		}
+	}
+}
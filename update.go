@@ -1,31 +1,100 @@
 package main
 
 import (
+	"errors"
 	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime/debug"
 	"strings"
+	"time"
 
 	tea "github.com/charmbracelet/bubbletea"
 	"github.com/charmbracelet/bubbles/key"
 	"github.com/charmbracelet/bubbles/list"
 
+	"github.com/rai/interactive-git/git"
 	"github.com/rai/interactive-git/ui"
 )
 
-// Update handles messages and updates the model
-func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
+// Update handles messages and updates the model. A panic while handling a
+// message is reported as an error and the model is left as it was before the
+// message, so an edge-case bug doesn't take the whole program down
+func (m Model) Update(msg tea.Msg) (model tea.Model, cmd tea.Cmd) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("panic in Update", "msg", fmt.Sprintf("%T", msg), "panic", r, "stack", string(debug.Stack()))
+			model, cmd = m, func() tea.Msg {
+				return errorMsg{err: fmt.Sprintf("Internal error: %v", r)}
+			}
+		}
+	}()
+
+	model, cmd = m.update(msg)
+	// An operation that finished with an error on screen can be retried
+	if next, ok := model.(Model); ok && m.processing && !next.processing {
+		next.retryable = next.lastOperation != nil && next.err != ""
+		if warned := next.showWarnings(); warned != nil {
+			cmd = tea.Batch(cmd, warned)
+		}
+		model = next
+	}
+	return model, cmd
+}
+
+// showWarnings adds the warnings git printed, such as line ending
+// conversions under core.autocrlf, to the status line. They are info: the
+// commands succeeded, so they never take the error line
+func (m *Model) showWarnings() tea.Cmd {
+	if m.gitClient == nil {
+		return nil
+	}
+	warnings := m.gitClient.TakeWarnings()
+	if len(warnings) == 0 {
+		return nil
+	}
+	for _, w := range warnings {
+		slog.Info("git warning", "warning", w)
+	}
+	note := warnings[0]
+	if len(warnings) > 1 {
+		note += fmt.Sprintf(" (+%d more, see the log)", len(warnings)-1)
+	}
+	if m.status != "" {
+		note = m.status + "; " + note
+	}
+	m.status = note
+	return m.clearStatus()
+}
+
+// update dispatches a message to its handler
+func (m Model) update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	switch msg := msg.(type) {
 	case tea.KeyMsg:
-		if m.err != "" {
-			// If there's an error, only allow quitting or dismissing
-			switch {
-			case key.Matches(msg, m.keys.Quit):
-				return m, tea.Quit
-			}
-			return m, nil
+		if m.err != "" && m.errSticky {
+			return m.handleErrorKeys(msg)
 		}
 
 		return m.handleKeyMsg(msg)
 
+	case list.FilterMatchesMsg:
+		// Matches go to the list whose filter is being typed
+		var cmd tea.Cmd
+		switch m.state {
+		case StatePalette:
+			m.palette, cmd = m.palette.Update(msg)
+		case StateCommitFiles:
+			m.commitFiles, cmd = m.commitFiles.Update(msg)
+			cmd = tea.Batch(cmd, m.showCommitFile())
+		case StateFlaggedFiles:
+			m.flagged, cmd = m.flagged.Update(msg)
+		case StateFileList:
+			m.list, cmd = m.list.Update(msg)
+			cmd = tea.Batch(cmd, m.reloadPreview())
+		}
+		return m, cmd
+
 	case tea.WindowSizeMsg:
 		m.width = msg.Width
 		m.height = msg.Height
@@ -34,25 +103,11 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		// Recalculate layout
 		m.layout = ui.NewLayout(m.width, m.height)
 
-		// Calculate shared pane height for split mode
-		paneHeight := m.layout.ListHeight()
-		// Viewport height: paneHeight - border (2) - title line (1)
-		viewportHeight := paneHeight - 3
-		if viewportHeight < 1 {
-			viewportHeight = 1
-		}
+		// Footer help is padded by 1 on each side
+		m.help.Width = m.width - 2
+		m.progress.Width = m.width - 2
 
-		// Adjust list size based on layout
-		// Subtract 4 for border (2) + padding (2)
-		if m.layout.HasPreviewPane() && m.showPreview {
-			m.list.SetWidth(m.layout.ListWidth - 4)
-			m.viewport.Width = m.layout.PreviewWidth - 4
-		} else {
-			m.list.SetWidth(m.width - 4)
-			m.viewport.Width = m.width - 4
-		}
-		m.list.SetHeight(paneHeight)
-		m.viewport.Height = viewportHeight
+		m.updateComponentSizes()
 
 		// Fetch initial diff for current file
 		if m.showPreview && len(m.files) > 0 {
@@ -66,24 +121,25 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 
 	case gitStatusMsg:
 		m.gitStatus = msg.status
-		m.files = msg.status.AllFiles()
+		m.files = m.carrySelection(msg.status.AllFiles())
 
-		// Properly set items in the list
-		// Create a slice of list.Item interface
-		var listFileItems []list.Item
-		for _, f := range m.files {
-			listFileItems = append(listFileItems, f)
-		}
-		m.list.SetItems(listFileItems)
+		m.syncListItems()
+		m.setFileKeysEnabled(len(m.files) > 0)
+		m.followStaged()
 
 		// Ensure list has a selection (defaults to -1, needs to be 0)
-		if m.list.Index() < 0 && len(m.files) > 0 {
+		visible := len(m.list.VisibleItems())
+		if m.list.Index() < 0 && visible > 0 {
 			m.list.Select(0)
 		}
+		// Keep the cursor on the nearest remaining file when the list shrinks
+		if m.list.Index() >= visible && visible > 0 {
+			m.list.Select(visible - 1)
+		}
 
 		// Fetch initial diff for first file
-		if m.showPreview && len(m.files) > 0 && m.ready && m.list.Index() >= 0 {
-			m.lastFileIndex = m.list.Index()
+		if m.showPreview && visible > 0 && m.ready && m.list.Index() >= 0 {
+			m.lastFileIndex = m.cursorIndex()
 			currentFile := m.getCurrentFile()
 			if currentFile != nil {
 				return m, m.fetchDiffCmd(*currentFile)
@@ -96,10 +152,25 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 	case errorMsg:
 		m.err = msg.err
 		if msg.err == "" {
+			m.errSticky = false
 			return m, nil
 		}
+		m.processing = false
 		return m, m.clearError()
 
+	case keyHintsMsg:
+		if msg.id == m.keyHintsID && !m.errSticky {
+			m.showKeyHints = m.keyHintsSignature() != ""
+		}
+		return m, nil
+
+	case slowOperationMsg:
+		// Only the operation that scheduled this tick counts
+		if m.processing && msg.id == m.processingID {
+			m.slowOperation = true
+		}
+		return m, nil
+
 	case statusMsg:
 		m.status = msg.msg
 		if msg.msg == "" {
@@ -107,17 +178,63 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		}
 		return m, m.clearStatus()
 
+	case clearStatusMsg:
+		// A newer status has its own timer
+		if msg.setAt.Equal(m.lastStatusMsg) {
+			m.status = ""
+		}
+		return m, nil
+
+	case clearErrorMsg:
+		// A newer error has its own timer, and sticky errors have none
+		if msg.setAt.Equal(m.lastErrorMsg) && !m.errSticky {
+			m.err = ""
+		}
+		return m, nil
+
+	case errorFlashMsg:
+		if msg.setAt.Equal(m.errFlashAt) {
+			m.errFlashAt = time.Time{}
+		}
+		return m, nil
+
 	case gitStageMsg:
 		m.processing = false
 		if msg.err != nil {
+			m.followPath = ""
 			m.err = msg.err.Error()
 			return m, m.clearError()
 		}
+		m.forgetDiffs(msg.files...)
 		m.status = fmt.Sprintf("Staged %d file(s)", len(msg.files))
-		// Clear selection after staging
-		m.deselectAll()
 		return m, tea.Batch(m.refreshStatus(), m.clearStatus())
 
+	case gitStageTrackedMsg:
+		m.processing = false
+		if msg.err != nil {
+			m.macro = nil
+			m.err = msg.err.Error()
+			return m, m.clearError()
+		}
+		if m.macro != nil {
+			m.macro.staged = msg.count > 0
+		}
+		m.status = fmt.Sprintf("Staged %d modified file(s) (untracked skipped)", msg.count)
+		if msg.submodules > 0 {
+			m.status = fmt.Sprintf("Staged %d modified file(s) (untracked and %d submodule(s) skipped)", msg.count, msg.submodules)
+		}
+		if msg.of > 0 {
+			m.status = fmt.Sprintf("Staged %d of %d modified files (filtered)", msg.count, msg.of)
+			if msg.count == 0 && msg.submodules == 0 {
+				m.status = "No modified files match the filter"
+				return m, tea.Batch(m.nextMacroStep(), m.clearStatus())
+			}
+			if msg.submodules > 0 {
+				m.status = fmt.Sprintf("Staged %d of %d modified files (filtered, %d submodule(s) skipped)", msg.count, msg.of, msg.submodules)
+			}
+		}
+		return m, tea.Batch(m.refreshStatus(), m.nextMacroStep(), m.clearStatus())
+
 	case gitUnstageMsg:
 		m.processing = false
 		if msg.err != nil {
@@ -125,24 +242,338 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 			return m, m.clearError()
 		}
 		m.status = fmt.Sprintf("Unstaged %d file(s)", len(msg.files))
-		// Clear selection after unstaging
-		m.deselectAll()
+		return m, tea.Batch(m.refreshStatus(), m.clearStatus())
+
+	case stageProgressMsg:
+		if msg.err != nil {
+			m.processing = false
+			m.progressTotal = 0
+			m.err = msg.err.Error()
+			return m, tea.Batch(m.refreshStatus(), m.clearError())
+		}
+
+		// Only show a bar for operations spanning several batches
+		if msg.total > stageBatchSize {
+			m.progressDone, m.progressTotal = msg.done, msg.total
+		}
+		if len(msg.remaining) > 0 {
+			return m, m.stageBatchCmd(msg.stage, msg.remaining, msg.done, msg.total)
+		}
+
+		m.processing = false
+		m.progressTotal = 0
+		if msg.stage {
+			m.status = fmt.Sprintf("Staged %d file(s)", msg.total)
+		} else {
+			m.status = fmt.Sprintf("Unstaged %d file(s)", msg.total)
+		}
 		return m, tea.Batch(m.refreshStatus(), m.clearStatus())
 
 	case gitRefreshMsg:
+		if m.isTyping() {
+			m.refreshPending = true
+			return m, nil
+		}
 		return m, m.refreshStatus()
 
+	case autoRefreshMsg:
+		if m.isTyping() {
+			m.refreshPending = true
+			return m, m.scheduleAutoRefresh()
+		}
+		// Skip polls that would disturb a running operation; the next
+		// tick catches up
+		if m.state != StateFileList || m.processing || m.confirm != nil {
+			return m, m.scheduleAutoRefresh()
+		}
+		// Files may have been edited elsewhere, so cached diffs can't be trusted
+		m.diffCache = make(map[string]string)
+		return m, tea.Batch(m.refreshStatus(), m.scheduleAutoRefresh())
+
+	case patchCheckMsg:
+		m.processing = false
+		if msg.err != nil {
+			m.patchCheck = msg.err.Error()
+			m.patchChecked = ""
+			return m, nil
+		}
+		m.patchCheck = msg.summary
+		m.patchChecked = msg.path
+		return m, nil
+
+	case patchApplyMsg:
+		m.processing = false
+		if msg.err != nil {
+			m.patchCheck = msg.err.Error()
+			m.patchChecked = ""
+			return m, nil
+		}
+		m.cancelApplyPatch()
+		m.status = fmt.Sprintf("Applied %s", msg.path)
+		m.diffCache = make(map[string]string)
+		return m, tea.Batch(m.refreshStatus(), m.clearStatus())
+
+	case patchModeMsg:
+		m.processing = false
+		if msg.err != nil {
+			m.err = msg.err.Error()
+			return m, m.clearError()
+		}
+		if len(msg.files) == 0 {
+			m.status = "No unstaged hunks to pick from"
+			return m, m.clearStatus()
+		}
+		m.enterPatchMode(msg.files)
+		return m, nil
+
+	case patchStagedMsg:
+		m.processing = false
+		m.diffCache = make(map[string]string)
+		if msg.err != nil {
+			// Hunks staged before the failure stay staged, and show in the list
+			m.err = fmt.Sprintf("Staging stopped after %d hunks: %v", msg.hunks, msg.err)
+			return m, tea.Batch(m.refreshStatus(), m.clearError())
+		}
+		cmd := m.enterCommitMode()
+		return m, tea.Batch(cmd, m.refreshStatus())
+
+	case stagedDiffMsg:
+		m.processing = false
+		if msg.err != nil {
+			m.err = msg.err.Error()
+			return m, m.clearError()
+		}
+		m.reviewFrom = m.state
+		m.state = StateReview
+		m.reviewView.SetContent(ui.DegradeColors(msg.content, m.colorProfile))
+		m.reviewView.GotoTop()
+		return m, nil
+
+	case diffViewMsg:
+		m.processing = false
+		m.openDiffView(msg.file, ui.DegradeColors(msg.content, m.colorProfile))
+		return m, nil
+
+	case restoreRefMsg:
+		m.processing = false
+		if msg.err != nil {
+			m.err = msg.err.Error()
+			return m, m.clearError()
+		}
+		if len(msg.paths) == 1 {
+			m.status = fmt.Sprintf("Restored %s from %s", msg.paths[0], msg.ref)
+		} else {
+			m.status = fmt.Sprintf("Restored %d files from %s", len(msg.paths), msg.ref)
+		}
+		m.diffCache = make(map[string]string)
+		return m, tea.Batch(m.refreshStatus(), m.clearStatus())
+
+	case pagerMsg:
+		if msg.err != nil {
+			m.err = fmt.Sprintf("Pager failed: %v", msg.err)
+			return m, m.clearError()
+		}
+		return m, nil
+
+	case editorMsg:
+		if msg.err != nil {
+			m.err = msg.err.Error()
+			return m, m.clearError()
+		}
+		if msg.message == "" {
+			m.status = "Empty message from editor, draft kept"
+			return m, m.clearStatus()
+		}
+		// The edited message is final, move straight on to the date
+		m.commitTextarea.SetValue(msg.message)
+		m.commitMessage = msg.message
+		m.proceedToDateInput()
+		return m, nil
+
+	case gitIntentToAddMsg:
+		m.processing = false
+		if msg.err != nil {
+			m.err = msg.err.Error()
+			return m, m.clearError()
+		}
+		m.status = fmt.Sprintf("Marked %d file(s) as intent to add", len(msg.files))
+		return m, tea.Batch(m.refreshStatus(), m.clearStatus())
+
+	case gitResolveMsg:
+		m.processing = false
+		if msg.err != nil {
+			m.err = msg.err.Error()
+			return m, m.clearError()
+		}
+		m.status = fmt.Sprintf("Resolved %s using %s", msg.file, msg.side)
+		// The file's diffs changed underneath the cache
+		m.diffCache = make(map[string]string)
+		return m, tea.Batch(m.refreshStatus(), m.clearStatus())
+
 	case gitDiffMsg:
 		if msg.err != nil {
 			m.previewContent = fmt.Sprintf("Error loading diff: %v", msg.err)
 		} else {
-			m.previewContent = msg.content
+			m.previewContent = ui.DegradeColors(msg.content, m.colorProfile)
+		}
+		m.previewTitle = ""
+		m.keys.BrowseCommit.SetEnabled(false)
+		m.previewHidden = msg.hidden
+		m.previewTranscoded = msg.transcoded
+		// Keep the cursor in place when the same file is reloaded
+		if msg.file != m.previewFile {
+			m.previewFile = msg.file
+			m.diffCursor = 0
+			m.diffAnchor = -1
+		}
+		m.diffCursor = min(m.diffCursor, len(strings.Split(m.previewContent, "\n"))-1)
+		m.renderPreviewContent()
+		m.restoreKeptLine()
+		return m, m.splitDiffCmd()
+
+	case splitDiffMsg:
+		if msg.err != nil {
+			m.err = fmt.Sprintf("Failed to load split preview: %v", msg.err)
+			return m, m.clearError()
+		}
+		if msg.file != m.splitFile {
+			m.splitFile = msg.file
+			m.splitStagedAt, m.splitUnstagedAt = 0, 0
+		}
+		m.splitStaged = strings.Split(ui.DegradeColors(msg.staged, m.colorProfile), "\n")
+		m.splitUnstaged = strings.Split(ui.DegradeColors(msg.unstaged, m.colorProfile), "\n")
+		m.scrollSplit(0)
+		return m, nil
+
+	case gitHunkMsg:
+		m.processing = false
+		if msg.err != nil {
+			m.err = msg.err.Error()
+			return m, m.clearError()
+		}
+		m.status = msg.message
+		m.diffAnchor = -1
+		// Cached diffs of this file are stale now
+		m.diffCache = make(map[string]string)
+		return m, tea.Batch(m.refreshStatus(), m.clearStatus())
+
+	case gitBlameMsg:
+		m.processing = false
+		if msg.err != nil {
+			m.err = msg.err.Error()
+			return m, m.clearError()
+		}
+		if !msg.info.Committed() {
+			m.status = fmt.Sprintf("%s:%d is not committed yet", msg.file, msg.line)
+		} else {
+			m.status = fmt.Sprintf("%s:%d %s %s, %s: %s", msg.file, msg.line, msg.info.ShortHash(),
+				msg.info.Author, msg.info.Time.Format("2006-01-02"), msg.info.Summary)
+		}
+		return m, m.clearStatus()
+
+	case gitShowCommitMsg:
+		if msg.err != nil {
+			m.err = fmt.Sprintf("Failed to show commit: %v", msg.err)
+			return m, m.clearError()
+		}
+		// Show the commit in a focused preview so it can be scrolled right away
+		m.previewTitle = fmt.Sprintf("commit %s", msg.ref)
+		m.previewRef = msg.ref
+		m.previewSig = msg.sig
+		m.keys.BrowseCommit.SetEnabled(true)
+		m.previewFile = ""
+		m.previewContent = ui.DegradeColors(msg.content, m.colorProfile)
+		m.viewport.GotoTop()
+		m.showPreview = true
+		m.updateComponentSizes()
+		m.setFocus(PanePreview)
+		return m, nil
+
+	case commitFilesMsg:
+		m.processing = false
+		if msg.err != nil {
+			m.err = msg.err.Error()
+			return m, m.clearError()
+		}
+		if len(msg.files) == 0 {
+			m.status = fmt.Sprintf("%s changes no files", msg.ref)
+			return m, m.clearStatus()
+		}
+		return m, m.enterCommitFiles(msg.ref, msg.files)
+
+	case commitFileDiffMsg:
+		m.updateCommitFileDiff(msg)
+		return m, nil
+
+	case patchExportedMsg:
+		m.processing = false
+		if msg.err != nil {
+			m.err = msg.err.Error()
+			return m, m.clearError()
+		}
+		if msg.path == "" {
+			m.status = fmt.Sprintf("Copied patch of %d file(s) to the clipboard", msg.files)
+		} else {
+			m.status = fmt.Sprintf("Wrote patch of %d file(s) to %s", msg.files, msg.path)
+		}
+		return m, m.clearStatus()
+
+	case flaggedFilesMsg:
+		m.processing = false
+		if msg.refresh && m.state != StateFlaggedFiles {
+			return m, nil
+		}
+		if msg.err != nil {
+			m.err = msg.err.Error()
+			return m, m.clearError()
+		}
+		m.showFlaggedFiles(msg.files)
+		return m, m.clearStatus()
+
+	case flagToggledMsg:
+		m.processing = false
+		if msg.err != nil {
+			m.err = msg.err.Error()
+			return m, m.clearError()
+		}
+		m.status = flagToggledStatus(msg)
+		m.forgetDiffs(msg.path)
+		if m.state == StateFlaggedFiles {
+			return m, tea.Batch(m.flaggedFilesCmd(true), m.refreshStatus(), m.clearStatus())
+		}
+		m.status = fmt.Sprintf("Hiding changes to %s (assume-unchanged); %s lists hidden files", msg.path, m.keys.FlaggedFiles.Help().Key)
+		return m, tea.Batch(m.refreshStatus(), m.clearStatus())
+
+	case selectionDiffMsg:
+		m.processing = false
+		if msg.err != nil {
+			m.err = fmt.Sprintf("Failed to diff the selection: %v", msg.err)
+			return m, m.clearError()
+		}
+		// Show the patch in a focused preview, like a commit
+		m.previewTitle = fmt.Sprintf("%d selected files", msg.count)
+		m.previewRef = ""
+		m.previewSig = git.SigNone
+		m.keys.BrowseCommit.SetEnabled(false)
+		m.previewFile = ""
+		m.previewContent = ui.DegradeColors(msg.content, m.colorProfile)
+		m.viewport.GotoTop()
+		m.showPreview = true
+		m.updateComponentSizes()
+		m.setFocus(PanePreview)
+		return m, nil
+
+	case gitDiffStatMsg:
+		// Stats are only an annotation, so a failure just leaves them off
+		if msg.err == nil {
+			m.stagedStats = msg.staged
+			m.unstagedTotals = git.SumDiffStats(msg.unstaged)
 		}
-		m.viewport.SetContent(m.previewContent)
 		return m, nil
 
 	case gitCommitMsg:
 		if msg.err != nil {
+			m.macro = nil
 			m.err = fmt.Sprintf("Commit failed: %v", msg.err)
 			return m, m.clearError()
 		}
@@ -150,13 +581,94 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		m.state = StateFileList
 		m.commitMessage = ""
 		m.commitDate = ""
-		return m, tea.Batch(m.refreshStatus(), m.clearStatus())
+		m.commitPaths = nil
+		// Offer to review the commit that just landed
+		m.keys.ViewCommit.SetEnabled(true)
+		return m, tea.Batch(m.refreshStatus(), m.nextMacroStep(), m.clearStatus())
+
+	case gitPushMsg:
+		m.processing = false
+		if msg.err != nil {
+			m.macro = nil
+			m.err = msg.err.Error()
+			return m, m.clearError()
+		}
+		m.status = "Pushed"
+		if m.gitStatus.Upstream != "" {
+			m.status = "Pushed to " + m.gitStatus.Upstream
+		}
+		return m, tea.Batch(m.refreshStatus(), m.nextMacroStep(), m.clearStatus())
+
+	case fileCommitReadyMsg:
+		m.processing = false
+		if msg.err != nil {
+			m.err = msg.err.Error()
+			return m, m.clearError()
+		}
+		cmd := m.enterCommitMode()
+		m.commitPaths = msg.paths
+		return m, tea.Batch(cmd, m.refreshStatus())
 
 	case gitHeadInfoMsg:
+		m.processing = false
 		m.headInfo = msg.info
+		m.reverting = msg.reverting
+		m.rebasing = msg.rebasing
 		return m, nil
 
+	case redateCheckMsg:
+		m.processing = false
+		if msg.err != nil {
+			m.err = msg.err.Error()
+			return m, m.clearError()
+		}
+		prompt := fmt.Sprintf("Set the date of %s to %s? It and every later commit are rewritten", msg.summary, msg.date)
+		if msg.pushed {
+			prompt += ". It is already pushed, so this rewrites published history"
+		}
+		m.askDestructive(prompt, func(m *Model) tea.Cmd {
+			m.cancelRedate()
+			return m.runOperation("git rebase", m.redateCmd(msg.ref, msg.date, msg.summary, false))
+		})
+		return m, nil
+
+	case redateMsg:
+		m.processing = false
+		m.cancelModifyHead()
+		switch {
+		case msg.err != nil:
+			m.err = msg.err.Error()
+			return m, m.clearError()
+		case msg.aborted:
+			m.status = "Rebase aborted"
+		default:
+			m.status = fmt.Sprintf("Redated %s", msg.summary)
+		}
+		m.diffCache = make(map[string]string)
+		return m, tea.Batch(m.refreshStatus(), m.clearStatus())
+
+	case revertMsg:
+		m.processing = false
+		m.cancelModifyHead()
+		switch {
+		case errors.Is(msg.err, git.ErrConflicts):
+			// The conflicted files show up in the list to be resolved
+			m.err = fmt.Sprintf("Revert of %s stopped on conflicts; resolve them and commit, or abort it from modify HEAD", msg.ref)
+			return m, tea.Batch(m.refreshStatus(), m.clearError())
+		case msg.err != nil:
+			m.err = msg.err.Error()
+			return m, m.clearError()
+		case msg.aborted:
+			m.status = "Revert aborted"
+		default:
+			m.status = fmt.Sprintf("Reverted %s", msg.ref)
+			m.keys.ViewCommit.SetEnabled(true)
+		}
+		m.diffCache = make(map[string]string)
+		return m, tea.Batch(m.refreshStatus(), m.clearStatus())
+
 	case gitAmendMsg:
+		m.processing = false
 		if msg.err != nil {
 			m.err = fmt.Sprintf("Amendment failed: %v", msg.err)
 			return m, m.clearError()
@@ -167,72 +679,419 @@ func (m Model) Update(msg tea.Msg) (tea.Model, tea.Cmd) {
 		return m, tea.Batch(m.refreshStatus(), m.clearStatus())
 	}
 
-	// Handle list updates
-	var cmd tea.Cmd
-	m.list, cmd = m.list.Update(msg)
+	// Handle list updates
+	var cmd tea.Cmd
+	m.list, cmd = m.list.Update(msg)
+
+	// If list index changed and preview is shown, fetch new diff
+	if m.showPreview && m.ready && m.state == StateFileList {
+		currentIndex := m.cursorIndex()
+		if currentIndex >= 0 && currentIndex != m.lastFileIndex {
+			m.lastFileIndex = currentIndex
+			currentFile := m.getCurrentFile()
+			if currentFile != nil {
+				// Clear old content and fetch new diff
+				m.previewContent = ""
+				return m, m.fetchDiffCmd(*currentFile)
+			}
+		}
+	}
+
+	// Handle viewport updates
+	_, vpCmd := m.viewport.Update(msg)
+
+	return m, tea.Batch(cmd, vpCmd)
+}
+
+// handleKeyMsg handles key messages
+func (m Model) handleKeyMsg(msg tea.KeyMsg) (Model, tea.Cmd) {
+	// Any key dismisses the key hints and cancels pending ones
+	mode := m.keyHintsSignature()
+	m.showKeyHints = false
+	m.keyHintsID++
+
+	m, cmd := m.dispatchKeyMsg(msg)
+
+	// Offer the keys of a newly entered mode if the user pauses there
+	if next := m.keyHintsSignature(); next != "" && next != mode {
+		cmd = tea.Batch(cmd, m.scheduleKeyHints())
+	}
+
+	// Catch up on a refresh held back while typing
+	if m.refreshPending && !m.isTyping() {
+		return m, tea.Batch(cmd, m.refreshStatus())
+	}
+	return m, cmd
+}
+
+// dispatchKeyMsg routes a key to the handler for the current state
+func (m Model) dispatchKeyMsg(msg tea.KeyMsg) (Model, tea.Cmd) {
+	// A pending confirmation takes every key until it's answered
+	if m.confirm != nil {
+		return m.handleConfirmKeys(msg)
+	}
+
+	switch m.state {
+	case StateFileList:
+		return m.handleFileListKeys(msg)
+	case StateCommitMessage, StateCommitDate:
+		return m.handleCommitKeys(msg)
+	case StateModifyHead:
+		return m.handleModifyHeadKeys(msg)
+	case StateHelp:
+		return m.handleHelpKeys(msg)
+	case StateApplyPatch:
+		return m.handleApplyPatchKeys(msg)
+	case StatePalette:
+		return m.handlePaletteKeys(msg)
+	case StateRestoreRef:
+		return m.handleRestoreRefKeys(msg)
+	case StateReview:
+		return m.handleReviewKeys(msg)
+	case StatePatchMode:
+		return m.handlePatchModeKeys(msg)
+	case StateCommitFiles:
+		return m.handleCommitFilesKeys(msg)
+	case StateFlaggedFiles:
+		return m.handleFlaggedFilesKeys(msg)
+	case StateExportPatch:
+		return m.handleExportPatchKeys(msg)
+	case StateDiff:
+		return m.handleDiffViewKeys(msg)
+	default:
+		return m.handleFileListKeys(msg)
+	}
+}
+
+// handleApplyPatchKeys handles keys in the patch file prompt. The first
+// confirm checks the patch, the second applies it
+func (m Model) handleApplyPatchKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Confirm):
+		path := expandHome(strings.TrimSpace(m.patchInput.Value()))
+		if path == "" {
+			return m, nil
+		}
+		if path == m.patchChecked {
+			return m, m.runOperation("git apply", m.applyPatchCmd(path, m.patchCached))
+		}
+		return m, m.runOperation("git apply --check", m.checkPatchCmd(path, m.patchCached))
+
+	case key.Matches(msg, m.keys.ToggleCached):
+		// The check only holds for the target it ran against
+		m.patchCached = !m.patchCached
+		m.patchCheck = ""
+		m.patchChecked = ""
+		return m, nil
+
+	case key.Matches(msg, m.keys.Cancel):
+		m.cancelApplyPatch()
+		return m, nil
+
+	default:
+		var cmd tea.Cmd
+		previous := m.patchInput.Value()
+		m.patchInput, cmd = m.patchInput.Update(msg)
+		if m.patchInput.Value() != previous {
+			m.patchCheck = ""
+			m.patchChecked = ""
+		}
+		return m, cmd
+	}
+}
+
+// handleReviewKeys scrolls or closes the staged patch review
+func (m Model) handleReviewKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
+	if key.Matches(msg, m.keys.Close) {
+		m.closeReview()
+		return m, nil
+	}
+	var cmd tea.Cmd
+	m.reviewView, cmd = m.reviewView.Update(msg)
+	return m, cmd
+}
+
+// handleRestoreRefKeys handles keys in the restore ref prompt. Entering a
+// ref asks for confirmation, since the files' changes are lost
+func (m Model) handleRestoreRefKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Confirm):
+		ref := strings.TrimSpace(m.restoreInput.Value())
+		if ref == "" {
+			return m, nil
+		}
+		paths := m.restorePaths
+		what := paths[0]
+		if len(paths) > 1 {
+			what = fmt.Sprintf("%d files", len(paths))
+		}
+		m.askDestructive(fmt.Sprintf("Restore %s to its state at %s? Uncommitted changes are lost", what, ref), func(m *Model) tea.Cmd {
+			m.cancelRestoreRef()
+			return m.runOperation("git checkout", m.restoreFromRefCmd(ref, paths))
+		})
+		return m, nil
+
+	case key.Matches(msg, m.keys.Cancel):
+		m.cancelRestoreRef()
+		return m, nil
+
+	default:
+		var cmd tea.Cmd
+		m.restoreInput, cmd = m.restoreInput.Update(msg)
+		return m, cmd
+	}
+}
+
+// expandHome expands a leading "~/" to the user's home directory
+func expandHome(path string) string {
+	if !strings.HasPrefix(path, "~/") {
+		return path
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return path
+	}
+	return filepath.Join(home, path[2:])
+}
+
+// handleErrorKeys scrolls or dismisses the error view
+func (m Model) handleErrorKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Quit):
+		return m, tea.Quit
+
+	case key.Matches(msg, m.keys.Dismiss):
+		m.err = ""
+		m.errSticky = false
+		return m, nil
 
-	// If list index changed and preview is shown, fetch new diff
-	if m.showPreview && m.ready && m.state == StateFileList {
-		currentIndex := m.list.Index()
-		if currentIndex >= 0 && currentIndex != m.lastFileIndex {
-			m.lastFileIndex = currentIndex
-			currentFile := m.getCurrentFile()
-			if currentFile != nil {
-				// Clear old content and fetch new diff
-				m.previewContent = ""
-				return m, m.fetchDiffCmd(*currentFile)
-			}
-		}
+	default:
+		var cmd tea.Cmd
+		m.errorView, cmd = m.errorView.Update(msg)
+		return m, cmd
 	}
+}
 
-	// Handle viewport updates
-	_, vpCmd := m.viewport.Update(msg)
+// handleConfirmKeys answers the pending confirmation. y and n always
+// answer; Enter gives the default answer, and Esc says no when the settings
+// allow it
+func (m Model) handleConfirmKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
+	yes := key.Matches(msg, m.keys.Yes)
+	no := key.Matches(msg, m.keys.No) || (key.Matches(msg, m.keys.Cancel) && m.settings.ConfirmEscCancels)
+	if key.Matches(msg, m.keys.Confirm) {
+		yes = m.defaultsToYes()
+		no = !yes
+	}
 
-	return m, tea.Batch(cmd, vpCmd)
-}
+	switch {
+	case yes:
+		confirm := m.confirm
+		m.confirm = nil
+		return m, confirm.onConfirm(&m)
+
+	case no:
+		m.confirm = nil
+		m.status = "Cancelled"
+		return m, m.clearStatus()
 
-// handleKeyMsg handles key messages
-func (m Model) handleKeyMsg(msg tea.KeyMsg) (Model, tea.Cmd) {
-	switch m.state {
-	case StateFileList:
-		return m.handleFileListKeys(msg)
-	case StateCommitMessage, StateCommitDate:
-		return m.handleCommitKeys(msg)
-	case StateModifyHead:
-		return m.handleModifyHeadKeys(msg)
-	case StateHelp:
-		return m.handleHelpKeys(msg)
 	default:
-		return m.handleFileListKeys(msg)
+		return m, nil
 	}
 }
 
 // handleFileListKeys handles keys in the file list view
 func (m Model) handleFileListKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
+	// Let the filter prompt have every key while it's open
+	if m.list.SettingFilter() {
+		var cmd tea.Cmd
+		m.list, cmd = m.list.Update(msg)
+		return m, tea.Batch(cmd, m.reloadPreview())
+	}
+
+	// While the preview has focus, navigation moves the diff cursor
+	if m.previewHasFocus() {
+		if handled, cmd := m.handlePreviewKeys(msg); handled {
+			return m, cmd
+		}
+	}
+
 	switch {
 	case key.Matches(msg, m.keys.Quit):
+		// Optionally catch a habitual q with work staged; ctrl+c never asks
+		if n := m.gitStatus.StagedCount(); m.settings.ConfirmQuit && n > 0 && msg.String() != "ctrl+c" {
+			files := "files"
+			if n == 1 {
+				files = "file"
+			}
+			m.askConfirm(fmt.Sprintf("You have %d staged %s not yet committed. Quit anyway?", n, files), func(m *Model) tea.Cmd {
+				return tea.Quit
+			})
+			return m, nil
+		}
 		return m, tea.Quit
 
 	case key.Matches(msg, m.keys.Select):
 		// Toggle selection of current item
-		m.toggleSelection(m.list.Index())
+		m.toggleSelection(m.cursorIndex())
 		return m, nil
 
 	case key.Matches(msg, m.keys.SelectAll):
-		m.selectAll()
+		n := m.selectAll()
+		if m.list.IsFiltered() {
+			m.status = fmt.Sprintf("Selected %d of %d files (filtered)", n, len(m.files))
+			return m, m.clearStatus()
+		}
 		return m, nil
 
 	case key.Matches(msg, m.keys.Deselect):
-		m.deselectAll()
+		n := m.deselectAll()
+		if m.list.IsFiltered() {
+			m.status = fmt.Sprintf("Deselected %d of %d files (filtered)", n, len(m.files))
+			return m, m.clearStatus()
+		}
 		return m, nil
 
-	case key.Matches(msg, m.keys.TogglePreview):
-		// Toggle focus between list and preview
+	case key.Matches(msg, m.keys.Search):
+		var cmd tea.Cmd
+		m.list, cmd = m.list.Update(msg)
+		return m, cmd
+
+	case m.list.IsFiltered() && key.Matches(msg, m.keys.Cancel):
+		m.list.ResetFilter()
+		m.status = "Filter cleared"
+		return m, tea.Batch(m.reloadPreview(), m.clearStatus())
+
+	case key.Matches(msg, m.keys.FocusPreview):
+		// Toggle focus between list and preview. Unfocusing is always allowed,
+		// focusing only when the preview pane is actually visible
+		if m.previewFocused() {
+			m.setFocus(PaneList)
+		} else if m.showPreview && m.layout.HasPreviewPane() {
+			m.setFocus(PanePreview)
+		}
+		return m, nil
+
+	case key.Matches(msg, m.keys.FocusRight):
+		// Only reached from the list; the preview handles its own moves
 		if m.showPreview && m.layout.HasPreviewPane() {
-			m.previewFocused = !m.previewFocused
+			m.setFocus(PanePreview)
 		}
 		return m, nil
 
+	case key.Matches(msg, m.keys.TogglePreview):
+		// Show or hide the preview pane entirely. Navigation doesn't fetch
+		// diffs while hidden, so refresh the preview when it comes back
+		m.togglePreview()
+		return m, m.reloadPreview()
+
+	case key.Matches(msg, m.keys.MaximizePreview):
+		// Unlike focusing, list navigation keeps working, so files can be
+		// reviewed one after another at full width
+		m.maximizePreview = !m.maximizePreview
+		var cmd tea.Cmd
+		if m.maximizePreview && !m.showPreview {
+			m.togglePreview()
+			cmd = m.reloadPreview()
+		}
+		m.updateComponentSizes()
+		if m.maximizePreview {
+			m.status = "Preview maximized"
+		} else {
+			m.status = "Preview restored"
+		}
+		return m, tea.Batch(cmd, m.clearStatus())
+
+	case key.Matches(msg, m.keys.IgnoreWhitespace):
+		m.diffOptions.IgnoreWhitespace = !m.diffOptions.IgnoreWhitespace
+		m.status = fmt.Sprintf("Ignore whitespace: %s", onOff(m.diffOptions.IgnoreWhitespace))
+		return m, tea.Batch(m.reloadPreview(), m.clearStatus())
+
+	case key.Matches(msg, m.keys.HighlightWhitespace):
+		m.diffOptions.HighlightWhitespace = !m.diffOptions.HighlightWhitespace
+		m.status = fmt.Sprintf("Highlight whitespace errors: %s", onOff(m.diffOptions.HighlightWhitespace))
+		return m, tea.Batch(m.reloadPreview(), m.clearStatus())
+
+	case key.Matches(msg, m.keys.WrapLines):
+		m.wrapPreview = !m.wrapPreview
+		m.renderPreviewContent()
+		m.status = fmt.Sprintf("Wrap lines: %s", onOff(m.wrapPreview))
+		return m, m.clearStatus()
+
+	case key.Matches(msg, m.keys.SplitPreview):
+		m.splitPreview = !m.splitPreview
+		if !m.splitPreview {
+			m.status = "Side-by-side preview off"
+			return m, m.clearStatus()
+		}
+		m.status = "Side-by-side preview on for partially staged files"
+		return m, tea.Batch(m.splitDiffCmd(), m.clearStatus())
+
+	case key.Matches(msg, m.keys.ExpandContext):
+		return m, m.expandContext(contextStep)
+
+	case key.Matches(msg, m.keys.ResetContext):
+		return m, m.expandContext(0)
+
+	case key.Matches(msg, m.keys.HideContext):
+		m.diffOptions.HideContext = !m.diffOptions.HideContext
+		m.status = fmt.Sprintf("Changed lines only: %s", onOff(m.diffOptions.HideContext))
+		return m, tea.Batch(m.reloadPreview(), m.clearStatus())
+
+	case key.Matches(msg, m.keys.DiffAlgorithm):
+		m.diffOptions.Algorithm = m.diffOptions.Algorithm.Next()
+		m.status = fmt.Sprintf("Diff algorithm: %s", m.diffOptions.Algorithm)
+		return m, tea.Batch(m.reloadPreview(), m.clearStatus())
+
+	case key.Matches(msg, m.keys.RenameDetection):
+		m.diffOptions.Renames = m.diffOptions.Renames.Next()
+		m.status = fmt.Sprintf("Rename detection: %s", m.diffOptions.Renames)
+		return m, tea.Batch(m.reloadPreview(), m.clearStatus())
+
+	case key.Matches(msg, m.keys.UntrackedMode):
+		m.untrackedMode = m.untrackedMode.Next()
+		m.status = fmt.Sprintf("Untracked files: %s", m.untrackedMode)
+		return m, tea.Batch(m.refreshStatus(), m.clearStatus())
+
+	case key.Matches(msg, m.keys.RelativePaths):
+		m.delegate.cwdRelative = !m.delegate.cwdRelative
+		m.settings.CwdRelativePaths = m.delegate.cwdRelative
+		if m.delegate.cwdRelative {
+			m.status = "Paths relative to the current directory"
+		} else {
+			m.status = "Paths relative to the repository root"
+		}
+		if err := m.settings.save(); err != nil {
+			m.err = err.Error()
+			return m, tea.Batch(m.clearStatus(), m.clearError())
+		}
+		return m, m.clearStatus()
+
+	case key.Matches(msg, m.keys.SkipSubmodules):
+		m.settings.SkipSubmodules = !m.settings.SkipSubmodules
+		if m.settings.SkipSubmodules {
+			m.status = "Staging several files leaves submodules out"
+		} else {
+			m.status = "Staging several files includes submodules"
+		}
+		if err := m.settings.save(); err != nil {
+			m.err = err.Error()
+			return m, tea.Batch(m.clearStatus(), m.clearError())
+		}
+		return m, m.clearStatus()
+
+	case key.Matches(msg, m.keys.LoadFullPreview):
+		currentFile := m.getCurrentFile()
+		if m.previewHidden == 0 || currentFile == nil {
+			return m, nil
+		}
+		m.previewFull = currentFile.Path
+		return m, m.reloadPreview()
+
+	case key.Matches(msg, m.keys.WordDiff):
+		m.diffOptions.WordDiff = !m.diffOptions.WordDiff
+		m.status = fmt.Sprintf("Word diff: %s", onOff(m.diffOptions.WordDiff))
+		return m, tea.Batch(m.reloadPreview(), m.clearStatus())
+
 	case key.Matches(msg, m.keys.ToggleHelp):
 		if m.state == StateFileList {
 			m.state = StateHelp
@@ -241,16 +1100,26 @@ func (m Model) handleFileListKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
 		}
 		return m, nil
 
-	case key.Matches(msg, m.keys.Up):
-		// If preview is focused, scroll up; otherwise navigate list
-		if m.previewFocused && m.viewport.Height < len(strings.Split(m.previewContent, "\n")) {
-			m.viewport.LineUp(3)
+	case key.Matches(msg, m.keys.NextSection), key.Matches(msg, m.keys.PrevSection):
+		index := m.nextSection(key.Matches(msg, m.keys.NextSection))
+		if index < 0 {
 			return m, nil
 		}
+		return m, m.jumpTo(index)
+
+	case key.Matches(msg, m.keys.NextConflict):
+		index := m.nextWithStatus(git.StatusConflicted)
+		if index < 0 {
+			m.status = "No conflicted files"
+			return m, m.clearStatus()
+		}
+		return m, m.jumpTo(index)
+
+	case key.Matches(msg, m.keys.Up):
 		// Let list handle navigation and fetch new diff if selection changed
 		var cmd tea.Cmd
 		m.list, cmd = m.list.Update(msg)
-		currentIndex := m.list.Index()
+		currentIndex := m.cursorIndex()
 		if m.showPreview && currentIndex >= 0 && currentIndex != m.lastFileIndex {
 			m.lastFileIndex = currentIndex
 			if currentFile := m.getCurrentFile(); currentFile != nil {
@@ -261,15 +1130,10 @@ func (m Model) handleFileListKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
 		return m, cmd
 
 	case key.Matches(msg, m.keys.Down):
-		// If preview is focused, scroll down; otherwise navigate list
-		if m.previewFocused && m.viewport.Height < len(strings.Split(m.previewContent, "\n")) {
-			m.viewport.LineDown(3)
-			return m, nil
-		}
 		// Let list handle navigation and fetch new diff if selection changed
 		var cmd tea.Cmd
 		m.list, cmd = m.list.Update(msg)
-		currentIndex := m.list.Index()
+		currentIndex := m.cursorIndex()
 		if m.showPreview && currentIndex >= 0 && currentIndex != m.lastFileIndex {
 			m.lastFileIndex = currentIndex
 			if currentFile := m.getCurrentFile(); currentFile != nil {
@@ -283,7 +1147,7 @@ func (m Model) handleFileListKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
 		// Let list handle Home key and fetch diff if selection changed
 		var cmd tea.Cmd
 		m.list, cmd = m.list.Update(msg)
-		currentIndex := m.list.Index()
+		currentIndex := m.cursorIndex()
 		if m.showPreview && currentIndex >= 0 && currentIndex != m.lastFileIndex {
 			m.lastFileIndex = currentIndex
 			if currentFile := m.getCurrentFile(); currentFile != nil {
@@ -297,7 +1161,7 @@ func (m Model) handleFileListKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
 		// Let list handle End key and fetch diff if selection changed
 		var cmd tea.Cmd
 		m.list, cmd = m.list.Update(msg)
-		currentIndex := m.list.Index()
+		currentIndex := m.cursorIndex()
 		if m.showPreview && currentIndex >= 0 && currentIndex != m.lastFileIndex {
 			m.lastFileIndex = currentIndex
 			if currentFile := m.getCurrentFile(); currentFile != nil {
@@ -309,31 +1173,307 @@ func (m Model) handleFileListKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
 
 	case key.Matches(msg, m.keys.Apply):
 		selected := m.getSelectedFiles()
+		if len(selected) == 0 && m.settings.EnterCommits && m.gitStatus.StagedCount() > 0 {
+			return m, m.enterCommitMode()
+		}
 		if len(selected) == 0 {
 			m.status = "No files selected"
 			return m, m.clearStatus()
 		}
-		m.status = fmt.Sprintf("Processing %d file(s)...", len(selected))
+		m.holdStatus(fmt.Sprintf("Processing %d file(s)...", len(selected)))
 		return m, m.applySelection()
 
+	case key.Matches(msg, m.keys.StageFile):
+		// Stage just the file under the cursor, ignoring checkboxes
+		currentFile := m.getCurrentFile()
+		if currentFile == nil {
+			return m, nil
+		}
+		if currentFile.Status == git.StatusStaged {
+			m.status = "File is already staged"
+			return m, m.clearStatus()
+		}
+		// Then show what landed in the index
+		m.followPath = currentFile.Path
+		return m, m.runOperation("git add", m.stageFilesCmd([]git.FileItem{*currentFile}))
+
+	case key.Matches(msg, m.keys.StageTracked):
+		if m.gitStatus.ConflictedCount() > 0 {
+			// add -u would mark conflicted files resolved, markers and all
+			m.status = "Resolve conflicts before staging all modified files"
+			return m, m.clearStatus()
+		}
+		if m.gitStatus.UnstagedCount() == 0 {
+			m.status = "No modified files to stage"
+			return m, m.clearStatus()
+		}
+		return m, m.runOperation("git add -u", m.stageTrackedCmd())
+
+	case key.Matches(msg, m.keys.UnstageFile):
+		// Unstage just the file under the cursor, ignoring checkboxes
+		currentFile := m.getCurrentFile()
+		if currentFile == nil {
+			return m, nil
+		}
+		if currentFile.Status != git.StatusStaged {
+			m.status = "File is not staged"
+			return m, m.clearStatus()
+		}
+		return m, m.runOperation("git reset", m.unstageFilesCmd([]git.FileItem{*currentFile}))
+
+	case key.Matches(msg, m.keys.IntentToAdd):
+		// Acts on the selection, or the file under the cursor without one
+		files := m.getSelectedFiles()
+		if len(files) == 0 {
+			if currentFile := m.getCurrentFile(); currentFile != nil {
+				files = []git.FileItem{*currentFile}
+			}
+		}
+		if len(files) == 0 {
+			return m, nil
+		}
+		return m, m.runOperation("git add -N", m.intentToAddCmd(files))
+
+	case key.Matches(msg, m.keys.TakeOurs), key.Matches(msg, m.keys.TakeTheirs):
+		currentFile := m.getCurrentFile()
+		if currentFile == nil || currentFile.Status != git.StatusConflicted {
+			m.status = "File is not conflicted"
+			return m, m.clearStatus()
+		}
+		file := currentFile.Path
+		ours := key.Matches(msg, m.keys.TakeOurs)
+		side := "theirs"
+		if ours {
+			side = "ours"
+		}
+		m.askDestructive(fmt.Sprintf("Resolve %s using %s? Other changes to it are lost", file, side), func(m *Model) tea.Cmd {
+			return m.runOperation("git checkout --"+side, m.resolveConflictCmd(file, ours))
+		})
+		return m, nil
+
 	case key.Matches(msg, m.keys.Commit):
 		if m.gitStatus.StagedCount() == 0 {
 			m.status = "No files staged"
 			return m, m.clearStatus()
 		}
-		m.enterCommitMode()
+		return m, m.enterCommitMode()
+
+	case key.Matches(msg, m.keys.HideChanges):
+		currentFile := m.getCurrentFile()
+		if currentFile == nil {
+			return m, nil
+		}
+		if currentFile.Status != git.StatusUnstaged {
+			m.status = "Only changes to tracked files can be hidden"
+			return m, m.clearStatus()
+		}
+		return m, m.runOperation("git update-index", m.setFlagCmd(currentFile.Path, false, true))
+
+	case key.Matches(msg, m.keys.FullscreenDiff):
+		currentFile := m.getCurrentFile()
+		if currentFile == nil {
+			return m, nil
+		}
+		return m, m.runOperation("git diff", m.diffViewCmd(*currentFile))
+
+	case key.Matches(msg, m.keys.ExportPatch):
+		if len(m.getSelectedFiles()) == 0 && m.gitStatus.StagedCount() == 0 {
+			m.status = "Select files or stage changes to export"
+			return m, m.clearStatus()
+		}
+		m.enterExportPatchMode()
+		return m, nil
+
+	case key.Matches(msg, m.keys.FlaggedFiles):
+		return m, m.runOperation("git ls-files", m.flaggedFilesCmd(false))
+
+	case key.Matches(msg, m.keys.Macro):
+		return m, m.startMacro()
+
+	case key.Matches(msg, m.keys.ViewCommit):
+		m.keys.ViewCommit.SetEnabled(false)
+		return m, m.showCommitCmd("HEAD")
+
+	case key.Matches(msg, m.keys.ShowHead):
+		return m, m.showCommitCmd("HEAD")
+
+	case key.Matches(msg, m.keys.SelectionDiff):
+		selected := m.getSelectedFiles()
+		if len(selected) == 0 {
+			m.status = "No files selected"
+			return m, m.clearStatus()
+		}
+		return m, m.runOperation("git diff", m.selectionDiffCmd(selected))
+
+	case key.Matches(msg, m.keys.Retry):
+		if !m.retryable {
+			m.status = "No failed operation to retry"
+			return m, m.clearStatus()
+		}
+		op := m.lastOperation
+		m.err = ""
+		m.holdStatus("Retrying " + op.label)
+		return m, m.runOperation(op.label, op.cmd)
+
+	case key.Matches(msg, m.keys.BrowseCommit):
+		// Review the previewed commit file by file rather than as one patch
+		if m.previewTitle == "" || m.previewRef == "" {
+			return m, nil
+		}
+		return m, m.runOperation("git diff-tree", m.commitFilesCmd(m.previewRef))
+
+	case key.Matches(msg, m.keys.Palette):
+		return m, m.openPalette()
+
+	case key.Matches(msg, m.keys.CopyPath):
+		return m, m.copyCurrentPath()
+
+	case key.Matches(msg, m.keys.OpenPager):
+		currentFile := m.getCurrentFile()
+		if currentFile == nil {
+			return m, nil
+		}
+		if strings.HasSuffix(currentFile.Path, "/") {
+			m.status = "Untracked directories have no diff to page"
+			return m, m.clearStatus()
+		}
+		return m, m.pagerCmd(*currentFile)
+
+	case key.Matches(msg, m.keys.OpenWeb):
+		// Open the commit being previewed, otherwise the file under the cursor
+		if m.previewTitle != "" && m.previewRef != "" {
+			return m, m.openWebCmd("", m.previewRef)
+		}
+		currentFile := m.getCurrentFile()
+		if currentFile == nil {
+			return m, nil
+		}
+		return m, m.openWebCmd(currentFile.Path, "")
+
+	case key.Matches(msg, m.keys.CommitFile):
+		// Stage and commit only the checked files, or the one under the
+		// cursor, leaving anything else in the index staged
+		if selected := m.getSelectedFiles(); len(selected) > 0 {
+			return m, m.runOperation("git add", m.prepareFileCommitCmd(selected...))
+		}
+		currentFile := m.getCurrentFile()
+		if currentFile == nil {
+			return m, nil
+		}
+		return m, m.runOperation("git add", m.prepareFileCommitCmd(*currentFile))
+
+	case key.Matches(msg, m.keys.ApplyPatch):
+		m.enterApplyPatchMode()
+		return m, nil
+
+	case key.Matches(msg, m.keys.ReviewStaged):
+		return m, m.reviewStaged()
+
+	case key.Matches(msg, m.keys.PatchMode):
+		if m.gitStatus.UnstagedCount() == 0 {
+			m.status = "No unstaged changes to pick hunks from"
+			return m, m.clearStatus()
+		}
+		return m, m.runOperation("git diff", m.loadPatchModeCmd(m.files))
+
+	case key.Matches(msg, m.keys.RestoreFromRef):
+		// Restore the checked files, or the one under the cursor
+		var paths []string
+		seen := make(map[string]bool)
+		for _, f := range m.getSelectedFiles() {
+			if !seen[f.Path] {
+				seen[f.Path] = true
+				paths = append(paths, f.Path)
+			}
+		}
+		if len(paths) == 0 {
+			currentFile := m.getCurrentFile()
+			if currentFile == nil {
+				return m, nil
+			}
+			paths = []string{currentFile.Path}
+		}
+		m.enterRestoreRefMode(paths)
 		return m, nil
 
 	case key.Matches(msg, m.keys.ModifyHead):
 		m.enterModifyHeadMode()
-		m.processing = true
-		return m, m.fetchHeadInfo()
+		return m, m.runOperation("git log", m.fetchHeadInfo())
 
 	default:
 		return m, nil
 	}
 }
 
+// handlePreviewKeys handles keys while the preview pane has focus, reporting
+// whether the key was consumed
+func (m *Model) handlePreviewKeys(msg tea.KeyMsg) (bool, tea.Cmd) {
+	// The split diffs only scroll; hunks are staged from the normal preview
+	if m.splitActive() {
+		switch {
+		case key.Matches(msg, m.keys.Up):
+			m.scrollSplit(-1)
+			return true, nil
+		case key.Matches(msg, m.keys.Down):
+			m.scrollSplit(1)
+			return true, nil
+		case key.Matches(msg, m.keys.FocusLeft):
+			if m.focus == PaneSplitUnstaged {
+				m.focus = PanePreview
+			} else {
+				m.setFocus(PaneList)
+			}
+			return true, nil
+		case key.Matches(msg, m.keys.FocusRight):
+			m.focus = PaneSplitUnstaged
+			return true, nil
+		case key.Matches(msg, m.keys.SelectLines), key.Matches(msg, m.keys.StageHunk), key.Matches(msg, m.keys.DiscardHunk):
+			m.status = "Turn off the side-by-side preview to stage or discard hunks"
+			return true, m.clearStatus()
+		}
+	}
+
+	switch {
+	case key.Matches(msg, m.keys.Up):
+		m.moveDiffCursor(-1)
+		return true, nil
+
+	case key.Matches(msg, m.keys.Down):
+		m.moveDiffCursor(1)
+		return true, nil
+
+	case key.Matches(msg, m.keys.SelectLines):
+		// Start a line selection at the cursor, or drop the current one
+		if m.diffAnchor >= 0 {
+			m.diffAnchor = -1
+		} else {
+			m.diffAnchor = m.diffCursor
+		}
+		m.renderPreviewContent()
+		return true, nil
+
+	case key.Matches(msg, m.keys.StageHunk):
+		return true, m.stageSelection()
+
+	case key.Matches(msg, m.keys.DiscardHunk):
+		return true, m.discardSelection()
+
+	case key.Matches(msg, m.keys.Blame):
+		return true, m.blameCursorLine()
+
+	case key.Matches(msg, m.keys.FocusLeft):
+		m.setFocus(PaneList)
+		return true, nil
+
+	case key.Matches(msg, m.keys.FocusRight):
+		// Nothing further right of a single preview
+		return true, nil
+
+	default:
+		return false, nil
+	}
+}
+
 // handleCommitKeys handles keys during commit input
 func (m Model) handleCommitKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
 	switch m.commitState {
@@ -348,8 +1488,8 @@ func (m Model) handleCommitKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
 
 // handleCommitMessageKeys handles keys for commit message input
 func (m Model) handleCommitMessageKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
-	switch msg.String() {
-	case "ctrl+d":
+	switch {
+	case key.Matches(msg, m.keys.Continue):
 		// Proceed to date input
 		m.commitMessage = m.commitTextarea.Value()
 		if m.commitMessage == "" {
@@ -359,7 +1499,14 @@ func (m Model) handleCommitMessageKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
 		m.proceedToDateInput()
 		return m, nil
 
-	case "esc":
+	case key.Matches(msg, m.keys.OpenEditor):
+		// Write the message in a full editor instead
+		return m, m.editMessageCmd(m.commitTextarea.Value())
+
+	case key.Matches(msg, m.keys.ReviewStaged):
+		return m, m.reviewStaged()
+
+	case key.Matches(msg, m.keys.Cancel):
 		// Cancel commit
 		m.cancelCommit()
 		return m, nil
@@ -374,15 +1521,15 @@ func (m Model) handleCommitMessageKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
 
 // handleCommitDateKeys handles keys for commit date input
 func (m Model) handleCommitDateKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
-	switch msg.String() {
-	case "enter":
+	switch {
+	case key.Matches(msg, m.keys.Confirm):
 		// Proceed to commit
 		m.commitDate = m.commitInput.Value()
 		m.commitInput.Blur()
 		m.commitTextarea.Blur()
-		return m, m.commitCmd(m.commitMessage, m.commitDate)
+		return m, m.commitCmd(m.commitMessage, m.commitDate, m.commitPaths)
 
-	case "esc":
+	case key.Matches(msg, m.keys.Back):
 		// Go back to message input
 		m.commitState = CommitStateMessage
 		m.commitTextarea.Focus()
@@ -400,7 +1547,7 @@ func (m Model) handleCommitDateKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
 // handleHelpKeys handles keys in the help view
 func (m Model) handleHelpKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
 	switch {
-	case key.Matches(msg, m.keys.ToggleHelp), key.Matches(msg, m.keys.Quit):
+	case key.Matches(msg, m.keys.ToggleHelp, m.keys.Close):
 		m.state = StateFileList
 		return m, nil
 	default:
@@ -413,10 +1560,10 @@ func (m Model) handleModifyHeadKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
 	switch m.headModifyState {
 	case HeadModifyStateMenu:
 		return m.handleHeadMenuKeys(msg)
-	case HeadModifyStateAmendMessage:
+	case HeadModifyStateAmend:
 		return m.handleHeadAmendMessageKeys(msg)
-	case HeadModifyStateAmendFiles:
-		return m.handleHeadAmendFilesKeys(msg)
+	case HeadModifyStateRedate:
+		return m.handleRedateKeys(msg)
 	default:
 		return m, nil
 	}
@@ -424,18 +1571,60 @@ func (m Model) handleModifyHeadKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
 
 // handleHeadMenuKeys handles keys in the HEAD modify menu
 func (m Model) handleHeadMenuKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
-	switch msg.String() {
-	case "m":
-		// Amend commit message
-		m.enterAmendMessageMode()
+	switch {
+	case key.Matches(msg, m.keys.Amend):
+		// Amend the message and staged files together
+		m.enterAmendMode()
+		return m, nil
+
+	case key.Matches(msg, m.keys.SoftReset):
+		// Spell out what a soft reset does before undoing the commit
+		prompt := "Undo the last commit? Its changes become staged; the working tree is untouched"
+		if m.headInfo != nil {
+			prompt = fmt.Sprintf("Undo commit %s %q? Its changes become staged; the working tree is untouched",
+				m.headInfo.ShortHash, m.headInfo.Message)
+			if m.headInfo.IsRoot {
+				prompt += ". It is the first commit, so the branch is left with no commits"
+			}
+			if m.headInfo.IsPushed {
+				prompt += ". It is already pushed, so this rewrites published history"
+			}
+		}
+		m.askDestructive(prompt, func(m *Model) tea.Cmd {
+			return m.runOperation("git reset --soft", m.softResetHeadCmd())
+		})
+		return m, nil
+
+	case key.Matches(msg, m.keys.Revert):
+		if m.reverting {
+			m.askDestructive("Abort the revert in progress? Conflict resolutions so far are lost", func(m *Model) tea.Cmd {
+				return m.runOperation("git revert --abort", m.revertCmd("", true))
+			})
+			return m, nil
+		}
+		if m.headInfo == nil {
+			return m, nil
+		}
+		// Reverting adds a commit rather than rewriting one, so it's safe
+		// for pushed history
+		ref := m.headInfo.ShortHash
+		prompt := fmt.Sprintf("Revert commit %s %q? A new commit undoing it is created", ref, m.headInfo.Message)
+		m.askConfirm(prompt, func(m *Model) tea.Cmd {
+			return m.runOperation("git revert", m.revertCmd(ref, false))
+		})
 		return m, nil
 
-	case "f":
-		// Soft reset (amend files)
-		m.processing = true
-		return m, m.softResetHeadCmd()
+	case key.Matches(msg, m.keys.Redate):
+		if m.rebasing {
+			m.askDestructive("Abort the rebase in progress? The branch goes back to where it started", func(m *Model) tea.Cmd {
+				return m.runOperation("git rebase --abort", m.redateCmd("", "", "", true))
+			})
+			return m, nil
+		}
+		m.enterRedateMode()
+		return m, nil
 
-	case "esc", "q":
+	case key.Matches(msg, m.keys.Close):
 		// Cancel and return to file list
 		m.cancelModifyHead()
 		return m, nil
@@ -447,19 +1636,38 @@ func (m Model) handleHeadMenuKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
 
 // handleHeadAmendMessageKeys handles keys for commit message amendment
 func (m Model) handleHeadAmendMessageKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
-	switch msg.String() {
-	case "ctrl+d":
+	switch {
+	case key.Matches(msg, m.keys.Continue):
 		// Confirm amendment
 		newMessage := m.headMessageTextarea.Value()
 		if newMessage == "" {
 			m.err = "Commit message cannot be empty"
 			return m, m.clearError()
 		}
-		m.processing = true
-		m.headMessageTextarea.Blur()
-		return m, m.amendMessageCmd(newMessage)
+		amend := func(m *Model) tea.Cmd {
+			m.headMessageTextarea.Blur()
+			return m.runOperation("git commit --amend", m.amendCmd(newMessage))
+		}
+		// Rewriting a published commit forces everyone else to recover
+		if m.headInfo != nil && m.headInfo.IsPushed {
+			m.askDestructive("HEAD is already pushed; amending rewrites published history. Amend anyway?", amend)
+			return m, nil
+		}
+		if m.settings.ConfirmAmend {
+			prompt := "Amend HEAD with this message?"
+			if n := m.gitStatus.StagedCount(); n > 0 {
+				prompt = fmt.Sprintf("Amend HEAD with this message and %d staged file(s)?", n)
+			}
+			m.askDestructive(prompt, amend)
+			return m, nil
+		}
+		return m, amend(&m)
+
+	case key.Matches(msg, m.keys.ResetAuthorDate):
+		m.amendResetDate = !m.amendResetDate
+		return m, nil
 
-	case "esc":
+	case key.Matches(msg, m.keys.Cancel):
 		// Cancel and return to menu
 		m.headModifyState = HeadModifyStateMenu
 		m.headMessageTextarea.Blur()
@@ -474,9 +1682,46 @@ func (m Model) handleHeadAmendMessageKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
 	}
 }
 
-// handleHeadAmendFilesKeys handles keys for soft reset
-func (m Model) handleHeadAmendFilesKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
-	// Soft reset is automatic, just return to file list or show menu again
-	m.cancelModifyHead()
-	return m, nil
+// handleRedateKeys handles the redate prompt: first the commit, then its new
+// date, which is checked with the same rules as a new commit's date
+func (m Model) handleRedateKeys(msg tea.KeyMsg) (Model, tea.Cmd) {
+	switch {
+	case key.Matches(msg, m.keys.Confirm):
+		value := strings.TrimSpace(m.redateInput.Value())
+		if m.redateRef == "" {
+			if value == "" {
+				return m, nil
+			}
+			m.redateRef = value
+			m.redateInput.Placeholder = "YYYY-MM-DD [HH:MM:SS] or now"
+			m.redateInput.Reset()
+			return m, nil
+		}
+		date, err := git.ValidateCommitDate(value)
+		if err != nil {
+			m.err = err.Error()
+			return m, m.clearError()
+		}
+		if date == "" {
+			date = time.Now().Format("2006-01-02 15:04:05")
+		}
+		return m, m.runOperation("git log", m.redateCheckCmd(m.redateRef, date))
+
+	case key.Matches(msg, m.keys.Cancel):
+		m.cancelRedate()
+		return m, nil
+
+	default:
+		var cmd tea.Cmd
+		m.redateInput, cmd = m.redateInput.Update(msg)
+		return m, cmd
+	}
+}
+
+// onOff formats a toggle state for status messages
+func onOff(enabled bool) string {
+	if enabled {
+		return "on"
+	}
+	return "off"
 }
@@ -0,0 +1,161 @@
+package main
+
+import (
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/rai/interactive-git/ui"
+)
+
+// selectedPaths returns the paths of the checked files
+func selectedPaths(m Model) []string {
+	var paths []string
+	for _, f := range m.getSelectedFiles() {
+		paths = append(paths, f.Path)
+	}
+	return paths
+}
+
+// assertView fails the test unless the rendered view shows every one of want
+func assertView(t *testing.T, m Model, want ...string) {
+	t.Helper()
+	view := ui.StripColors(m.View())
+	for _, w := range want {
+		if !strings.Contains(view, w) {
+			t.Errorf("view is missing %q:\n%s", w, view)
+		}
+	}
+}
+
+func TestStageUnstageFlow(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.write("b.txt", "one\n")
+	r.commit("initial")
+	r.write("a.txt", "two\n")
+	r.write("b.txt", "two\n")
+	r.write("c.txt", "new\n")
+
+	m := openModel(t)
+	if f := m.getCurrentFile(); f == nil || f.Path != "a.txt" {
+		t.Fatalf("cursor starts on %v, want a.txt", f)
+	}
+	assertView(t, m, "[ ] - a.txt", "[ ] - b.txt", "[ ] ? c.txt", "Preview: a.txt (unstaged)")
+
+	// Check b.txt and c.txt, then stage them
+	m = press(t, m, "j", "tab", "j", "tab")
+	if got := selectedPaths(m); !slices.Equal(got, []string{"b.txt", "c.txt"}) {
+		t.Fatalf("selected %q, want [b.txt c.txt]", got)
+	}
+	assertView(t, m, "[ ] - a.txt", "[X] - b.txt", "[X] ? c.txt")
+
+	m = press(t, m, "enter")
+	if !slices.Equal(m.gitStatus.Staged, []string{"b.txt", "c.txt"}) || !slices.Equal(m.gitStatus.Unstaged, []string{"a.txt"}) {
+		t.Errorf("after staging: staged %q, unstaged %q", m.gitStatus.Staged, m.gitStatus.Unstaged)
+	}
+	if got := selectedPaths(m); len(got) != 0 {
+		t.Errorf("still selected after staging: %q", got)
+	}
+	assertView(t, m, "[ ] - a.txt", "[ ] + b.txt", "[ ] + c.txt", "Staged 2 file(s)")
+
+	// The cursor followed c.txt; check b.txt above it and unstage it
+	m = press(t, m, "k", "tab", "enter")
+	if !slices.Equal(m.gitStatus.Staged, []string{"c.txt"}) || !slices.Equal(m.gitStatus.Unstaged, []string{"a.txt", "b.txt"}) {
+		t.Errorf("after unstaging: staged %q, unstaged %q", m.gitStatus.Staged, m.gitStatus.Unstaged)
+	}
+	assertView(t, m, "[ ] - b.txt", "[ ] + c.txt", "Unstaged 1 file(s)")
+	if staged := strings.TrimSpace(r.git("diff", "--cached", "--name-only")); staged != "c.txt" {
+		t.Errorf("index holds %q, want c.txt", staged)
+	}
+}
+
+func TestCommitFlow(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.commit("initial")
+	r.write("a.txt", "two\n")
+	r.write("b.txt", "new\n")
+
+	m := openModel(t)
+	m = press(t, m, "c")
+	if m.state != StateFileList {
+		t.Fatalf("state = %v after c with nothing staged, want the file list", m.state)
+	}
+	assertView(t, m, "No files staged")
+
+	// Stage the file under the cursor and start the commit
+	m = press(t, m, "s")
+	if !slices.Equal(m.gitStatus.Staged, []string{"a.txt"}) {
+		t.Fatalf("staged %q after s, want [a.txt]", m.gitStatus.Staged)
+	}
+	m = press(t, m, "c")
+	if m.state != StateCommitMessage || m.commitState != CommitStateMessage {
+		t.Fatalf("state = %v/%v after c, want the commit message", m.state, m.commitState)
+	}
+	assertView(t, m, "Commit Staged Files", "Files to commit (+1 -1):", "+ a.txt", "Commit Message")
+
+	// An empty message isn't accepted
+	m = press(t, m, "ctrl+d")
+	if m.commitState != CommitStateMessage || m.err == "" {
+		t.Errorf("continued with an empty message: step %v, error %q", m.commitState, m.err)
+	}
+	// run drops timers, so fire the one clearing the error
+	m = update(t, m, clearErrorMsg{setAt: m.lastErrorMsg})
+
+	m = typeText(t, m, "Add feature")
+	m = press(t, m, "ctrl+d")
+	if m.commitState != CommitStateDate {
+		t.Fatalf("step = %v after continuing, want the date", m.commitState)
+	}
+	assertView(t, m, "Commit Date (Optional)")
+
+	// An empty date commits now
+	m = press(t, m, "enter")
+	if m.state != StateFileList {
+		t.Errorf("state = %v after committing, want the file list", m.state)
+	}
+	if subject := strings.TrimSpace(r.git("log", "-1", "--format=%s")); subject != "Add feature" {
+		t.Errorf("HEAD subject = %q, want Add feature", subject)
+	}
+	if len(m.gitStatus.Staged) != 0 || !slices.Equal(m.gitStatus.Untracked, []string{"b.txt"}) {
+		t.Errorf("after committing: staged %q, untracked %q", m.gitStatus.Staged, m.gitStatus.Untracked)
+	}
+	assertView(t, m, "Commit created successfully", "[ ] ? b.txt")
+}
+
+// TestPasteMultilineMessage checks a pasted message keeps its blank line in
+// the commit and amend textareas, rather than the newlines acting as keys
+func TestPasteMultilineMessage(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.commit("initial")
+	r.write("a.txt", "two\n")
+	r.git("add", "a.txt")
+
+	const message = "Subject\n\nBody of the message"
+
+	m := openModel(t)
+	m = press(t, m, "c")
+	m = typeText(t, m, message)
+	if m.state != StateCommitMessage || m.commitState != CommitStateMessage {
+		t.Errorf("state = %v/%v after pasting, want the commit message", m.state, m.commitState)
+	}
+	if got := m.commitTextarea.Value(); got != message {
+		t.Errorf("commit message = %q after pasting, want %q", got, message)
+	}
+	m = press(t, m, "esc")
+
+	m = press(t, m, "m", "m")
+	before := m.headMessageTextarea.Value()
+	m = typeText(t, m, message)
+	if m.state != StateModifyHead || m.headModifyState != HeadModifyStateAmend {
+		t.Errorf("state = %v/%v after pasting, want amending HEAD", m.state, m.headModifyState)
+	}
+	if got := m.headMessageTextarea.Value(); got != before+message {
+		t.Errorf("amend message = %q after pasting, want %q", got, before+message)
+	}
+	if subject := strings.TrimSpace(r.git("log", "-1", "--format=%s")); subject != "initial" {
+		t.Errorf("HEAD subject = %q after pasting, want it unchanged", subject)
+	}
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"fmt"
+	"runtime/debug"
+)
+
+// Build information, set with
+// -ldflags "-X main.version=v1.2.3 -X main.commit=abc1234 -X main.date=2024-01-01"
+var (
+	version = ""
+	commit  = ""
+	date    = ""
+)
+
+// versionString describes this build. Anything not set through -ldflags is
+// taken from the module and VCS information the Go toolchain embeds
+func versionString() string {
+	v, c, d := version, commit, date
+	modified := false
+
+	if info, ok := debug.ReadBuildInfo(); ok {
+		if v == "" && info.Main.Version != "" && info.Main.Version != "(devel)" {
+			v = info.Main.Version
+		}
+		for _, setting := range info.Settings {
+			switch setting.Key {
+			case "vcs.revision":
+				if c == "" {
+					c = setting.Value
+				}
+			case "vcs.time":
+				if d == "" {
+					d = setting.Value
+				}
+			case "vcs.modified":
+				modified = setting.Value == "true"
+			}
+		}
+	}
+
+	if v == "" {
+		v = "dev"
+	}
+	if len(c) > 12 {
+		c = c[:12]
+	}
+	if c == "" {
+		c = "unknown"
+	} else if modified && commit == "" {
+		c += "-dirty"
+	}
+	if d == "" {
+		d = "unknown"
+	}
+
+	return fmt.Sprintf("igit %s (commit %s, built %s)", v, c, d)
+}
@@ -4,8 +4,10 @@ import (
 	"fmt"
 	"strings"
 
+	"github.com/charmbracelet/bubbles/help"
 	"github.com/charmbracelet/lipgloss"
 
+	"github.com/rai/interactive-git/git"
 	"github.com/rai/interactive-git/ui"
 )
 
@@ -15,10 +17,36 @@ func (m Model) View() string {
 		return "Initializing..."
 	}
 
-	if m.err != "" {
+	if m.err != "" && m.errSticky {
 		return m.renderError()
 	}
 
+	view := m.renderState()
+	if m.showKeyHints {
+		// Below the header, clear of the title
+		view = ui.OverlayRight(view, m.renderKeyHints(), m.width, 4)
+	}
+	return view
+}
+
+// renderKeyHints renders the popup listing the current mode's keys
+func (m Model) renderKeyHints() string {
+	bindings := m.keyHintBindings()
+	keyWidth := 0
+	for _, b := range bindings {
+		keyWidth = max(keyWidth, lipgloss.Width(b.Help().Key))
+	}
+
+	lines := []string{ui.TitleStyle.UnsetPadding().Render("Keys")}
+	for _, b := range bindings {
+		k := lipgloss.NewStyle().Width(keyWidth).Render(b.Help().Key)
+		lines = append(lines, ui.InfoStyle.Render(k)+"  "+b.Help().Desc)
+	}
+	return ui.KeyHintsStyle.Render(strings.Join(lines, "\n"))
+}
+
+// renderState renders the view for the current state
+func (m Model) renderState() string {
 	switch m.state {
 	case StateFileList:
 		return m.renderFileList()
@@ -28,14 +56,296 @@ func (m Model) View() string {
 		return m.renderModifyHeadView()
 	case StateHelp:
 		return m.renderHelp()
+	case StateApplyPatch:
+		return m.renderApplyPatchView()
+	case StatePalette:
+		return m.renderPaletteView()
+	case StateRestoreRef:
+		return m.renderRestoreRefView()
+	case StateReview:
+		return m.renderReviewView()
+	case StatePatchMode:
+		return m.renderPatchModeView()
+	case StateCommitFiles:
+		return m.renderCommitFilesView()
+	case StateFlaggedFiles:
+		return m.renderFlaggedFilesView()
+	case StateExportPatch:
+		return m.renderExportPatchView()
+	case StateDiff:
+		return m.renderDiffView()
 	default:
 		return m.renderFileList()
 	}
 }
 
-// renderError renders the error view
+// renderReviewView renders the staged patch under review
+func (m Model) renderReviewView() string {
+	var sections []string
+
+	sections = append(sections, m.renderHeader())
+	title := "Review Staged Changes"
+	if m.reviewFrom == StateCommitMessage || m.reviewFrom == StateCommitDate {
+		title = "Review Changes to Commit"
+	}
+	sections = append(sections, "", ui.TitleStyle.Render(title), "")
+	sections = append(sections, m.reviewView.View())
+
+	content := strings.Join(sections, "\n")
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.NewStyle().Padding(0, 1).Render(content),
+		m.renderFooter(),
+	)
+}
+
+// renderDiffView renders one file's diff full screen, with the search
+// prompt below the title while it's open
+func (m Model) renderDiffView() string {
+	var sections []string
+
+	sections = append(sections, m.renderHeader())
+	prompt := ""
+	if m.diffSearch.Focused() {
+		prompt = m.diffSearch.View()
+	}
+	sections = append(sections, "", ui.TitleStyle.Render("Diff: "+m.diffViewFile), prompt)
+	sections = append(sections, m.diffView.View())
+
+	content := strings.Join(sections, "\n")
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.NewStyle().Padding(0, 1).Render(content),
+		m.renderFooter(),
+	)
+}
+
+// renderCommitFilesView renders a commit's files beside the diff of the one
+// under the cursor
+func (m Model) renderCommitFilesView() string {
+	var sections []string
+
+	sections = append(sections, m.renderHeader())
+	sections = append(sections, "", ui.TitleStyle.Render("Browse Commit "+m.commitFilesRef), "")
+	sections = append(sections, lipgloss.JoinHorizontal(lipgloss.Top,
+		m.commitFiles.View(),
+		"  ",
+		m.commitFileView.View(),
+	))
+
+	content := strings.Join(sections, "\n")
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.NewStyle().Padding(0, 1).Render(content),
+		m.renderFooter(),
+	)
+}
+
+// renderFlaggedFilesView renders the files whose changes git ignores
+func (m Model) renderFlaggedFilesView() string {
+	var sections []string
+
+	sections = append(sections, m.renderHeader())
+	sections = append(sections, "", ui.TitleStyle.Render("Files With Hidden Changes"), "")
+	sections = append(sections, m.flagged.View())
+
+	content := strings.Join(sections, "\n")
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.NewStyle().Padding(0, 1).Render(content),
+		m.renderFooter(),
+	)
+}
+
+// renderPatchModeView renders the hunk being picked in patch mode
+func (m Model) renderPatchModeView() string {
+	var sections []string
+
+	sections = append(sections, m.renderHeader())
+	p := m.patch.piece()
+	title := fmt.Sprintf("Pick Hunks to Commit (%d/%d)", m.patch.current+1, len(m.patch.pieces))
+	sections = append(sections, "", ui.TitleStyle.Render(title))
+	sections = append(sections, fmt.Sprintf("%s - %d chosen so far", m.patch.files[p.file].path, m.patch.chosen()), "")
+	sections = append(sections, m.patchView.View())
+
+	content := strings.Join(sections, "\n")
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.NewStyle().Padding(0, 1).Render(content),
+		m.renderFooter(),
+	)
+}
+
+// renderRestoreRefView renders the prompt for the ref to restore files from
+func (m Model) renderRestoreRefView() string {
+	var sections []string
+
+	// Header
+	sections = append(sections, m.renderHeader())
+
+	// Title
+	sections = append(sections, "", ui.TitleStyle.Render("Restore Files from a Commit"), "")
+
+	// Files being restored
+	for _, path := range m.restorePaths {
+		sections = append(sections, "  "+path)
+	}
+	sections = append(sections, "")
+
+	// Ref input
+	sections = append(sections, "Restore to their state at:")
+	sections = append(sections, m.restoreInput.View(), "")
+	sections = append(sections, ui.HelpStyle.Render("Both the index and working tree are replaced"))
+
+	content := strings.Join(sections, "\n")
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.NewStyle().Padding(1).Render(content),
+		m.renderFooter(),
+	)
+}
+
+// renderExportPatchView renders the prompt for where to export a patch
+func (m Model) renderExportPatchView() string {
+	var sections []string
+
+	// Header
+	sections = append(sections, m.renderHeader())
+
+	// Title
+	what := "Unstaged"
+	if m.exportStaged {
+		what = "Staged"
+	}
+	sections = append(sections, "", ui.TitleStyle.Render("Export "+what+" Changes as a Patch"), "")
+
+	// Files being exported
+	if len(m.exportFiles) == 0 {
+		sections = append(sections, "  All files")
+	}
+	for _, path := range m.exportFiles {
+		sections = append(sections, "  "+path)
+	}
+	sections = append(sections, "")
+
+	// Path input
+	sections = append(sections, "Patch file:")
+	sections = append(sections, m.exportInput.View(), "")
+	sections = append(sections, ui.HelpStyle.Render("Leave empty to copy the patch to the clipboard; apply it with git apply"))
+
+	content := strings.Join(sections, "\n")
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.NewStyle().Padding(1).Render(content),
+		m.renderFooter(),
+	)
+}
+
+// renderApplyPatchView renders the patch file prompt and its check result
+func (m Model) renderApplyPatchView() string {
+	var sections []string
+
+	// Header
+	sections = append(sections, m.renderHeader())
+
+	// Title
+	target := "working tree"
+	if m.patchCached {
+		target = "index"
+	}
+	sections = append(sections, "", ui.TitleStyle.Render("Apply Patch to "+target), "")
+
+	// Path input
+	sections = append(sections, "Patch file:")
+	sections = append(sections, m.patchInput.View(), "")
+
+	// Check result
+	switch {
+	case m.processing:
+		sections = append(sections, "Checking...")
+	case m.patchChecked != "":
+		sections = append(sections, ui.SuccessStyle.Render("[OK] Patch applies cleanly, press enter to apply"))
+		sections = append(sections, strings.TrimRight(m.patchCheck, "\n"))
+	case m.patchCheck != "":
+		sections = append(sections, ui.ErrorStyle.Render("[!] "+strings.TrimRight(m.patchCheck, "\n")))
+	default:
+		sections = append(sections, ui.HelpStyle.Render("Press enter to check the patch before applying it"))
+	}
+
+	content := strings.Join(sections, "\n")
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.NewStyle().Padding(1).Render(content),
+		m.renderFooter(),
+	)
+}
+
+// renderSplitPreview renders a partially staged file's staged and unstaged
+// diffs next to each other, or one above the other when the pane is narrow
+func (m Model) renderSplitPreview(width, height int) string {
+	if width < 80 {
+		half := max((height-1)/2, 2)
+		return lipgloss.JoinVertical(
+			lipgloss.Left,
+			m.renderSplitSide(PanePreview, width, half),
+			"",
+			m.renderSplitSide(PaneSplitUnstaged, width, half),
+		)
+	}
+
+	half := (width - 3) / 2
+	divider := strings.TrimSuffix(strings.Repeat(" │ \n", height), "\n")
+	return lipgloss.JoinHorizontal(
+		lipgloss.Top,
+		m.renderSplitSide(PanePreview, half, height),
+		ui.HelpStyle.Render(divider),
+		m.renderSplitSide(PaneSplitUnstaged, half, height),
+	)
+}
+
+// renderSplitSide renders one side of the split preview, cutting long lines
+// so the sides stay aligned
+func (m Model) renderSplitSide(side Pane, width, height int) string {
+	title, lines, offset := "Staged (will be committed)", m.splitStaged, m.splitStagedAt
+	if side == PaneSplitUnstaged {
+		title, lines, offset = "Unstaged (left out)", m.splitUnstaged, m.splitUnstagedAt
+	}
+	if m.previewFocused() && m.focus == side {
+		title += " [FOCUSED]"
+	}
+
+	rows := []string{ui.InfoStyle.Render(title)}
+	for i := offset; i < len(lines) && len(rows) < height; i++ {
+		rows = append(rows, ui.WrapANSI(lines[i], width)[0])
+	}
+	return lipgloss.NewStyle().Width(width).Height(height).Render(strings.Join(rows, "\n"))
+}
+
+// renderPaletteView renders the command palette
+func (m Model) renderPaletteView() string {
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		m.renderHeader(),
+		lipgloss.NewStyle().Padding(1).Render(m.palette.View()),
+		m.renderFooter(),
+	)
+}
+
+// renderError renders the error view, which holds errors too long for the
+// footer until they're dismissed
 func (m Model) renderError() string {
-	return ui.ErrorStyle.Render("[ERROR] " + m.err)
+	var sections []string
+
+	sections = append(sections, m.renderHeader())
+	sections = append(sections, "", ui.ErrorStyle.Render("[ERROR]"), "")
+	sections = append(sections, m.errorView.View())
+
+	content := strings.Join(sections, "\n")
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.NewStyle().Padding(0, 1).Render(content),
+		m.renderFooter(),
+	)
 }
 
 // renderCommitView renders the commit workflow view
@@ -47,20 +357,30 @@ func (m Model) renderCommitView() string {
 	sections = append(sections, header)
 
 	// Title
-	title := ui.TitleStyle.Render("Commit Staged Files")
+	titleText := "Commit Staged Files"
+	if len(m.commitPaths) == 1 {
+		titleText = "Commit File"
+	} else if len(m.commitPaths) > 1 {
+		titleText = "Commit Selected Files"
+	}
+	title := ui.TitleStyle.Render(titleText)
 	sections = append(sections, "", title, "")
 
-	// Show files to be committed
-	filesList := "Files to commit:\n" + m.getStagedFilesList()
-	sections = append(sections, filesList, "")
+	// Show files to be committed, totalled once their stats are fetched
+	filesHeader := "Files to commit:"
+	if m.stagedStats != nil {
+		filesHeader = "Files to commit" + totalsSuffix(len(m.stagedStats), m.commitTotals()) + ":"
+	}
+	sections = append(sections, filesHeader+"\n"+m.getStagedFilesList(), "")
+	if n := m.gitStatus.UnstagedCount(); m.stagedStats != nil && n > 0 {
+		sections = append(sections, fmt.Sprintf("Left unstaged: %d file(s)%s", n, totalsSuffix(n, m.unstagedTotals)), "")
+	}
 
 	// Show input based on commit state
 	if m.commitState == CommitStateMessage {
 		// Show message input
 		sections = append(sections, ui.TitleStyle.Render("Commit Message"))
 		sections = append(sections, m.commitTextarea.View())
-		sections = append(sections, "")
-		sections = append(sections, ui.HelpStyle.Render("[Ctrl+D] Continue  [Esc] Cancel"))
 	} else if m.commitState == CommitStateDate {
 		// Show date input (optional)
 		sections = append(sections, ui.TitleStyle.Render("Commit Date (Optional)"))
@@ -68,12 +388,14 @@ func (m Model) renderCommitView() string {
 		sections = append(sections, "Format: YYYY-MM-DD or YYYY-MM-DD HH:MM:SS")
 		sections = append(sections, "")
 		sections = append(sections, m.commitInput.View())
-		sections = append(sections, "")
-		sections = append(sections, ui.HelpStyle.Render("[Enter] Commit  [Esc] Back"))
 	}
 
 	content := strings.Join(sections, "\n")
-	return lipgloss.NewStyle().Padding(1).Render(content)
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.NewStyle().Padding(1).Render(content),
+		m.renderFooter(),
+	)
 }
 
 // renderFileList renders the main file list view
@@ -121,8 +443,9 @@ func (m Model) renderHeader() string {
 
 // renderMainContent renders the main content (file list and preview)
 func (m Model) renderMainContent() string {
-	// If preview is focused, show it full screen (works even on small terminals)
-	if m.previewFocused && m.showPreview {
+	// If preview is focused or maximized, show it full screen (works even on
+	// small terminals)
+	if m.previewFullScreen() {
 		// Subtract border (2 chars) and padding (2 chars) overhead
 		previewWidth := m.width - 4
 		if previewWidth < 20 {
@@ -131,17 +454,15 @@ func (m Model) renderMainContent() string {
 		return m.renderPreview(previewWidth, m.layout.ListHeight()+m.layout.PreviewHeight()-2)
 	}
 
+	// A clean tree has nothing to list or preview
+	if m.gitStatus.IsClean && len(m.files) == 0 {
+		return m.renderCleanState()
+	}
+
 	// If preview is disabled or layout doesn't support split view, just show list
 	if !m.showPreview || !m.layout.HasPreviewPane() {
 		// Build status title for list
-		statusTitle := fmt.Sprintf(
-			"Files - Staged: %d | Unstaged: %d | Untracked: %d | Selected: %d",
-			m.gitStatus.StagedCount(),
-			m.gitStatus.UnstagedCount(),
-			m.gitStatus.UntrackedCount(),
-			len(m.selectedFiles),
-		)
-		m.list.Title = statusTitle
+		m.list.Title = m.listTitle()
 
 		// Subtract border (2 chars) and padding (2 chars) overhead
 		listWidth := m.width - 4
@@ -162,14 +483,7 @@ func (m Model) renderMainContent() string {
 	paneHeight := m.layout.ListHeight()
 
 	// Build status title for list
-	statusTitle := fmt.Sprintf(
-		"Files - Staged: %d | Unstaged: %d | Untracked: %d | Selected: %d",
-		m.gitStatus.StagedCount(),
-		m.gitStatus.UnstagedCount(),
-		m.gitStatus.UntrackedCount(),
-		len(m.selectedFiles),
-	)
-	m.list.Title = statusTitle
+	m.list.Title = m.listTitle()
 
 	// Render file list pane
 	// Subtract border (2 chars) and padding (2 chars) overhead
@@ -203,6 +517,51 @@ func (m Model) renderMainContent() string {
 	return content
 }
 
+// renderCleanState renders the empty list of a clean working tree, with
+// where the branch stands against its upstream
+func (m Model) renderCleanState() string {
+	// Subtract border (2 chars) and padding (2 chars) overhead
+	width := m.width - 4
+	if width < 20 {
+		width = 20
+	}
+	height := m.layout.ListHeight()
+
+	lines := []string{ui.SuccessStyle.Render("Working tree clean ✓"), ""}
+	if m.gitStatus.Branch == git.DetachedHead {
+		lines = append(lines, "HEAD is detached")
+	} else {
+		lines = append(lines, fmt.Sprintf("On branch %s", ui.InfoStyle.Render(m.gitStatus.Branch)))
+	}
+
+	st := m.gitStatus
+	switch {
+	case st.Upstream == "":
+		lines = append(lines, ui.HelpStyle.Render("No upstream branch"))
+	case st.Ahead == 0 && st.Behind == 0:
+		lines = append(lines, fmt.Sprintf("Up to date with %s", st.Upstream))
+	case st.Behind == 0:
+		lines = append(lines, fmt.Sprintf("%d ahead of %s", st.Ahead, st.Upstream))
+	case st.Ahead == 0:
+		lines = append(lines, fmt.Sprintf("%d behind %s", st.Behind, st.Upstream))
+	default:
+		lines = append(lines, fmt.Sprintf("%d ahead, %d behind %s", st.Ahead, st.Behind, st.Upstream))
+	}
+
+	if m.untrackedMode == git.UntrackedNo {
+		lines = append(lines, "", ui.HelpStyle.Render("Untracked files are hidden"))
+	}
+
+	message := lipgloss.JoinVertical(lipgloss.Center, lines...)
+	return lipgloss.NewStyle().
+		Border(lipgloss.RoundedBorder()).
+		BorderForeground(ui.ColorBlue).
+		Width(width).
+		Height(height).
+		Padding(0, 1).
+		Render(lipgloss.Place(width-2, height, lipgloss.Center, lipgloss.Center, message))
+}
+
 // renderPreview renders the preview pane
 func (m Model) renderPreview(width, height int) string {
 	if width < 10 || height < 3 {
@@ -210,21 +569,35 @@ func (m Model) renderPreview(width, height int) string {
 	}
 
 	title := "Preview"
-	if m.previewFocused {
+	if m.previewFocused() {
 		title = "Preview (FOCUSED)"
 	}
 	var content string
 
-	if m.list.Index() >= 0 && m.list.Index() < len(m.files) {
-		file := m.files[m.list.Index()]
-		if m.previewFocused {
-			title = fmt.Sprintf("Preview: %s (%s) [FOCUSED]", file.Path, file.Status.String())
-		} else {
-			title = fmt.Sprintf("Preview: %s (%s)", file.Path, file.Status.String())
+	if m.previewTitle != "" {
+		// Non-file content such as a commit
+		title = "Preview: " + m.previewTitle
+		if m.previewRef != "" && m.previewSig != git.SigNone {
+			title += " [" + m.previewSig.String() + "]"
+		}
+		if m.previewFocused() {
+			title += " [FOCUSED]"
+		}
+		content = m.viewport.View()
+	} else if file := m.getCurrentFile(); file != nil {
+		title = fmt.Sprintf("Preview: %s (%s)", file.Path, file.Status.String())
+		if m.previewTranscoded {
+			title += " [non-UTF8 encoding, shown best-effort]"
+		}
+		if m.previewFocused() {
+			title += " [FOCUSED]"
 		}
 
 		// Show preview content
-		if m.previewContent == "" {
+		if m.splitActive() {
+			// Padding takes a column on each side, the title a row
+			content = m.renderSplitPreview(width-2, height-1)
+		} else if m.previewContent == "" {
 			content = "[...] Loading preview..."
 		} else {
 			// Content is ready - show it
@@ -249,13 +622,51 @@ func (m Model) renderPreview(width, height int) string {
 	return previewBox
 }
 
+// listTitle summarizes the file counts for the list title
+func (m Model) listTitle() string {
+	title := fmt.Sprintf(
+		"Files - Staged: %d | Unstaged: %d | Untracked: %d | Selected: %d",
+		m.gitStatus.StagedCount(),
+		m.gitStatus.UnstagedCount(),
+		m.gitStatus.UntrackedCount(),
+		len(m.selectedFiles),
+	)
+	if n := m.gitStatus.ConflictedCount(); n > 0 {
+		title = fmt.Sprintf("Files - Conflicted: %d | %s", n, strings.TrimPrefix(title, "Files - "))
+	}
+	if m.list.IsFiltered() {
+		title += fmt.Sprintf(" | Filter %q: %d shown", m.list.FilterValue(), len(m.list.VisibleItems()))
+	}
+	return title
+}
+
+// totalsSuffix formats line totals to follow a file count, or "" when there
+// are no files to total
+func totalsSuffix(count int, totals git.DiffTotals) string {
+	if count == 0 {
+		return ""
+	}
+	return " (" + totals.String() + ")"
+}
+
 // renderFooter renders the footer with keybinding hints
 func (m Model) renderFooter() string {
 	var sections []string
 
 	// Status or error line
-	if m.err != "" {
-		sections = append(sections, ui.ErrorStyle.Render("[!] "+m.err))
+	if m.confirm != nil {
+		// The capital letter is the answer Enter gives
+		answers := " (y/N)"
+		if m.defaultsToYes() {
+			answers = " (Y/n)"
+		}
+		sections = append(sections, ui.WarningStyle.Render(m.confirm.prompt+answers))
+	} else if m.err != "" && !m.errSticky {
+		style := ui.ErrorStyle
+		if !m.errFlashAt.IsZero() {
+			style = style.Copy().Reverse(true)
+		}
+		sections = append(sections, style.Render("[!] "+m.err))
 	} else if m.status != "" {
 		statusLine := m.status
 		if m.processing {
@@ -264,9 +675,18 @@ func (m Model) renderFooter() string {
 		sections = append(sections, ui.InfoStyle.Render(statusLine))
 	}
 
-	// Show keybinding hints
-	keybindingHint := ui.HelpStyle.Render("[Space] Toggle  [a] Select All  [d] Deselect All  [Enter] Apply  [c] Commit  [m] Modify HEAD  [p] Preview  [?] Help  [q] Quit")
-	sections = append(sections, keybindingHint)
+	// Reassure that a slow operation hasn't hung
+	if m.processing && m.slowOperation {
+		sections = append(sections, ui.WarningStyle.Render(fmt.Sprintf("Still working… (%s)", m.processingLabel)))
+	}
+
+	// Progress of a bulk stage/unstage
+	if m.progressTotal > 0 {
+		sections = append(sections, m.progress.ViewAs(float64(m.progressDone)/float64(m.progressTotal)))
+	}
+
+	// Show keybinding hints for the current view
+	sections = append(sections, m.help.View(m.helpKeyMap()))
 
 	footer := lipgloss.JoinVertical(lipgloss.Left, sections...)
 
@@ -277,6 +697,64 @@ func (m Model) renderFooter() string {
 		Render(footer)
 }
 
+// helpKeyMap returns the keybindings relevant to the current view
+func (m Model) helpKeyMap() help.KeyMap {
+	if m.err != "" && m.errSticky {
+		return ui.HelpKeyMap{m.keys.Up, m.keys.Down, m.keys.Dismiss, m.keys.Quit}
+	}
+	if m.confirm != nil {
+		if m.settings.ConfirmEscCancels {
+			return ui.HelpKeyMap{m.keys.Yes, m.keys.No, m.keys.Cancel}
+		}
+		return ui.HelpKeyMap{m.keys.Yes, m.keys.No}
+	}
+
+	switch m.state {
+	case StateCommitMessage, StateCommitDate:
+		if m.commitState == CommitStateDate {
+			return ui.HelpKeyMap{m.keys.Confirm, m.keys.Back}
+		}
+		return ui.HelpKeyMap{m.keys.Continue, m.keys.OpenEditor, m.keys.ReviewStaged, m.keys.Cancel}
+	case StateModifyHead:
+		switch m.headModifyState {
+		case HeadModifyStateAmend:
+			return ui.HelpKeyMap{m.keys.Continue, m.keys.ResetAuthorDate, m.keys.Cancel}
+		case HeadModifyStateRedate:
+			return ui.HelpKeyMap{m.keys.Confirm, m.keys.Cancel}
+		}
+		return ui.HelpKeyMap{m.keys.Amend, m.keys.SoftReset, m.keys.Revert, m.keys.Redate, m.keys.Close}
+	case StateHelp:
+		return ui.HelpKeyMap{m.keys.Close}
+	case StateApplyPatch:
+		return ui.HelpKeyMap{m.keys.Confirm, m.keys.ToggleCached, m.keys.Cancel}
+	case StatePalette:
+		return ui.HelpKeyMap{m.keys.Up, m.keys.Down, m.keys.Confirm, m.keys.Cancel}
+	case StateRestoreRef:
+		return ui.HelpKeyMap{m.keys.Confirm, m.keys.Cancel}
+	case StateExportPatch:
+		return ui.HelpKeyMap{m.keys.Confirm, m.keys.ToggleCached, m.keys.Cancel}
+	case StateReview:
+		return ui.HelpKeyMap{m.keys.Up, m.keys.Down, m.keys.PageUp, m.keys.PageDown, m.keys.Close}
+	case StateDiff:
+		if m.diffSearch.Focused() {
+			return ui.HelpKeyMap{m.keys.Confirm, m.keys.Cancel}
+		}
+		return ui.HelpKeyMap{m.keys.Up, m.keys.Down, m.keys.PageUp, m.keys.PageDown, m.keys.Home, m.keys.End,
+			m.keys.NextHunk, m.keys.PrevHunk, m.keys.Search, m.keys.NextMatch, m.keys.PrevMatch, m.keys.Close}
+	case StateCommitFiles:
+		return ui.HelpKeyMap{m.keys.Up, m.keys.Down, m.keys.PageUp, m.keys.PageDown, m.keys.Search, m.keys.Close}
+	case StateFlaggedFiles:
+		return ui.HelpKeyMap{m.keys.Up, m.keys.Down, m.keys.ToggleAssumeUnchanged, m.keys.ToggleSkipWorktree, m.keys.Search, m.keys.Close}
+	case StatePatchMode:
+		return ui.HelpKeyMap{m.keys.IncludeHunk, m.keys.SkipHunk, m.keys.SplitHunk, m.keys.PreviousHunk, m.keys.Cancel}
+	default:
+		if m.previewHasFocus() {
+			return ui.HelpKeyMap{m.keys.Up, m.keys.Down, m.keys.SelectLines, m.keys.StageHunk, m.keys.DiscardHunk, m.keys.Blame, m.keys.BrowseCommit, m.keys.FocusLeft, m.keys.FocusRight, m.keys.FocusPreview}
+		}
+		return m.keys
+	}
+}
+
 // renderHelp renders the help screen
 func (m Model) renderHelp() string {
 	var sections []string
@@ -289,9 +767,8 @@ func (m Model) renderHelp() string {
 	helpContent := m.renderHelpContent()
 	sections = append(sections, helpContent)
 
-	// Instructions
-	instructions := ui.HelpStyle.Render("Press [?] or [q] to close help")
-	sections = append(sections, instructions)
+	// Footer
+	sections = append(sections, m.renderFooter())
 
 	return lipgloss.JoinVertical(lipgloss.Left, sections...)
 }
@@ -300,38 +777,21 @@ func (m Model) renderHelp() string {
 func (m Model) renderHelpContent() string {
 	var helpLines []string
 
+	// Keybindings are rendered from the KeyMap so they never drift from the handlers
 	helpLines = append(helpLines, "")
-	helpLines = append(helpLines, ui.TitleStyle.Render("Navigation"))
-	helpLines = append(helpLines, "  ↑/k, ↓/j       Move up/down in list")
-	helpLines = append(helpLines, "  Home/g, End/G   Jump to top/bottom")
-	helpLines = append(helpLines, "")
-
-	helpLines = append(helpLines, ui.TitleStyle.Render("Selection"))
-	helpLines = append(helpLines, "  Space/Tab       Toggle file selection")
-	helpLines = append(helpLines, "  a               Select all files")
-	helpLines = append(helpLines, "  d               Deselect all files")
-	helpLines = append(helpLines, "")
-
-	helpLines = append(helpLines, ui.TitleStyle.Render("Actions"))
-	helpLines = append(helpLines, "  Enter           Stage/unstage selected files")
-	helpLines = append(helpLines, "  c               Commit staged files")
-	helpLines = append(helpLines, "  m               Modify HEAD commit")
-	helpLines = append(helpLines, "  p               Focus/unfocus preview pane")
-	helpLines = append(helpLines, "  /               Search/filter files")
+	helpLines = append(helpLines, ui.TitleStyle.Render("Keybindings"))
+	helpLines = append(helpLines, m.help.FullHelpView(m.keys.FullHelp()))
 	helpLines = append(helpLines, "")
 
 	helpLines = append(helpLines, ui.TitleStyle.Render("Git Status Symbols"))
 	helpLines = append(helpLines, fmt.Sprintf("  %s   Staged file",
-		ui.StagedStyle.Render("+")))
+		ui.StagedStyle.Render(m.delegate.glyphs.Staged)))
 	helpLines = append(helpLines, fmt.Sprintf("  %s   Unstaged file",
-		ui.UnstagedStyle.Render("-")))
+		ui.UnstagedStyle.Render(m.delegate.glyphs.Unstaged)))
 	helpLines = append(helpLines, fmt.Sprintf("  %s   Untracked file",
-		ui.UntrackedStyle.Render("?")))
-	helpLines = append(helpLines, "")
-
-	helpLines = append(helpLines, ui.TitleStyle.Render("Other"))
-	helpLines = append(helpLines, "  ?               Toggle this help")
-	helpLines = append(helpLines, "  q/Ctrl+C        Quit")
+		ui.UntrackedStyle.Render(m.delegate.glyphs.Untracked)))
+	helpLines = append(helpLines, fmt.Sprintf("  %s   Conflicted file (stage it once resolved)",
+		ui.ConflictedStyle.Render(m.delegate.glyphs.Conflicted)))
 
 	content := strings.Join(helpLines, "\n")
 
@@ -351,13 +811,31 @@ func (m Model) renderModifyHeadView() string {
 	switch m.headModifyState {
 	case HeadModifyStateMenu:
 		return m.renderHeadModifyMenu()
-	case HeadModifyStateAmendMessage:
-		return m.renderHeadAmendMessageView()
+	case HeadModifyStateAmend:
+		return m.renderHeadAmendView()
+	case HeadModifyStateRedate:
+		return m.renderRedateView()
 	default:
 		return m.renderHeadModifyMenu()
 	}
 }
 
+// signatureBadge renders a commit's signature status, or "" for an
+// unsigned commit
+func signatureBadge(sig git.SigStatus) string {
+	label := "[" + sig.String() + "]"
+	switch sig {
+	case git.SigGood:
+		return ui.SuccessStyle.Render(label)
+	case git.SigBad:
+		return ui.ErrorStyle.Render(label)
+	case git.SigUnknown:
+		return ui.WarningStyle.Render(label)
+	default:
+		return ""
+	}
+}
+
 // renderHeadModifyMenu renders the HEAD modify menu
 func (m Model) renderHeadModifyMenu() string {
 	var sections []string
@@ -379,22 +857,82 @@ func (m Model) renderHeadModifyMenu() string {
 			m.headInfo.Author,
 			m.headInfo.Date,
 		)
+		if badge := signatureBadge(m.headInfo.Signature); badge != "" {
+			headContent += "\nSignature: " + badge
+		}
 		sections = append(sections, ui.PreviewStyle.Render(headContent), "")
 	}
 
 	// Menu options
 	sections = append(sections, ui.TitleStyle.Render("Options:"))
-	sections = append(sections, "  [m] Amend commit message")
+	sections = append(sections, "  [m] Amend commit (message and staged files)")
 	sections = append(sections, "  [f] Soft reset (modify files)")
-	sections = append(sections, "")
-	sections = append(sections, ui.HelpStyle.Render("[Esc] Cancel"))
+	if m.reverting {
+		sections = append(sections, "  [r] Abort the revert in progress")
+	} else {
+		sections = append(sections, "  [r] Revert commit (new commit undoing it, safe once pushed)")
+	}
+	if m.rebasing {
+		sections = append(sections, "  [d] Abort the rebase in progress")
+	} else {
+		sections = append(sections, "  [d] Change the date of an earlier commit (rewrites later ones)")
+	}
+
+	content := strings.Join(sections, "\n")
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.NewStyle().Padding(1).Render(content),
+		m.renderFooter(),
+	)
+}
+
+// renderRedateView renders the prompt for the commit to redate and its date
+func (m Model) renderRedateView() string {
+	var sections []string
+
+	sections = append(sections, m.renderHeader())
+	sections = append(sections, "", ui.TitleStyle.Render("Change Commit Date"), "")
+
+	if m.redateRef == "" {
+		sections = append(sections, "Commit to redate (on the current branch):")
+	} else {
+		sections = append(sections, fmt.Sprintf("Commit: %s", ui.InfoStyle.Render(m.redateRef)), "")
+		sections = append(sections, "New author and committer date:")
+	}
+	sections = append(sections, m.redateInput.View(), "")
+	sections = append(sections, ui.HelpStyle.Render("The branch is rebased, so every later commit gets a new hash; uncommitted changes are stashed meanwhile"))
 
 	content := strings.Join(sections, "\n")
-	return lipgloss.NewStyle().Padding(1).Render(content)
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.NewStyle().Padding(1).Render(content),
+		m.renderFooter(),
+	)
+}
+
+// renderMessageChange renders a commit message before and after an amend
+// in two columns
+func (m Model) renderMessageChange(old, new string) string {
+	width := (m.width - 8) / 2
+	if width < 20 {
+		width = 20
+	}
+	column := func(title, message string) string {
+		return lipgloss.JoinVertical(lipgloss.Left,
+			ui.TitleStyle.Render(title),
+			ui.PreviewStyle.Width(width).Render(message),
+		)
+	}
+	return lipgloss.JoinHorizontal(lipgloss.Top,
+		column("Old message:", old),
+		"  ",
+		column("New message:", new),
+	)
 }
 
-// renderHeadAmendMessageView renders the amend message input view
-func (m Model) renderHeadAmendMessageView() string {
+// renderHeadAmendView renders the combined amend screen: the editable
+// message and the staged changes that will be folded into HEAD
+func (m Model) renderHeadAmendView() string {
 	var sections []string
 
 	// Header
@@ -402,22 +940,50 @@ func (m Model) renderHeadAmendMessageView() string {
 	sections = append(sections, header)
 
 	// Title
-	title := ui.TitleStyle.Render("Amend Commit Message")
+	title := ui.TitleStyle.Render("Amend HEAD")
 	sections = append(sections, "", title, "")
 
-	// Current message
+	// Current commit
 	if m.headInfo != nil {
-		sections = append(sections, "Current message:")
-		sections = append(sections, ui.InfoStyle.Render(m.headInfo.Message))
+		amending := fmt.Sprintf("Amending %s: %s", m.headInfo.ShortHash, ui.InfoStyle.Render(m.headInfo.Message))
+		if badge := signatureBadge(m.headInfo.Signature); badge != "" {
+			amending += " " + badge
+		}
+		sections = append(sections, amending)
+		if m.headInfo.IsPushed {
+			sections = append(sections, ui.WarningStyle.Render("[!] This commit has been pushed"))
+		}
 		sections = append(sections, "")
 	}
 
-	// Message input
-	sections = append(sections, ui.TitleStyle.Render("New Message:"))
-	sections = append(sections, m.headMessageTextarea.View())
-	sections = append(sections, "")
-	sections = append(sections, ui.HelpStyle.Render("[Ctrl+D] Confirm  [Esc] Cancel"))
+	// Message input, or while confirming the old and new message side by side
+	if m.confirm != nil && m.headInfo != nil {
+		sections = append(sections, m.renderMessageChange(m.headInfo.Message, m.headMessageTextarea.Value()))
+	} else {
+		sections = append(sections, ui.TitleStyle.Render("Message:"))
+		sections = append(sections, m.headMessageTextarea.View())
+	}
+
+	// Staged changes to fold in
+	sections = append(sections, "", ui.TitleStyle.Render("Staged changes to include:"))
+	if m.gitStatus.StagedCount() == 0 {
+		sections = append(sections, ui.HelpStyle.Render("  (none, only the message changes)"))
+	}
+	for _, f := range m.gitStatus.Staged {
+		sections = append(sections, fmt.Sprintf("  %s %s", ui.StagedStyle.Render(m.delegate.glyphs.Staged), f))
+	}
+
+	// Author date handling
+	authorDate := "kept"
+	if m.amendResetDate {
+		authorDate = "reset to now"
+	}
+	sections = append(sections, "", fmt.Sprintf("Author date: %s", authorDate))
 
 	content := strings.Join(sections, "\n")
-	return lipgloss.NewStyle().Padding(1).Render(content)
+	return lipgloss.JoinVertical(
+		lipgloss.Left,
+		lipgloss.NewStyle().Padding(1).Render(content),
+		m.renderFooter(),
+	)
 }
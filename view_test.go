@@ -0,0 +1,74 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/rai/interactive-git/ui"
+)
+
+// TestCommitViewsUseStagedGlyph checks the commit and amend views mark
+// staged files with the configured glyph, as the file list does
+func TestCommitViewsUseStagedGlyph(t *testing.T) {
+	r := newTestRepo(t)
+	r.write("a.txt", "one\n")
+	r.commit("initial")
+	r.write("a.txt", "two\n")
+	r.git("add", "a.txt")
+
+	config := filepath.Join(os.Getenv("XDG_CONFIG_HOME"), "igit", "settings.json")
+	if err := os.MkdirAll(filepath.Dir(config), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	if err := os.WriteFile(config, []byte(`{"glyphs": {"staged": "●"}}`), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	m := openModel(t)
+	m = press(t, m, "c")
+	if view := ui.StripColors(m.View()); !strings.Contains(view, "● a.txt") || strings.Contains(view, "+ a.txt") {
+		t.Errorf("commit view doesn't mark a.txt with the staged glyph:\n%s", view)
+	}
+	m = press(t, m, "esc")
+
+	// Amend from the HEAD menu
+	m = press(t, m, "m", "m")
+	if m.state != StateModifyHead || m.headModifyState != HeadModifyStateAmend {
+		t.Fatalf("state = %v/%v after m m, want amending HEAD", m.state, m.headModifyState)
+	}
+	if view := ui.StripColors(m.View()); !strings.Contains(view, "● a.txt") || strings.Contains(view, "+ a.txt") {
+		t.Errorf("amend view doesn't mark a.txt with the staged glyph:\n%s", view)
+	}
+}
+
+// TestPreviewFollowsFilteredCursor checks the preview names the file under
+// the cursor while a filter hides files before it
+func TestPreviewFollowsFilteredCursor(t *testing.T) {
+	r := newTestRepo(t)
+	for _, name := range []string{"a.txt", "b1.txt", "b2.txt"} {
+		r.write(name, "one\n")
+	}
+	r.commit("initial")
+	for _, name := range []string{"a.txt", "b1.txt", "b2.txt"} {
+		r.write(name, "two\n")
+	}
+
+	m := openModel(t)
+	m = press(t, m, "/")
+	m = typeText(t, m, "b")
+	m = press(t, m, "enter")
+	if !m.list.IsFiltered() {
+		t.Fatal("list isn't filtered")
+	}
+	assertView(t, m, "Preview: b1.txt")
+
+	m = press(t, m, "down")
+	assertView(t, m, "Preview: b2.txt")
+	if m.lastFileIndex != m.cursorIndex() {
+		t.Errorf("lastFileIndex = %d, want the cursor's file %d", m.lastFileIndex, m.cursorIndex())
+	}
+	m = press(t, m, "up")
+	assertView(t, m, "Preview: b1.txt")
+}